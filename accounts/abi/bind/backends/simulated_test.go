@@ -17,11 +17,14 @@
 package backends_test
 
 import (
+	"bytes"
 	"context"
 	"math/big"
+	"strings"
 	"testing"
 
 	ccmchain "github.com/ccmchain/go-ccmchain"
+	"github.com/ccmchain/go-ccmchain/accounts/abi"
 	"github.com/ccmchain/go-ccmchain/accounts/abi/bind"
 	"github.com/ccmchain/go-ccmchain/accounts/abi/bind/backends"
 	"github.com/ccmchain/go-ccmchain/common"
@@ -80,3 +83,48 @@ func TestSimulatedBackend(t *testing.T) {
 	}
 
 }
+
+// Tests that bound contracts hand reverts to the caller as typed errors, both
+// when calling a contract and when estimating the gas of a transaction.
+func TestBoundContractRevert(t *testing.T) {
+	// Deploy a contract reverting every call with the reason "nope"
+	var (
+		reason = common.FromHex("0x08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000004" +
+			"6e6f706500000000000000000000000000000000000000000000000000000000")
+		code     = append(common.FromHex("0x6064600c60003960646000fd"), reason...)
+		contract = common.HexToAddress("0x0100")
+	)
+	key, _ := crypto.GenerateKey()
+	auth := bind.NewKeyedTransactor(key)
+
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{
+		auth.From: {Balance: big.NewInt(1000000000000000000)},
+		contract:  {Code: code, Balance: common.Big0},
+	}, 10000000)
+
+	parsed, err := abi.JSON(strings.NewReader(`[{"constant":false,"inputs":[],"name":"fail","outputs":[{"name":"","type":"uint256"}],"type":"function"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse ABI: %v", err)
+	}
+	bound := bind.NewBoundContract(contract, parsed, sim, sim, sim)
+
+	check := func(kind string, err error) {
+		revert, ok := err.(*ccmchain.RevertError)
+		if !ok {
+			t.Fatalf("%s: error type mismatch: have %T (%v), want %T", kind, err, err, revert)
+		}
+		if revert.Reason != "nope" {
+			t.Errorf("%s: revert reason mismatch: have %q, want %q", kind, revert.Reason, "nope")
+		}
+		if !bytes.Equal(revert.Data, reason) {
+			t.Errorf("%s: revert data mismatch: have %x, want %x", kind, revert.Data, reason)
+		}
+	}
+	var out *big.Int
+	check("call", bound.Call(nil, &out, "fail"))
+
+	_, err = bound.Transact(auth, "fail")
+	check("transact", err)
+}
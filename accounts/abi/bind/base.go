@@ -221,6 +221,10 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 		msg := ccmchain.CallMsg{From: opts.From, To: contract, Value: value, Data: input}
 		gasLimit, err = c.transactor.EstimateGas(ensureContext(opts.Context), msg)
 		if err != nil {
+			// Hand reverts to the caller as is, so they can inspect the reason
+			if _, ok := err.(*ccmchain.RevertError); ok {
+				return nil, err
+			}
 			return nil, fmt.Errorf("failed to estimate gas needed: %v", err)
 		}
 	}
@@ -0,0 +1,326 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/ccmdb"
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/bitutil"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/core/state"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/crypto"
+	"github.com/ccmchain/go-ccmchain/rlp"
+	"github.com/ccmchain/go-ccmchain/trie"
+)
+
+const (
+	// accountHistorySection is the number of blocks covered by a single section
+	// of the account history index.
+	accountHistorySection = 4096
+
+	// accountHistoryConfirms is the number of confirmation blocks before an
+	// account history section is considered probably final.
+	accountHistoryConfirms = 256
+
+	// accountHistoryThrottling is the time to wait between processing two
+	// consecutive index sections.
+	accountHistoryThrottling = 100 * time.Millisecond
+
+	// maxAccountHistoryResults is the maximum number of account changes a single
+	// history query may return.
+	maxAccountHistoryResults = 10000
+)
+
+// errMissingHistoryState is returned if the state needed to answer a history
+// query has been pruned.
+var errMissingHistoryState = errors.New("missing historical state, account history requires an archive node")
+
+// AccountHistoryIndexer implements a core.ChainIndexerBackend, recording for
+// every account the blocks in which its balance or nonce changed. Each section
+// of the index stores a compressed bitmap per changed account, keyed by the hash
+// of the account address, with one bit for every block of the section.
+type AccountHistoryIndexer struct {
+	size    uint64                   // section size to generate the index for
+	db      ccmdb.Database           // database instance to write index data and metadata into
+	state   state.Database           // state database to diff the account tries in
+	changes map[common.Hash][]uint32 // Offsets of the blocks changing each account in the current section
+	section uint64                   // Section is the section number being processed currently
+	head    common.Hash              // Head is the hash of the last header processed
+}
+
+// NewAccountHistoryIndexer returns a chain indexer that records the balance and
+// nonce changes of all accounts along the canonical chain. The indexer needs the
+// full state of every block, so it can only run on an archive node.
+func NewAccountHistoryIndexer(db ccmdb.Database, stateDb state.Database, size, confirms uint64) *core.ChainIndexer {
+	backend := &AccountHistoryIndexer{
+		db:    db,
+		state: stateDb,
+		size:  size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.AccountHistoryIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, confirms, accountHistoryThrottling, "accounthistory")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new account history
+// index section.
+func (b *AccountHistoryIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.changes, b.section, b.head = make(map[common.Hash][]uint32), section, common.Hash{}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the accounts changed by a
+// new header into the index.
+func (b *AccountHistoryIndexer) Process(ctx context.Context, header *types.Header) error {
+	number := header.Number.Uint64()
+
+	var parentRoot common.Hash
+	if number > 0 {
+		parent := rawdb.ReadHeader(b.db, header.ParentHash, number-1)
+		if parent == nil {
+			return fmt.Errorf("missing parent header #%d [%x]", number-1, header.ParentHash)
+		}
+		parentRoot = parent.Root
+	}
+	changed, err := changedAccounts(b.state, parentRoot, header.Root)
+	if err != nil {
+		return err
+	}
+	for _, account := range changed {
+		b.changes[account] = append(b.changes[account], uint32(number-b.section*b.size))
+	}
+	b.head = header.Hash()
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the account history
+// section and writing it out into the database.
+func (b *AccountHistoryIndexer) Commit() error {
+	batch := b.db.NewBatch()
+	for account, offsets := range b.changes {
+		bits := make([]byte, b.size/8)
+		for _, offset := range offsets {
+			bits[offset/8] |= 1 << (7 - offset%8)
+		}
+		rawdb.WriteAccountHistory(batch, account, b.section, b.head, bitutil.CompressBytes(bits))
+
+		if batch.ValueSize() >= ccmdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
+
+// changedAccounts returns the hashes of all accounts whose balance or nonce
+// differs between the two state roots. Accounts only touching their code or
+// storage are skipped.
+func changedAccounts(db state.Database, parentRoot, root common.Hash) ([]common.Hash, error) {
+	if parentRoot == root {
+		return nil, nil
+	}
+	oldTrie, err := db.OpenTrie(parentRoot)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := db.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	// Collect the leaves differing in either direction, the forward pass yielding
+	// the new account values and the backward pass the old ones.
+	blobs := make(map[common.Hash][2][]byte)
+	collect := func(a, b state.Trie, index int) error {
+		it, _ := trie.NewDifferenceIterator(a.NodeIterator(nil), b.NodeIterator(nil))
+		for it.Next(true) {
+			if !it.Leaf() {
+				continue
+			}
+			hash := common.BytesToHash(it.LeafKey())
+			entry := blobs[hash]
+			entry[index] = common.CopyBytes(it.LeafBlob())
+			blobs[hash] = entry
+		}
+		return it.Error()
+	}
+	if err := collect(newTrie, oldTrie, 0); err != nil {
+		return nil, err
+	}
+	if err := collect(oldTrie, newTrie, 1); err != nil {
+		return nil, err
+	}
+	var changed []common.Hash
+	for hash, entry := range blobs {
+		prev, err := decodeAccount(entry[0])
+		if err != nil {
+			return nil, err
+		}
+		next, err := decodeAccount(entry[1])
+		if err != nil {
+			return nil, err
+		}
+		if prev.Nonce != next.Nonce || prev.Balance.Cmp(next.Balance) != 0 {
+			changed = append(changed, hash)
+		}
+	}
+	return changed, nil
+}
+
+// decodeAccount decodes an account trie leaf, returning an empty account if the
+// leaf is missing.
+func decodeAccount(blob []byte) (*state.Account, error) {
+	if blob == nil {
+		return &state.Account{Balance: new(big.Int)}, nil
+	}
+	account := new(state.Account)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// accountHistory answers account history queries from the index generated by
+// an AccountHistoryIndexer, scanning the state of the blocks not yet indexed.
+type accountHistory struct {
+	db       ccmdb.Database
+	chain    *core.BlockChain
+	indexer  *core.ChainIndexer
+	size     uint64
+	confirms uint64
+}
+
+// newAccountHistory creates an account history index for the given chain and
+// starts indexing its canonical blocks.
+func newAccountHistory(chain *core.BlockChain, db ccmdb.Database, size, confirms uint64) *accountHistory {
+	h := &accountHistory{
+		db:       db,
+		chain:    chain,
+		indexer:  NewAccountHistoryIndexer(db, chain.StateCache(), size, confirms),
+		size:     size,
+		confirms: confirms,
+	}
+	h.indexer.Start(chain)
+	return h
+}
+
+// close stops the indexer of the account history.
+func (h *accountHistory) close() error {
+	return h.indexer.Close()
+}
+
+// changes returns the numbers of the canonical blocks in the [from, to] range
+// which changed the balance or nonce of the given account. Blocks not yet indexed
+// are scanned on the fly, as long as they are no more than a section and its
+// confirmations, which is the most a caught up indexer leaves behind.
+func (h *accountHistory) changes(ctx context.Context, account common.Address, from, to uint64) ([]uint64, error) {
+	var (
+		hash           = crypto.Keccak256Hash(account.Bytes())
+		sections, _, _ = h.indexer.Sections()
+		blocks         []uint64
+	)
+
+	// Gather the changes from the sections already indexed
+	for section := from / h.size; section < sections && section*h.size <= to; section++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		head := rawdb.ReadCanonicalHash(h.db, (section+1)*h.size-1)
+		compressed, err := rawdb.ReadAccountHistory(h.db, hash, section, head)
+		if err != nil {
+			continue // account untouched in this section
+		}
+		bits, err := bitutil.DecompressBytes(compressed, int(h.size/8))
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < h.size; i++ {
+			if bits[i/8]&(1<<(7-i%8)) == 0 {
+				continue
+			}
+			if number := section*h.size + i; number >= from && number <= to {
+				blocks = append(blocks, number)
+			}
+		}
+		if len(blocks) > maxAccountHistoryResults {
+			return nil, fmt.Errorf("query returned more than %d results", maxAccountHistoryResults)
+		}
+	}
+	// Compare the account state block by block for the unindexed tail
+	start := from
+	if indexed := sections * h.size; start < indexed {
+		start = indexed
+	}
+	if start > to {
+		return blocks, nil
+	}
+	if to-start >= h.size+h.confirms {
+		return nil, fmt.Errorf("account history of blocks #%d-#%d not yet indexed", start, to)
+	}
+	var prev *state.Account
+	if start > 0 {
+		var err error
+		if prev, err = h.accountAt(account, start-1); err != nil {
+			return nil, err
+		}
+	} else {
+		prev = &state.Account{Balance: new(big.Int)}
+	}
+	for number := start; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		next, err := h.accountAt(account, number)
+		if err != nil {
+			return nil, err
+		}
+		if prev.Nonce != next.Nonce || prev.Balance.Cmp(next.Balance) != 0 {
+			blocks = append(blocks, number)
+			if len(blocks) > maxAccountHistoryResults {
+				return nil, fmt.Errorf("query returned more than %d results", maxAccountHistoryResults)
+			}
+		}
+		prev = next
+	}
+	return blocks, nil
+}
+
+// accountAt returns the balance and nonce of an account at the given canonical
+// block.
+func (h *accountHistory) accountAt(account common.Address, number uint64) (*state.Account, error) {
+	header := h.chain.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	statedb, err := h.chain.StateAt(header.Root)
+	if err != nil {
+		return nil, errMissingHistoryState
+	}
+	return &state.Account{
+		Nonce:   statedb.GetNonce(account),
+		Balance: statedb.GetBalance(account),
+	}, nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccm
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/consensus/ccmash"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/params"
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+// newArchiveTestChain creates an archive chain of the given length, funding the
+// test bank and the given accounts in its genesis.
+func newArchiveTestChain(t *testing.T, blocks int, alloc core.GenesisAlloc, generator func(int, *core.BlockGen)) *Ccmchain {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000)}},
+		}
+		cache = &core.CacheConfig{TrieCleanLimit: 256, TrieDirtyDisabled: true, TrieTimeLimit: 5 * time.Minute}
+	)
+	for address, account := range alloc {
+		gspec.Alloc[address] = account
+	}
+	genesis := gspec.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, cache, gspec.Config, ccmash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	generated, _ := core.GenerateChain(gspec.Config, genesis, ccmash.NewFaker(), db, blocks, generator)
+	if _, err := chain.InsertChain(generated); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return &Ccmchain{chainDb: db, blockchain: chain}
+}
+
+// sendAt returns a chain generator making the test bank send a transaction to
+// the recipient in each of the listed blocks.
+func sendAt(recipient common.Address, blocks ...int) func(int, *core.BlockGen) {
+	return func(i int, gen *core.BlockGen) {
+		for _, number := range blocks {
+			if number == i+1 {
+				tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testBank), recipient, big.NewInt(1000), params.TxGas*2, nil, nil), types.HomesteadSigner{}, testBankKey)
+				gen.AddTx(tx)
+			}
+		}
+	}
+}
+
+// Tests that the account history index returns the same changes for indexed
+// sections as a scan over the state of every block.
+func TestAccountHistoryIndex(t *testing.T) {
+	recipient := common.HexToAddress("0xdeadbeef")
+	ccm := newArchiveTestChain(t, 33, nil, sendAt(recipient, 3, 10, 17, 33))
+	defer ccm.blockchain.Stop()
+
+	// Index all the sections of the chain and wait for the indexer to finish
+	indexed := newAccountHistory(ccm.blockchain, ccm.chainDb, 8, 0)
+	defer indexed.close()
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := indexed.indexer.Sections(); sections == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("account history indexing timed out")
+		}
+	}
+	// Create an unstarted index with enough confirmations to answer all queries
+	// from the state
+	scanned := &accountHistory{
+		db:       ccm.chainDb,
+		chain:    ccm.blockchain,
+		indexer:  NewAccountHistoryIndexer(ccm.chainDb, ccm.blockchain.StateCache(), 8, 32),
+		size:     8,
+		confirms: 32,
+	}
+	tests := []struct {
+		account  common.Address
+		from, to uint64
+		want     []uint64
+	}{
+		{recipient, 0, 33, []uint64{3, 10, 17, 33}},
+		{recipient, 4, 17, []uint64{10, 17}},
+		{recipient, 11, 16, nil},
+		{testBank, 0, 33, []uint64{0, 3, 10, 17, 33}},
+		{testBank, 1, 9, []uint64{3}},
+		{common.HexToAddress("0xcafebabe"), 0, 33, nil},
+	}
+	for i, tt := range tests {
+		for _, history := range []*accountHistory{indexed, scanned} {
+			have, err := history.changes(context.Background(), tt.account, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("test %d: failed to query history: %v", i, err)
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("test %d: changes mismatch: have %v, want %v", i, have, tt.want)
+			}
+		}
+	}
+}
+
+// Tests that queries reaching too far into the blocks not yet indexed are
+// rejected instead of scanning the state of all of them.
+func TestAccountHistoryUnindexed(t *testing.T) {
+	recipient := common.HexToAddress("0xdeadbeef")
+	ccm := newArchiveTestChain(t, 33, nil, sendAt(recipient, 3, 10))
+	defer ccm.blockchain.Stop()
+
+	history := &accountHistory{
+		db:       ccm.chainDb,
+		chain:    ccm.blockchain,
+		indexer:  NewAccountHistoryIndexer(ccm.chainDb, ccm.blockchain.StateCache(), 8, 4),
+		size:     8,
+		confirms: 4,
+	}
+	if _, err := history.changes(context.Background(), recipient, 0, 33); err == nil {
+		t.Fatalf("query of unindexed blocks accepted")
+	}
+	have, err := history.changes(context.Background(), recipient, 22, 33)
+	if err != nil {
+		t.Fatalf("failed to query recent history: %v", err)
+	}
+	if len(have) != 0 {
+		t.Fatalf("unexpected changes: %v", have)
+	}
+}
+
+// Tests that the account history API resolves the block range and returns the
+// account values after every change.
+func TestGetAccountHistory(t *testing.T) {
+	recipient := common.HexToAddress("0xdeadbeef")
+	ccm := newArchiveTestChain(t, 12, nil, sendAt(recipient, 2, 7))
+	defer ccm.blockchain.Stop()
+
+	ccm.accountHistory = newAccountHistory(ccm.blockchain, ccm.chainDb, 8, 8)
+	defer ccm.accountHistory.close()
+
+	api := NewPublicAccountHistoryAPI(ccm)
+	changes, err := api.GetAccountHistory(context.Background(), recipient, rpc.EarliestBlockNumber, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to retrieve account history: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("change count mismatch: have %d, want %d", len(changes), 2)
+	}
+	for i, want := range []struct {
+		number  uint64
+		balance int64
+	}{{2, 1000}, {7, 2000}} {
+		if uint64(changes[i].BlockNumber) != want.number {
+			t.Errorf("change %d: block mismatch: have %d, want %d", i, changes[i].BlockNumber, want.number)
+		}
+		if hash := ccm.blockchain.GetHeaderByNumber(want.number).Hash(); changes[i].BlockHash != hash {
+			t.Errorf("change %d: block hash mismatch: have %x, want %x", i, changes[i].BlockHash, hash)
+		}
+		if changes[i].Balance.ToInt().Int64() != want.balance {
+			t.Errorf("change %d: balance mismatch: have %v, want %d", i, changes[i].Balance.ToInt(), want.balance)
+		}
+	}
+	if _, err := api.GetAccountHistory(context.Background(), recipient, 10, 5); err == nil {
+		t.Fatalf("inverted block range accepted")
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/ccmdb"
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/crypto"
+	"github.com/ccmchain/go-ccmchain/params"
+	"github.com/ccmchain/go-ccmchain/rlp"
+)
+
+const (
+	// addressTxsSection is the number of blocks covered by a single section of
+	// the address transaction index. It is kept smaller than the bloom sections
+	// to limit the number of blocks scanned on the fly for every query.
+	addressTxsSection = 1024
+
+	// addressTxsConfirms is the number of confirmation blocks before an address
+	// transaction section is considered probably final.
+	addressTxsConfirms = 64
+
+	// addressTxsThrottling is the time to wait between processing two consecutive
+	// index sections.
+	addressTxsThrottling = 100 * time.Millisecond
+
+	// defaultAddressTxsPage is the maximum number of transactions returned by a
+	// single ccm_getTransactionsByAddress request.
+	defaultAddressTxsPage = 1000
+)
+
+// Roles an address may take in a transaction, stored as a bitmask in the index.
+const (
+	addressTxSender    uint8 = 1 << iota // Address signed the transaction
+	addressTxRecipient                   // Address is the recipient, or the contract created
+	addressTxInternal                    // Address is the target of an internal call
+)
+
+var (
+	errInvalidAddressTxsCursor = errors.New("invalid transaction cursor")
+	errAddressTxsCursorReorged = errors.New("transaction cursor invalidated by chain reorganisation")
+)
+
+// addressTxPosting is an entry of the address transaction index, identifying a
+// transaction within a section and the roles the address took in it.
+type addressTxPosting struct {
+	Offset uint64 // Offset of the block within the section
+	Index  uint64 // Index of the transaction within the block
+	Roles  uint8  // Bitmask of the roles of the address in the transaction
+}
+
+// AddressTxIndexer implements a core.ChainIndexerBackend, recording for every
+// address the transactions it sent or received. If enabled, the blocks are also
+// replayed to record the targets of internal calls, needing an archive node.
+type AddressTxIndexer struct {
+	size     uint64                                // section size to generate the index for
+	db       ccmdb.Database                        // database instance to write index data and metadata into
+	chain    *core.BlockChain                      // blockchain to replay blocks on, nil if internal calls aren't traced
+	config   *params.ChainConfig                   // chain configuration to derive the transaction signers from
+	postings map[common.Address][]addressTxPosting // Postings of each address in the current section
+	section  uint64                                // Section is the section number being processed currently
+	head     common.Hash                           // Head is the hash of the last header processed
+}
+
+// NewAddressTxIndexer returns a chain indexer that records the transactions of
+// all addresses along the canonical chain, optionally tracing internal calls.
+func NewAddressTxIndexer(db ccmdb.Database, chain *core.BlockChain, traceInternal bool, size, confirms uint64) *core.ChainIndexer {
+	backend := &AddressTxIndexer{
+		db:     db,
+		config: chain.Config(),
+		size:   size,
+	}
+	if traceInternal {
+		backend.chain = chain
+	}
+	table := rawdb.NewTable(db, string(rawdb.AddressTxsIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, confirms, addressTxsThrottling, "addresstxs")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new address transaction
+// index section.
+func (b *AddressTxIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.postings, b.section, b.head = make(map[common.Address][]addressTxPosting), section, common.Hash{}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the transactions of a new
+// header into the index.
+func (b *AddressTxIndexer) Process(ctx context.Context, header *types.Header) error {
+	hash, number := header.Hash(), header.Number.Uint64()
+
+	body := rawdb.ReadBody(b.db, hash, number)
+	if body == nil {
+		return fmt.Errorf("missing block body #%d [%x]", number, hash)
+	}
+	roles, err := addressTxRoles(b.config, b.chain, header, body.Transactions)
+	if err != nil {
+		return err
+	}
+	for i, addresses := range roles {
+		for address, role := range addresses {
+			b.postings[address] = append(b.postings[address], addressTxPosting{
+				Offset: number - b.section*b.size,
+				Index:  uint64(i),
+				Roles:  role,
+			})
+		}
+	}
+	b.head = hash
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the address transaction
+// section and writing it out into the database.
+func (b *AddressTxIndexer) Commit() error {
+	batch := b.db.NewBatch()
+	for address, postings := range b.postings {
+		blob, err := rlp.EncodeToBytes(postings)
+		if err != nil {
+			return err
+		}
+		rawdb.WriteAddressTxs(batch, address, b.section, b.head, blob)
+
+		if batch.ValueSize() >= ccmdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
+
+// addressTxRoles returns the addresses taking part in each of the transactions
+// of a block, along with their roles. The targets of internal calls are only
+// included if a chain to replay the transactions on is given.
+func addressTxRoles(config *params.ChainConfig, chain *core.BlockChain, header *types.Header, txs types.Transactions) ([]map[common.Address]uint8, error) {
+	var (
+		signer = types.MakeSigner(config, header.Number)
+		roles  = make([]map[common.Address]uint8, len(txs))
+	)
+	for i, tx := range txs {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		to := crypto.CreateAddress(from, tx.Nonce())
+		if tx.To() != nil {
+			to = *tx.To()
+		}
+		roles[i] = map[common.Address]uint8{from: addressTxSender}
+		roles[i][to] |= addressTxRecipient
+	}
+	if chain == nil || len(txs) == 0 {
+		return roles, nil
+	}
+	targets, err := traceCallTargets(chain, header, txs)
+	if err != nil {
+		return nil, err
+	}
+	for i, addresses := range targets {
+		for address := range addresses {
+			roles[i][address] |= addressTxInternal
+		}
+	}
+	return roles, nil
+}
+
+// traceCallTargets replays the transactions of a block on top of its parent
+// state, returning the addresses called internally by each of them.
+func traceCallTargets(chain *core.BlockChain, header *types.Header, txs types.Transactions) ([]map[common.Address]struct{}, error) {
+	number := header.Number.Uint64()
+
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return nil, fmt.Errorf("missing parent header #%d [%x]", number-1, header.ParentHash)
+	}
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, fmt.Errorf("missing state of block #%d, tracing internal calls requires an archive node", number-1)
+	}
+	var (
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		usedGas uint64
+		targets = make([]map[common.Address]struct{}, len(txs))
+	)
+	for i, tx := range txs {
+		tracer := &callTargetTracer{targets: make(map[common.Address]struct{})}
+		statedb.Prepare(tx.Hash(), header.Hash(), i)
+
+		if _, _, err := core.ApplyTransaction(chain.Config(), chain, nil, gp, statedb, header, tx, &usedGas, vm.Config{Debug: true, Tracer: tracer}); err != nil {
+			return nil, fmt.Errorf("failed to replay transaction %#x: %v", tx.Hash(), err)
+		}
+		targets[i] = tracer.targets
+	}
+	return targets, nil
+}
+
+// callTargetTracer is a vm.Tracer collecting the addresses called internally by
+// a transaction, be it via a message call, a contract creation or a self
+// destruct sending the remaining funds.
+type callTargetTracer struct {
+	targets map[common.Address]struct{}
+}
+
+// CaptureStart implements vm.Tracer, ignoring the top level call.
+func (t *callTargetTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements vm.Tracer, recording the targets of the calls made by
+// the executed opcodes and the contracts executing below the top level.
+func (t *callTargetTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if err != nil {
+		return nil
+	}
+	switch op {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		if len(stack.Data()) > 1 {
+			t.targets[common.BigToAddress(stack.Back(1))] = struct{}{}
+		}
+	case vm.SELFDESTRUCT:
+		if len(stack.Data()) > 0 {
+			t.targets[common.BigToAddress(stack.Back(0))] = struct{}{}
+		}
+	}
+	if depth > 1 {
+		t.targets[contract.Address()] = struct{}{}
+	}
+	return nil
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *callTargetTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer.
+func (t *callTargetTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// addressTx is a transaction found in the address transaction index.
+type addressTx struct {
+	number uint64 // Number of the block containing the transaction
+	index  uint64 // Index of the transaction within the block
+	roles  uint8  // Roles of the address in the transaction
+}
+
+// addressTxs answers address transaction queries from the index generated by an
+// AddressTxIndexer, scanning the blocks not yet indexed. Internal calls are not
+// traced for the unindexed blocks, so they only show up once indexed.
+type addressTxs struct {
+	db       ccmdb.Database
+	chain    *core.BlockChain
+	indexer  *core.ChainIndexer
+	size     uint64
+	confirms uint64
+}
+
+// newAddressTxs creates an address transaction index for the given chain and
+// starts indexing its canonical blocks.
+func newAddressTxs(chain *core.BlockChain, db ccmdb.Database, traceInternal bool, size, confirms uint64) *addressTxs {
+	a := &addressTxs{
+		db:       db,
+		chain:    chain,
+		indexer:  NewAddressTxIndexer(db, chain, traceInternal, size, confirms),
+		size:     size,
+		confirms: confirms,
+	}
+	a.indexer.Start(chain)
+	return a
+}
+
+// close stops the indexer of the address transactions.
+func (a *addressTxs) close() error {
+	return a.indexer.Close()
+}
+
+// find returns at most limit transactions of an address within the [from, to]
+// block range, starting at the given transaction index of the first block. Blocks
+// not yet indexed are scanned on the fly, as long as they are no more than a
+// section and its confirmations, which is the most a caught up indexer leaves
+// behind.
+func (a *addressTxs) find(ctx context.Context, address common.Address, from, index, to uint64, limit int) ([]addressTx, error) {
+	var (
+		sections, _, _ = a.indexer.Sections()
+		txs            []addressTx
+	)
+	// Gather the transactions from the sections already indexed
+	for section := from / a.size; section < sections && section*a.size <= to && len(txs) < limit; section++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		head := rawdb.ReadCanonicalHash(a.db, (section+1)*a.size-1)
+		blob, err := rawdb.ReadAddressTxs(a.db, address, section, head)
+		if err != nil {
+			continue // address not seen in this section
+		}
+		var postings []addressTxPosting
+		if err := rlp.DecodeBytes(blob, &postings); err != nil {
+			return nil, err
+		}
+		for _, posting := range postings {
+			number := section*a.size + posting.Offset
+			if number < from || (number == from && posting.Index < index) || number > to {
+				continue
+			}
+			txs = append(txs, addressTx{number: number, index: posting.Index, roles: posting.Roles})
+			if len(txs) == limit {
+				break
+			}
+		}
+	}
+	// Scan the blocks of the unindexed tail
+	start := from
+	if indexed := sections * a.size; start < indexed {
+		start = indexed
+	}
+	if start <= to && len(txs) < limit && to-start >= a.size+a.confirms {
+		return nil, fmt.Errorf("transactions of blocks #%d-#%d not yet indexed", start, to)
+	}
+	for number := start; number <= to && len(txs) < limit; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := a.chain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		roles, err := addressTxRoles(a.chain.Config(), nil, block.Header(), block.Transactions())
+		if err != nil {
+			return nil, err
+		}
+		for i, addresses := range roles {
+			if role, ok := addresses[address]; ok && (number > from || uint64(i) >= index) {
+				txs = append(txs, addressTx{number: number, index: uint64(i), roles: role})
+				if len(txs) == limit {
+					break
+				}
+			}
+		}
+	}
+	return txs, nil
+}
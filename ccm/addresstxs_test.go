@@ -0,0 +1,157 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/rlp"
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+// Tests that the address transaction index records the senders, recipients and
+// internal call targets of transactions, and that the API pages through them.
+func TestGetTransactionsByAddress(t *testing.T) {
+	var (
+		recipient = common.HexToAddress("0xdeadbeef")
+		forwarder = common.HexToAddress("0xf0")
+		target    = common.HexToAddress("0xcafe")
+	)
+	// Deploy a contract forwarding every call to the target, and call it in an
+	// indexed and an unindexed block
+	code := append(append(common.FromHex("0x6000600060006000600073"), target.Bytes()...), common.FromHex("0x5af100")...)
+
+	transfers, calls := sendAt(recipient, 3, 10, 25, 42), sendAt(forwarder, 12, 43)
+	ccm := newArchiveTestChain(t, 44, core.GenesisAlloc{forwarder: {Code: code, Balance: common.Big0}}, func(i int, gen *core.BlockGen) {
+		transfers(i, gen)
+		calls(i, gen)
+	})
+	defer ccm.blockchain.Stop()
+
+	ccm.addressTxs = newAddressTxs(ccm.blockchain, ccm.chainDb, true, 8, 0)
+	defer ccm.addressTxs.close()
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := ccm.addressTxs.indexer.Sections(); sections == 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("address transaction indexing timed out")
+		}
+	}
+	api := NewPublicAddressTxsAPI(ccm)
+
+	tests := []struct {
+		address  common.Address
+		from, to rpc.BlockNumber
+		want     []uint64
+		role     func(tx *AddressTx) bool
+	}{
+		{recipient, rpc.EarliestBlockNumber, rpc.LatestBlockNumber, []uint64{3, 10, 25, 42}, func(tx *AddressTx) bool { return tx.Recipient && !tx.Sender }},
+		{recipient, 4, 41, []uint64{10, 25}, func(tx *AddressTx) bool { return tx.Recipient }},
+		{forwarder, rpc.EarliestBlockNumber, rpc.LatestBlockNumber, []uint64{12, 43}, func(tx *AddressTx) bool { return tx.Recipient && !tx.Internal }},
+		{target, rpc.EarliestBlockNumber, rpc.LatestBlockNumber, []uint64{12}, func(tx *AddressTx) bool { return tx.Internal && !tx.Recipient }},
+		{testBank, 11, 43, []uint64{12, 25, 42, 43}, func(tx *AddressTx) bool { return tx.Sender && !tx.Recipient }},
+	}
+	for i, tt := range tests {
+		page, err := api.GetTransactionsByAddress(context.Background(), tt.address, tt.from, tt.to, nil, nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve transactions: %v", i, err)
+		}
+		if page.Cursor != nil {
+			t.Errorf("test %d: cursor returned for complete page", i)
+		}
+		var have []uint64
+		for _, tx := range page.Transactions {
+			have = append(have, uint64(tx.BlockNumber))
+			if !tt.role(tx) {
+				t.Errorf("test %d: block #%d: role mismatch: %+v", i, tx.BlockNumber, tx)
+			}
+			block := ccm.blockchain.GetBlockByNumber(uint64(tx.BlockNumber))
+			if tx.BlockHash != block.Hash() || tx.Hash != block.Transactions()[tx.Index].Hash() {
+				t.Errorf("test %d: block #%d: transaction mismatch", i, tx.BlockNumber)
+			}
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// Page through the transactions of the bank and ensure all are returned
+	var (
+		have   []uint64
+		cursor *hexutil.Bytes
+		limit  = hexutil.Uint(2)
+	)
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("pagination did not terminate")
+		}
+		page, err := api.GetTransactionsByAddress(context.Background(), testBank, rpc.EarliestBlockNumber, rpc.LatestBlockNumber, cursor, &limit)
+		if err != nil {
+			t.Fatalf("page %d: failed to retrieve transactions: %v", pages, err)
+		}
+		if len(page.Transactions) > int(limit) {
+			t.Fatalf("page %d: too many transactions: have %d, want at most %d", pages, len(page.Transactions), limit)
+		}
+		for _, tx := range page.Transactions {
+			have = append(have, uint64(tx.BlockNumber))
+		}
+		if cursor = page.Cursor; cursor == nil {
+			break
+		}
+	}
+	if want := []uint64{3, 10, 12, 25, 42, 43}; !reflect.DeepEqual(have, want) {
+		t.Errorf("paginated blocks mismatch: have %v, want %v", have, want)
+	}
+	// Ensure cursors of reorged blocks are rejected
+	enc, _ := rlp.EncodeToBytes(&addressTxsCursor{Number: 12, Hash: common.HexToHash("0x01")})
+	if _, err := api.GetTransactionsByAddress(context.Background(), testBank, rpc.EarliestBlockNumber, rpc.LatestBlockNumber, (*hexutil.Bytes)(&enc), nil); err != errAddressTxsCursorReorged {
+		t.Fatalf("reorged cursor error mismatch: have %v, want %v", err, errAddressTxsCursorReorged)
+	}
+}
+
+// Tests that queries reaching too far into the blocks not yet indexed are
+// rejected instead of scanning all of them.
+func TestTransactionsByAddressUnindexed(t *testing.T) {
+	recipient := common.HexToAddress("0xdeadbeef")
+	ccm := newArchiveTestChain(t, 33, nil, sendAt(recipient, 3, 30))
+	defer ccm.blockchain.Stop()
+
+	txs := &addressTxs{
+		db:       ccm.chainDb,
+		chain:    ccm.blockchain,
+		indexer:  NewAddressTxIndexer(ccm.chainDb, ccm.blockchain, false, 8, 4),
+		size:     8,
+		confirms: 4,
+	}
+	if _, err := txs.find(context.Background(), recipient, 0, 0, 33, 10); err == nil {
+		t.Fatalf("query of unindexed blocks accepted")
+	}
+	have, err := txs.find(context.Background(), recipient, 22, 0, 33, 10)
+	if err != nil {
+		t.Fatalf("failed to query recent transactions: %v", err)
+	}
+	if len(have) != 1 || have[0].number != 30 {
+		t.Fatalf("transactions mismatch: have %v, want block #30", have)
+	}
+}
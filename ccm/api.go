@@ -531,3 +531,193 @@ func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Bloc
 	}
 	return dirty, nil
 }
+
+// PublicAccountHistoryAPI provides access to the balance and nonce history of
+// accounts, as recorded by the account history index.
+type PublicAccountHistoryAPI struct {
+	ccm *Ccmchain
+}
+
+// NewPublicAccountHistoryAPI creates a new API definition for the account
+// history index of a full node.
+func NewPublicAccountHistoryAPI(ccm *Ccmchain) *PublicAccountHistoryAPI {
+	return &PublicAccountHistoryAPI{ccm: ccm}
+}
+
+// AccountChange is the balance and nonce of an account right after a block
+// which changed either of them.
+type AccountChange struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	Balance     *hexutil.Big   `json:"balance"`
+	Nonce       hexutil.Uint64 `json:"nonce"`
+}
+
+// GetAccountHistory returns the canonical blocks within the given range which
+// changed the balance or nonce of an account, along with their values after
+// each of them.
+func (api *PublicAccountHistoryAPI) GetAccountHistory(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber) ([]*AccountChange, error) {
+	head := api.ccm.blockchain.CurrentBlock().NumberU64()
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number < 0 || uint64(number) > head {
+			return head // latest and pending both resolve to the current head
+		}
+		return uint64(number)
+	}
+	from, to := resolve(fromBlock), resolve(toBlock)
+	if from > to {
+		return nil, fmt.Errorf("invalid block range #%d-#%d", from, to)
+	}
+	blocks, err := api.ccm.accountHistory.changes(ctx, address, from, to)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]*AccountChange, 0, len(blocks))
+	for _, number := range blocks {
+		account, err := api.ccm.accountHistory.accountAt(address, number)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, &AccountChange{
+			BlockNumber: hexutil.Uint64(number),
+			BlockHash:   rawdb.ReadCanonicalHash(api.ccm.chainDb, number),
+			Balance:     (*hexutil.Big)(account.Balance),
+			Nonce:       hexutil.Uint64(account.Nonce),
+		})
+	}
+	return changes, nil
+}
+
+// RPCParamNames names the parameters of the API methods for service discovery.
+func (api *PublicAccountHistoryAPI) RPCParamNames() map[string][]string {
+	return map[string][]string{
+		"getAccountHistory": {"address", "fromBlock", "toBlock"},
+	}
+}
+
+// PublicAddressTxsAPI provides access to the transactions of addresses, as
+// recorded by the address transaction index.
+type PublicAddressTxsAPI struct {
+	ccm *Ccmchain
+}
+
+// NewPublicAddressTxsAPI creates a new API definition for the address
+// transaction index of a full node.
+func NewPublicAddressTxsAPI(ccm *Ccmchain) *PublicAddressTxsAPI {
+	return &PublicAddressTxsAPI{ccm: ccm}
+}
+
+// AddressTx is a transaction an address took part in, along with the roles the
+// address had in it.
+type AddressTx struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	Hash        common.Hash    `json:"transactionHash"`
+	Index       hexutil.Uint64 `json:"transactionIndex"`
+	Sender      bool           `json:"sender"`
+	Recipient   bool           `json:"recipient"`
+	Internal    bool           `json:"internal"`
+}
+
+// AddressTxsPage is a batch of transactions returned by
+// ccm_getTransactionsByAddress, along with the cursor to retrieve the next
+// batch with. The cursor is nil once all the transactions have been returned.
+type AddressTxsPage struct {
+	Transactions []*AddressTx   `json:"transactions"`
+	Cursor       *hexutil.Bytes `json:"cursor"`
+}
+
+// addressTxsCursor is the position within an address transaction query from
+// which a paginated search resumes. It is handed out to clients as an opaque RLP
+// encoded token.
+type addressTxsCursor struct {
+	Number uint64      // Number of the block to resume from
+	Hash   common.Hash // Hash of the block to resume from, used to detect reorgs
+	Index  uint64      // Index within the block of the first transaction not yet returned
+}
+
+// GetTransactionsByAddress returns a batch of the transactions within the given
+// block range which were sent by or to an address, or called it internally if
+// the node traces internal calls. The batch starts at the position identified
+// by the given cursor, or at the beginning of the range if no cursor is given.
+// The returned page holds the cursor to resume the query from, until all the
+// transactions have been returned.
+//
+// Internal calls are only reported for blocks already covered by the index.
+func (api *PublicAddressTxsAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, cursor *hexutil.Bytes, limit *hexutil.Uint) (*AddressTxsPage, error) {
+	// Figure out the number of transactions to return in this page
+	size := defaultAddressTxsPage
+	if limit != nil && *limit > 0 && int(*limit) < size {
+		size = int(*limit)
+	}
+	// Resolve the block range and the position to resume the search from
+	head := api.ccm.blockchain.CurrentBlock().NumberU64()
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number < 0 || uint64(number) > head {
+			return head // latest and pending both resolve to the current head
+		}
+		return uint64(number)
+	}
+	from, to := resolve(fromBlock), resolve(toBlock)
+	if from > to {
+		return nil, fmt.Errorf("invalid block range #%d-#%d", from, to)
+	}
+	var index uint64
+	if cursor != nil {
+		pos := new(addressTxsCursor)
+		if err := rlp.DecodeBytes(*cursor, pos); err != nil {
+			return nil, errInvalidAddressTxsCursor
+		}
+		if pos.Number < from || pos.Number > to {
+			return nil, errInvalidAddressTxsCursor
+		}
+		if rawdb.ReadCanonicalHash(api.ccm.chainDb, pos.Number) != pos.Hash {
+			return nil, errAddressTxsCursorReorged
+		}
+		from, index = pos.Number, pos.Index
+	}
+	txs, err := api.ccm.addressTxs.find(ctx, address, from, index, to, size+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &AddressTxsPage{Transactions: make([]*AddressTx, 0, len(txs))}
+
+	// Assemble the cursor to the next page, if there's anything left
+	if len(txs) > size {
+		next := &addressTxsCursor{
+			Number: txs[size].number,
+			Hash:   rawdb.ReadCanonicalHash(api.ccm.chainDb, txs[size].number),
+			Index:  txs[size].index,
+		}
+		enc, err := rlp.EncodeToBytes(next)
+		if err != nil {
+			return nil, err
+		}
+		page.Cursor = (*hexutil.Bytes)(&enc)
+		txs = txs[:size]
+	}
+	for _, tx := range txs {
+		hash := rawdb.ReadCanonicalHash(api.ccm.chainDb, tx.number)
+		body := api.ccm.blockchain.GetBody(hash)
+		if body == nil || tx.index >= uint64(len(body.Transactions)) {
+			return nil, fmt.Errorf("transaction %d of block #%d not found", tx.index, tx.number)
+		}
+		page.Transactions = append(page.Transactions, &AddressTx{
+			BlockNumber: hexutil.Uint64(tx.number),
+			BlockHash:   hash,
+			Hash:        body.Transactions[tx.index].Hash(),
+			Index:       hexutil.Uint64(tx.index),
+			Sender:      tx.roles&addressTxSender != 0,
+			Recipient:   tx.roles&addressTxRecipient != 0,
+			Internal:    tx.roles&addressTxInternal != 0,
+		})
+	}
+	return page, nil
+}
+
+// RPCParamNames names the parameters of the API methods for service discovery.
+func (api *PublicAddressTxsAPI) RPCParamNames() map[string][]string {
+	return map[string][]string{
+		"getTransactionsByAddress": {"address", "fromBlock", "toBlock", "cursor", "limit"},
+	}
+}
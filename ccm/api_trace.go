@@ -0,0 +1,362 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ccmchain/go-ccmchain/ccm/tracers/native"
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/core/state"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+const (
+	// maxTraceFilterBlocks is the maximum number of blocks a single trace_filter
+	// request is allowed to span, as each of them needs to be re-executed.
+	maxTraceFilterBlocks = 100
+)
+
+// PrivateTraceAPI is the collection of Parity style tracing APIs, reporting the
+// calls made by transactions as flat lists of traces.
+type PrivateTraceAPI struct {
+	ccm   *Ccmchain
+	debug *PrivateDebugAPI
+}
+
+// NewPrivateTraceAPI creates a new API definition for the Parity style tracing
+// methods of the Ccmchain service.
+func NewPrivateTraceAPI(ccm *Ccmchain) *PrivateTraceAPI {
+	return &PrivateTraceAPI{ccm: ccm, debug: NewPrivateDebugAPI(ccm)}
+}
+
+// TraceFilterArgs are the criteria of a trace_filter request.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// TraceResults is the outcome of replaying a single transaction, containing the
+// trace types requested by the caller.
+type TraceResults struct {
+	Output          hexutil.Bytes           `json:"output"`
+	StateDiff       native.StateDiff        `json:"stateDiff"`
+	Trace           []*native.FlatCallFrame `json:"trace"`
+	TransactionHash *common.Hash            `json:"transactionHash,omitempty"`
+	VmTrace         interface{}             `json:"vmTrace"`
+}
+
+// txTrace is the result of tracing a single transaction.
+type txTrace struct {
+	output []byte
+	calls  []*native.FlatCallFrame
+	diff   native.StateDiff
+}
+
+// Block returns the flat call traces of all the transactions in a block.
+func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]*native.FlatCallFrame, error) {
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := api.traceBlock(ctx, block, false)
+	if err != nil {
+		return nil, err
+	}
+	frames := []*native.FlatCallFrame{}
+	for _, trace := range traces {
+		frames = append(frames, trace.calls...)
+	}
+	return frames, nil
+}
+
+// Transaction returns the flat call traces of a single transaction.
+func (api *PrivateTraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*native.FlatCallFrame, error) {
+	tx, blockHash, _, index := rawdb.ReadTransaction(api.ccm.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	block := api.ccm.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	msg, vmctx, statedb, err := api.debug.computeTxEnv(blockHash, int(index), defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	trace, err := api.traceTx(ctx, msg, vmctx, statedb, false)
+	if err != nil {
+		return nil, err
+	}
+	annotateFrames(trace.calls, block, int(index))
+	return trace.calls, nil
+}
+
+// Filter returns the flat call traces matching the given criteria over a range
+// of blocks. A trace matches if it originates from any of the from addresses and
+// targets any of the to addresses; empty address lists match everything.
+func (api *PrivateTraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]*native.FlatCallFrame, error) {
+	from, to := rpc.LatestBlockNumber, rpc.LatestBlockNumber
+	if args.FromBlock != nil {
+		from = *args.FromBlock
+	}
+	if args.ToBlock != nil {
+		to = *args.ToBlock
+	}
+	start, err := api.blockByNumber(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := api.blockByNumber(to)
+	if err != nil {
+		return nil, err
+	}
+	if start.NumberU64() > end.NumberU64() {
+		return nil, fmt.Errorf("invalid block range #%d-#%d", start.NumberU64(), end.NumberU64())
+	}
+	if blocks := end.NumberU64() - start.NumberU64() + 1; blocks > maxTraceFilterBlocks {
+		return nil, fmt.Errorf("block range too large: %d > %d", blocks, maxTraceFilterBlocks)
+	}
+	var (
+		frames  = []*native.FlatCallFrame{}
+		skipped uint64
+	)
+	for number := start.NumberU64(); number <= end.NumberU64(); number++ {
+		block := end
+		if number != end.NumberU64() {
+			if block = api.ccm.blockchain.GetBlockByNumber(number); block == nil {
+				return nil, fmt.Errorf("block #%d not found", number)
+			}
+		}
+		traces, err := api.traceBlock(ctx, block, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			for _, frame := range trace.calls {
+				if !args.matches(frame) {
+					continue
+				}
+				if args.After != nil && skipped < *args.After {
+					skipped++
+					continue
+				}
+				frames = append(frames, frame)
+				if args.Count != nil && uint64(len(frames)) >= *args.Count {
+					return frames, nil
+				}
+			}
+		}
+	}
+	return frames, nil
+}
+
+// ReplayBlockTransactions replays all the transactions of a block, returning
+// the requested trace types for each of them. The supported trace types are
+// "trace" and "stateDiff".
+func (api *PrivateTraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]*TraceResults, error) {
+	var calls, diff bool
+	for _, typ := range traceTypes {
+		switch typ {
+		case "trace":
+			calls = true
+		case "stateDiff":
+			diff = true
+		case "vmTrace":
+			return nil, errors.New("vmTrace is not supported")
+		default:
+			return nil, fmt.Errorf("unknown trace type %q", typ)
+		}
+	}
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := api.traceBlock(ctx, block, diff)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*TraceResults, len(traces))
+	for i, trace := range traces {
+		hash := block.Transactions()[i].Hash()
+		results[i] = &TraceResults{
+			Output:          trace.output,
+			TransactionHash: &hash,
+		}
+		if calls {
+			// Replayed traces are identified by their position in the result
+			// list, so they don't carry the block and transaction fields.
+			for _, frame := range trace.calls {
+				frame.BlockHash, frame.BlockNumber = nil, nil
+				frame.TransactionHash, frame.TransactionPosition = nil, nil
+			}
+			results[i].Trace = trace.calls
+		}
+		if diff {
+			results[i].StateDiff = trace.diff
+		}
+	}
+	return results, nil
+}
+
+// matches returns whether a flat call trace satisfies the address criteria of
+// the filter.
+func (args *TraceFilterArgs) matches(frame *native.FlatCallFrame) bool {
+	if len(args.FromAddress) > 0 {
+		from := frame.Action.From
+		if from == nil {
+			from = frame.Action.Address
+		}
+		if !containsAddress(args.FromAddress, from) {
+			return false
+		}
+	}
+	if len(args.ToAddress) > 0 {
+		to := frame.Action.To
+		switch {
+		case frame.Action.RefundAddress != nil:
+			to = frame.Action.RefundAddress
+		case frame.Result != nil && frame.Result.Address != nil:
+			to = frame.Result.Address
+		}
+		if !containsAddress(args.ToAddress, to) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAddress returns whether the address is contained in the list.
+func containsAddress(addrs []common.Address, addr *common.Address) bool {
+	if addr == nil {
+		return false
+	}
+	for _, a := range addrs {
+		if a == *addr {
+			return true
+		}
+	}
+	return false
+}
+
+// blockByNumber retrieves a block from the local chain, resolving the pending
+// and latest tags.
+func (api *PrivateTraceAPI) blockByNumber(number rpc.BlockNumber) (*types.Block, error) {
+	var block *types.Block
+
+	switch number {
+	case rpc.PendingBlockNumber:
+		block = api.ccm.miner.PendingBlock()
+	case rpc.LatestBlockNumber:
+		block = api.ccm.blockchain.CurrentBlock()
+	default:
+		block = api.ccm.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return block, nil
+}
+
+// traceBlock traces all the transactions of a block, annotating their call
+// traces with the block and transaction they belong to.
+func (api *PrivateTraceAPI) traceBlock(ctx context.Context, block *types.Block, diff bool) ([]*txTrace, error) {
+	results, err := api.debug.traceBlockTxs(block, defaultTraceReexec, func(index int, msg core.Message, vmctx vm.Context, statedb *state.StateDB) (interface{}, error) {
+		return api.traceTx(ctx, msg, vmctx, statedb, diff)
+	})
+	if err != nil {
+		return nil, err
+	}
+	traces := make([]*txTrace, len(results))
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %#x failed: %v", block.Transactions()[i].Hash(), result.Error)
+		}
+		traces[i] = result.Result.(*txTrace)
+		annotateFrames(traces[i].calls, block, i)
+	}
+	return traces, nil
+}
+
+// traceTx executes the given message in the provided environment, collecting
+// its flat call traces and, if requested, the state changes it made.
+func (api *PrivateTraceAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, diff bool) (*txTrace, error) {
+	var (
+		calls  = native.NewFlatCallTracer()
+		tracer = native.NewMuxTracer(calls)
+		differ *native.StateDiffTracer
+		pre    *state.StateDB
+	)
+	if diff {
+		differ = native.NewStateDiffTracer(vmctx.Coinbase)
+		tracer = append(tracer, differ)
+		pre = statedb.Copy()
+	}
+	vmenv := vm.NewEVM(vmctx, statedb, api.ccm.blockchain.Config(), vm.Config{Debug: true, Tracer: tracer})
+
+	// Handle timeouts and RPC cancellations
+	deadlineCtx, cancel := context.WithTimeout(ctx, defaultTraceTimeout)
+	go func() {
+		<-deadlineCtx.Done()
+		vmenv.Cancel()
+	}()
+	defer cancel()
+
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	if err := deadlineCtx.Err(); err != nil {
+		return nil, fmt.Errorf("tracing aborted: %v", err)
+	}
+	frames, err := calls.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	trace := &txTrace{output: result.ReturnData, calls: frames}
+	if diff {
+		statedb.Finalise(vmenv.ChainConfig().IsEIP158(vmctx.BlockNumber))
+		trace.diff = differ.Diff(pre, statedb)
+	}
+	return trace, nil
+}
+
+// annotateFrames sets the block and transaction fields of the flat call traces
+// of the index-th transaction of a block.
+func annotateFrames(frames []*native.FlatCallFrame, block *types.Block, index int) {
+	var (
+		blockHash   = block.Hash()
+		blockNumber = block.NumberU64()
+		txHash      = block.Transactions()[index].Hash()
+		txPosition  = uint64(index)
+	)
+	for _, frame := range frames {
+		frame.BlockHash, frame.BlockNumber = &blockHash, &blockNumber
+		frame.TransactionHash, frame.TransactionPosition = &txHash, &txPosition
+	}
+}
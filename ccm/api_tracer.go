@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -30,6 +31,7 @@ import (
 
 	"github.com/ccmchain/go-ccmchain/common"
 	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/common/math"
 	"github.com/ccmchain/go-ccmchain/core"
 	"github.com/ccmchain/go-ccmchain/core/rawdb"
 	"github.com/ccmchain/go-ccmchain/core/state"
@@ -52,14 +54,30 @@ const (
 	// and reexecute to produce missing historical state necessary to run a specific
 	// trace.
 	defaultTraceReexec = uint64(128)
+
+	// maxTraceNewBlocksBacklog is the number of imported blocks a traceNewBlocks
+	// subscription is allowed to lag behind the chain head before it is dropped.
+	maxTraceNewBlocksBacklog = 16
 )
 
 // TraceConfig holds extra parameters to trace functions.
 type TraceConfig struct {
 	*vm.LogConfig
-	Tracer  *string
-	Timeout *string
-	Reexec  *uint64
+	Tracer       *string
+	TracerConfig json.RawMessage
+	Timeout      *string
+	Reexec       *uint64
+}
+
+// TraceCallConfig holds extra parameters to the call trace function, extending
+// the regular trace parameters with state overrides to apply before the call.
+type TraceCallConfig struct {
+	*vm.LogConfig
+	Tracer         *string
+	TracerConfig   json.RawMessage
+	Timeout        *string
+	Reexec         *uint64
+	StateOverrides *ccmapi.StateOverride
 }
 
 // StdTraceConfig holds extra parameters to standard-json trace functions.
@@ -92,6 +110,18 @@ type blockTraceResult struct {
 	Traces []*txTraceResult `json:"traces"` // Trace results produced by the task
 }
 
+// newBlockTxTraceResult is the trace of a single transaction of a newly imported
+// block, as pushed by traceNewBlocks subscriptions. If the entire block failed to
+// trace, the transaction fields are omitted.
+type newBlockTxTraceResult struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxIndex     *hexutil.Uint  `json:"transactionPosition,omitempty"`
+	TxHash      *common.Hash   `json:"transactionHash,omitempty"`
+	Result      interface{}    `json:"result,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
 // txTraceTask represents a single transaction trace task when an entire block
 // is being traced.
 type txTraceTask struct {
@@ -134,6 +164,107 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 	return api.traceChain(ctx, from, to, config)
 }
 
+// TraceNewBlocks subscribes to the chain head, tracing the transactions of every
+// newly imported block with the requested tracer and pushing the result of each
+// transaction as a separate notification. Blocks are traced on top of the state
+// the chain already computed for their parents.
+//
+// Tracing is decoupled from block import: if the subscriber falls more than a
+// few blocks behind, either because tracing is too slow or because the client
+// doesn't consume the notifications fast enough, the subscription is dropped.
+func (api *PrivateDebugAPI) TraceNewBlocks(ctx context.Context, config *TraceConfig) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	var (
+		chainCh  = make(chan core.ChainEvent, maxTraceNewBlocksBacklog)
+		chainSub = api.ccm.blockchain.SubscribeChainEvent(chainCh)
+		blocks   = make(chan *types.Block, maxTraceNewBlocksBacklog)
+	)
+	traceCtx, cancel := context.WithCancel(context.Background())
+
+	// Queue up the imported blocks, never blocking the chain event feed
+	go func() {
+		defer chainSub.Unsubscribe()
+		defer cancel()
+
+		for {
+			select {
+			case ev := <-chainCh:
+				select {
+				case blocks <- ev.Block:
+				default:
+					log.Warn("Dropping lagging block trace subscription", "id", sub.ID, "number", ev.Block.NumberU64(), "backlog", len(blocks))
+					return
+				}
+			case <-traceCtx.Done():
+				return
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-chainSub.Err():
+				return
+			}
+		}
+	}()
+	// Trace the queued blocks and stream the results to the user
+	go func() {
+		defer cancel()
+
+		for {
+			select {
+			case block := <-blocks:
+				for _, result := range api.traceNewBlock(traceCtx, block, config) {
+					if traceCtx.Err() != nil {
+						return
+					}
+					if err := notifier.Notify(sub.ID, result); err != nil {
+						log.Debug("Failed to deliver block trace", "id", sub.ID, "err", err)
+						return
+					}
+				}
+			case <-traceCtx.Done():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// traceNewBlock traces all the transactions of a newly imported block, wrapping
+// the results in notifications for the traceNewBlocks subscription.
+func (api *PrivateDebugAPI) traceNewBlock(ctx context.Context, block *types.Block, config *TraceConfig) []*newBlockTxTraceResult {
+	var (
+		number = hexutil.Uint64(block.NumberU64())
+		hash   = block.Hash()
+	)
+	traces, err := api.traceBlock(ctx, block, config)
+	if err != nil {
+		log.Warn("Failed to trace imported block", "number", block.NumberU64(), "hash", hash, "err", err)
+		return []*newBlockTxTraceResult{{BlockNumber: number, BlockHash: hash, Error: err.Error()}}
+	}
+	results := make([]*newBlockTxTraceResult, len(traces))
+	for i, trace := range traces {
+		var (
+			index  = hexutil.Uint(i)
+			txHash = block.Transactions()[i].Hash()
+		)
+		results[i] = &newBlockTxTraceResult{
+			BlockNumber: number,
+			BlockHash:   hash,
+			TxIndex:     &index,
+			TxHash:      &txHash,
+			Result:      trace.Result,
+			Error:       trace.Error,
+		}
+	}
+	return results
+}
+
 // traceChain configures a new tracer according to the provided configuration, and
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requested tracer.
@@ -442,6 +573,49 @@ func (api *PrivateDebugAPI) StandardTraceBadBlockToFile(ctx context.Context, has
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requestd tracer.
 func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block, config *TraceConfig) ([]*txTraceResult, error) {
+	// Short circuit if all the transactions were already traced
+	var (
+		txs     = block.Transactions()
+		results = make([]*txTraceResult, len(txs))
+	)
+	for i, tx := range txs {
+		result, ok := api.ccm.traceCache.get(tx.Hash(), block.Hash(), config)
+		if !ok {
+			results = nil
+			break
+		}
+		results[i] = &txTraceResult{Result: result}
+	}
+	if results != nil {
+		return results, nil
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	results, err := api.traceBlockTxs(block, reexec, func(index int, msg core.Message, vmctx vm.Context, statedb *state.StateDB) (interface{}, error) {
+		return api.traceTx(ctx, msg, vmctx, statedb, config)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Cache the successfully traced transactions
+	for i, result := range results {
+		if result.Error == "" {
+			api.ccm.traceCache.put(txs[i].Hash(), block.Hash(), config, result.Result)
+		}
+	}
+	return results, nil
+}
+
+// txTraceFn traces the execution of the index-th transaction of a block, given
+// as a message to run in the provided environment.
+type txTraceFn func(index int, msg core.Message, vmctx vm.Context, statedb *state.StateDB) (interface{}, error)
+
+// traceBlockTxs recreates the state of the block's parent, and traces all the
+// transactions of the block concurrently, each on top of the state left by its
+// predecessors, using the given trace function.
+func (api *PrivateDebugAPI) traceBlockTxs(block *types.Block, reexec uint64, trace txTraceFn) ([]*txTraceResult, error) {
 	// Create the parent state database
 	if err := api.ccm.engine.VerifyHeader(api.ccm.blockchain, block.Header(), true); err != nil {
 		return nil, err
@@ -450,10 +624,6 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 	if parent == nil {
 		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
 	}
-	reexec := defaultTraceReexec
-	if config != nil && config.Reexec != nil {
-		reexec = *config.Reexec
-	}
 	statedb, err := api.computeStateDB(parent, reexec)
 	if err != nil {
 		return nil, err
@@ -482,7 +652,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 				msg, _ := txs[task.index].AsMessage(signer)
 				vmctx := core.NewEVMContext(msg, block.Header(), api.ccm.blockchain, nil)
 
-				res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
+				res, err := trace(task.index, msg, vmctx, task.statedb)
 				if err != nil {
 					results[task.index] = &txTraceResult{Error: err.Error()}
 					continue
@@ -502,7 +672,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 		vmctx := core.NewEVMContext(msg, block.Header(), api.ccm.blockchain, nil)
 
 		vmenv := vm.NewEVM(vmctx, statedb, api.ccm.blockchain.Config(), vm.Config{})
-		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
 			failed = err
 			break
 		}
@@ -596,7 +766,7 @@ func (api *PrivateDebugAPI) standardTraceBlockToFile(ctx context.Context, block
 		}
 		// Execute the transaction and flush any traces to disk
 		vmenv := vm.NewEVM(vmctx, statedb, api.ccm.blockchain.Config(), vmConf)
-		_, _, _, err = core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+		_, err = core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
 		if writer != nil {
 			writer.Flush()
 		}
@@ -707,6 +877,10 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	if tx == nil {
 		return nil, fmt.Errorf("transaction %#x not found", hash)
 	}
+	// Short circuit if the transaction was already traced
+	if result, ok := api.ccm.traceCache.get(hash, blockHash, config); ok {
+		return result, nil
+	}
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
@@ -715,8 +889,65 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	if err != nil {
 		return nil, err
 	}
-	// Trace the transaction and return
-	return api.traceTx(ctx, msg, vmctx, statedb, config)
+	// Trace the transaction, caching the result
+	result, err := api.traceTx(ctx, msg, vmctx, statedb, config)
+	if err != nil {
+		return nil, err
+	}
+	api.ccm.traceCache.put(hash, blockHash, config, result)
+	return result, nil
+}
+
+// TraceCall traces the execution of a call, as if it was a transaction added on
+// top of the given block, without needing to sign or send it. The state of the
+// block can be customized with overrides before the call is executed.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ccmapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	// Retrieve the block and the state to execute the call on top of
+	var (
+		block   *types.Block
+		statedb *state.StateDB
+		err     error
+	)
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		block, statedb = api.ccm.miner.Pending()
+	} else {
+		if block, err = api.ccm.APIBackend.BlockByNumberOrHash(ctx, blockNrOrHash); err != nil {
+			return nil, err
+		}
+		if block != nil {
+			reexec := defaultTraceReexec
+			if config != nil && config.Reexec != nil {
+				reexec = *config.Reexec
+			}
+			if statedb, err = api.computeStateDB(block, reexec); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	// Apply the state overrides and assemble the trace config
+	var traceConfig *TraceConfig
+	if config != nil {
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			return nil, err
+		}
+		traceConfig = &TraceConfig{
+			LogConfig:    config.LogConfig,
+			Tracer:       config.Tracer,
+			TracerConfig: config.TracerConfig,
+			Timeout:      config.Timeout,
+			Reexec:       config.Reexec,
+		}
+	}
+	// Execute the call as a message on top of the block and trace it, funding
+	// the sender the same way ccm_call does
+	msg := args.ToMessage(api.ccm.APIBackend, api.ccm.APIBackend.RPCGasCap())
+	statedb.SetBalance(msg.From(), math.MaxBig256)
+	vmctx := core.NewEVMContext(msg, block.Header(), api.ccm.blockchain, nil)
+
+	return api.traceTx(ctx, msg, vmctx, statedb, traceConfig)
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
@@ -737,15 +968,18 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
+		// Constuct the native or JavaScript tracer to execute with
+		txTracer, err := tracers.NewTracer(*config.Tracer, vmctx, statedb, config.TracerConfig)
+		if err != nil {
 			return nil, err
 		}
+		tracer = txTracer
+
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			txTracer.Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 
@@ -758,21 +992,26 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	// Run the transaction with tracing enabled.
 	vmenv := vm.NewEVM(vmctx, statedb, api.ccm.blockchain.Config(), vm.Config{Debug: true, Tracer: tracer})
 
-	ret, gas, failed, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
 	}
 	// Depending on the tracer type, format and return the output
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
+		// If the result contains a revert reason, return it.
+		returnVal := fmt.Sprintf("%x", result.Return())
+		if len(result.Revert()) > 0 {
+			returnVal = fmt.Sprintf("%x", result.Revert())
+		}
 		return &ccmapi.ExecutionResult{
-			Gas:         gas,
-			Failed:      failed,
-			ReturnValue: fmt.Sprintf("%x", ret),
+			Gas:         result.UsedGas,
+			Failed:      result.Failed(),
+			ReturnValue: returnVal,
 			StructLogs:  ccmapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.TxTracer:
 		return tracer.GetResult()
 
 	default:
@@ -812,7 +1051,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 		}
 		// Not yet the searched for transaction, execute on top of the current state
 		vmenv := vm.NewEVM(context, statedb, api.ccm.blockchain.Config(), vm.Config{})
-		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
 			return nil, vm.Context{}, nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
 		}
 		// Ensure any modifications are committed to the state
@@ -0,0 +1,267 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/consensus/ccmash"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/internal/ccmapi"
+	"github.com/ccmchain/go-ccmchain/node"
+	"github.com/ccmchain/go-ccmchain/params"
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+// numberCode is the code of a contract returning the number of the block it is
+// executed in.
+var numberCode = common.FromHex("0x4360005260206000f3")
+
+// newTestCcmchain starts a node running a full Ccmchain service on top of the
+// given genesis, with a fake proof-of-work engine, and generates (but doesn't
+// import) the requested number of blocks on top of it.
+func newTestCcmchain(t *testing.T, genesis *core.Genesis, blocks int, generator func(int, *core.BlockGen)) (*node.Node, *Ccmchain, []*types.Block) {
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	var ccm *Ccmchain
+	stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		config := &Config{Genesis: genesis}
+		config.Ethash.PowMode = ccmash.ModeFake
+		ccm, err = New(ctx, config)
+		return ccm, err
+	})
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	db := rawdb.NewMemoryDatabase()
+	chain, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ccmash.NewFaker(), db, blocks, generator)
+
+	return stack, ccm, chain
+}
+
+// Tests that calls can be traced with the same defaults as ccm_call, on top of
+// historical and pending blocks and with state overrides applied.
+func TestTraceCall(t *testing.T) {
+	numberer := common.HexToAddress("0x0100")
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			testBank: {Balance: big.NewInt(1000000)},
+			numberer: {Code: numberCode, Balance: common.Big0},
+		},
+	}
+	stack, ccm, blocks := newTestCcmchain(t, genesis, 2, nil)
+	defer stack.Stop()
+
+	if _, err := ccm.blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	// Wait for the miner to assemble the pending block on top of the new head
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if block, _ := ccm.miner.Pending(); block != nil && block.NumberU64() == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pending block not assembled")
+		}
+	}
+	var (
+		api      = NewPrivateDebugAPI(ccm)
+		override = common.HexToAddress("0x0200")
+		code     = hexutil.Bytes(numberCode)
+	)
+	tests := []struct {
+		args   ccmapi.CallArgs
+		block  rpc.BlockNumber
+		config *TraceCallConfig
+		want   uint64
+	}{
+		// Default arguments, the zero sender paying for the default gas allowance
+		{args: ccmapi.CallArgs{To: &numberer}, block: rpc.LatestBlockNumber, want: 2},
+		{args: ccmapi.CallArgs{To: &numberer}, block: 1, want: 1},
+
+		// Calls executed on top of the pending block
+		{args: ccmapi.CallArgs{From: &testBank, To: &numberer}, block: rpc.PendingBlockNumber, want: 3},
+
+		// Code injected with state overrides
+		{
+			args:   ccmapi.CallArgs{To: &override},
+			block:  rpc.LatestBlockNumber,
+			config: &TraceCallConfig{StateOverrides: &ccmapi.StateOverride{override: ccmapi.OverrideAccount{Code: &code}}},
+			want:   2,
+		},
+	}
+	for i, tt := range tests {
+		res, err := api.TraceCall(context.Background(), tt.args, rpc.BlockNumberOrHashWithNumber(tt.block), tt.config)
+		if err != nil {
+			t.Fatalf("test %d: failed to trace call: %v", i, err)
+		}
+		result, ok := res.(*ccmapi.ExecutionResult)
+		if !ok {
+			t.Fatalf("test %d: result type mismatch: have %T, want %T", i, res, result)
+		}
+		if result.Failed {
+			t.Errorf("test %d: call failed", i)
+		}
+		if want := fmt.Sprintf("%064x", tt.want); result.ReturnValue != want {
+			t.Errorf("test %d: return value mismatch: have %s, want %s", i, result.ReturnValue, want)
+		}
+		if len(result.StructLogs) == 0 {
+			t.Errorf("test %d: no execution steps traced", i)
+		}
+	}
+}
+
+// Tests that the transactions of newly imported blocks are traced and streamed
+// to the subscribers, in the order they were imported.
+func TestTraceNewBlocks(t *testing.T) {
+	recipient := common.HexToAddress("0xdeadbeef")
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	stack, ccm, blocks := newTestCcmchain(t, genesis, 3, sendAt(recipient, 1, 3))
+	defer stack.Stop()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	server.RegisterName("debug", NewPrivateDebugAPI(ccm))
+
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	results := make(chan *newBlockTxTraceResult)
+	sub, err := client.Subscribe(context.Background(), "debug", results, "traceNewBlocks")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if _, err := ccm.blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	for _, want := range []*types.Block{blocks[0], blocks[2]} {
+		select {
+		case res := <-results:
+			if res.Error != "" {
+				t.Fatalf("block #%d: trace failed: %s", res.BlockNumber, res.Error)
+			}
+			if uint64(res.BlockNumber) != want.NumberU64() || res.BlockHash != want.Hash() {
+				t.Fatalf("block mismatch: have #%d [%x], want #%d [%x]", res.BlockNumber, res.BlockHash, want.NumberU64(), want.Hash())
+			}
+			if res.TxHash == nil || *res.TxHash != want.Transactions()[0].Hash() {
+				t.Errorf("block #%d: transaction hash mismatch: have %v, want %x", res.BlockNumber, res.TxHash, want.Transactions()[0].Hash())
+			}
+			if res.Result == nil {
+				t.Errorf("block #%d: missing trace result", res.BlockNumber)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("block #%d: trace not delivered", want.NumberU64())
+		}
+	}
+}
+
+// Tests that a subscriber not consuming its notifications is dropped once it
+// falls too far behind, without ever blocking the import of new blocks.
+func TestTraceNewBlocksStalled(t *testing.T) {
+	recipient := common.HexToAddress("0xdeadbeef")
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000000000000)}},
+	}
+	var numbers []int
+	for i := 1; i <= 3*maxTraceNewBlocksBacklog; i++ {
+		numbers = append(numbers, i)
+	}
+	stack, ccm, blocks := newTestCcmchain(t, genesis, len(numbers), sendAt(recipient, numbers...))
+	defer stack.Stop()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	server.RegisterName("debug", NewPrivateDebugAPI(ccm))
+
+	// Subscribe over a raw connection, so the test controls when the
+	// notifications are read
+	p1, p2 := net.Pipe()
+	defer p2.Close()
+	go server.ServeCodec(rpc.NewJSONCodec(p1), rpc.OptionMethodInvocation|rpc.OptionSubscriptions)
+
+	if _, err := p2.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"debug_subscribe","params":["traceNewBlocks"]}`)); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	var (
+		dec  = json.NewDecoder(p2)
+		resp struct {
+			Result string
+			Params struct {
+				Result newBlockTxTraceResult
+			}
+		}
+	)
+	if err := dec.Decode(&resp); err != nil || resp.Result == "" {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	// Import the first half of the blocks without reading any notifications
+	// and ensure the import is not held up by the stalled subscriber
+	half := len(blocks) / 2
+	done := make(chan error)
+	go func() {
+		_, err := ccm.blockchain.InsertChain(blocks[:half])
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to import blocks: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("block import blocked by stalled subscriber")
+	}
+	// Import the rest of the blocks after the subscription was dropped and
+	// ensure none of them are delivered
+	if _, err := ccm.blockchain.InsertChain(blocks[half:]); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	var delivered int
+	for {
+		p2.SetReadDeadline(time.Now().Add(time.Second))
+		if err := dec.Decode(&resp); err != nil {
+			break
+		}
+		if number := int(resp.Params.Result.BlockNumber); number > half {
+			t.Fatalf("block #%d traced after subscription was dropped", number)
+		}
+		delivered++
+	}
+	if delivered > maxTraceNewBlocksBacklog+1 {
+		t.Fatalf("stalled subscriber delivered %d traces, want at most %d", delivered, maxTraceNewBlocksBacklog+1)
+	}
+}
@@ -87,6 +87,10 @@ type Ccmchain struct {
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
 
+	accountHistory *accountHistory // Account balance and nonce history index, nil if disabled
+	addressTxs     *addressTxs     // Address to transaction index, nil if disabled
+	traceCache     *traceCache     // On-disk cache of tracing results, nil if disabled
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -122,6 +126,12 @@ func New(ctx *node.ServiceContext, config *Config) (*Ccmchain, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
+	if config.AccountHistory && !config.NoPruning {
+		return nil, errors.New("account history index requires an archive node (no state pruning)")
+	}
+	if config.AddressTxIndex && config.AddressTxIndexInternal && !config.NoPruning {
+		return nil, errors.New("indexing internal calls requires an archive node (no state pruning)")
+	}
 	if config.Miner.GasPrice == nil || config.Miner.GasPrice.Cmp(common.Big0) <= 0 {
 		log.Warn("Sanitizing invalid miner gas price", "provided", config.Miner.GasPrice, "updated", DefaultConfig.Miner.GasPrice)
 		config.Miner.GasPrice = new(big.Int).Set(DefaultConfig.Miner.GasPrice)
@@ -198,6 +208,20 @@ func New(ctx *node.ServiceContext, config *Config) (*Ccmchain, error) {
 	}
 	ccm.bloomIndexer.Start(ccm.blockchain)
 
+	if config.AccountHistory {
+		ccm.accountHistory = newAccountHistory(ccm.blockchain, chainDb, accountHistorySection, accountHistoryConfirms)
+	}
+	if config.AddressTxIndex {
+		ccm.addressTxs = newAddressTxs(ccm.blockchain, chainDb, config.AddressTxIndexInternal, addressTxsSection, addressTxsConfirms)
+	}
+
+	if config.TraceCache > 0 {
+		ccm.traceCache = newTraceCache(chainDb, uint64(config.TraceCache)*1024*1024)
+		ccm.traceCache.start(ccm.blockchain)
+	} else {
+		deleteTraceCache(chainDb)
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
@@ -290,6 +314,23 @@ func (s *Ccmchain) APIs() []rpc.API {
 		apis = append(apis, s.lesServer.APIs()...)
 	}
 
+	// Append the APIs of the optional indexes if they are maintained
+	if s.accountHistory != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "ccm",
+			Version:   "1.0",
+			Service:   NewPublicAccountHistoryAPI(s),
+			Public:    true,
+		})
+	}
+	if s.addressTxs != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "ccm",
+			Version:   "1.0",
+			Service:   NewPublicAddressTxsAPI(s),
+			Public:    true,
+		})
+	}
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -315,8 +356,11 @@ func (s *Ccmchain) APIs() []rpc.API {
 		}, {
 			Namespace: "ccm",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
-			Public:    true,
+			Service: filters.NewPublicFilterAPI(s.APIBackend, false, filters.Config{
+				LogBlockRange: s.config.RPCLogBlockRange,
+				LogResultCap:  s.config.RPCLogResultCap,
+			}),
+			Public: true,
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
@@ -330,6 +374,10 @@ func (s *Ccmchain) APIs() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   NewPrivateDebugAPI(s),
+		}, {
+			Namespace: "trace",
+			Version:   "1.0",
+			Service:   NewPrivateTraceAPI(s),
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -550,6 +598,13 @@ func (s *Ccmchain) Start(srvr *p2p.Server) error {
 // Ccmchain protocol.
 func (s *Ccmchain) Stop() error {
 	s.bloomIndexer.Close()
+	if s.accountHistory != nil {
+		s.accountHistory.close()
+	}
+	if s.addressTxs != nil {
+		s.addressTxs.close()
+	}
+	s.traceCache.stop()
 	s.blockchain.Stop()
 	s.engine.Close()
 	s.protocolManager.Stop()
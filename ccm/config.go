@@ -98,6 +98,10 @@ type Config struct {
 	NoPruning  bool // Whccmer to disable pruning and flush everything to disk
 	NoPrefetch bool // Whccmer to disable prefetching and only load state on demand
 
+	AccountHistory         bool `toml:",omitempty"` // Whccmer to index the balance and nonce history of accounts (archive only)
+	AddressTxIndex         bool `toml:",omitempty"` // Whccmer to index the transactions sent by and to each address
+	AddressTxIndexInternal bool `toml:",omitempty"` // Whccmer to also index the targets of internal calls (archive only)
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
@@ -122,6 +126,8 @@ type Config struct {
 	TrieDirtyCache int
 	TrieTimeout    time.Duration
 
+	TraceCache int `toml:",omitempty"` // Megabytes of disk space to cache tracing results in (0 = disabled, deleting any cached results)
+
 	// Mining options
 	Miner miner.Config
 
@@ -149,6 +155,14 @@ type Config struct {
 	// RPCGasCap is the global gas cap for ccm-call variants.
 	RPCGasCap *big.Int `toml:",omitempty"`
 
+	// RPCLogBlockRange is the maximum number of blocks a single ccm_getLogs
+	// query may span (0 = unlimited).
+	RPCLogBlockRange uint64 `toml:",omitempty"`
+
+	// RPCLogResultCap is the maximum number of logs a single ccm_getLogs
+	// query may return (0 = unlimited).
+	RPCLogResultCap int `toml:",omitempty"`
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
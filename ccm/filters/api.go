@@ -31,6 +31,9 @@ import (
 	"github.com/ccmchain/go-ccmchain/core/types"
 	"github.com/ccmchain/go-ccmchain/ccmdb"
 	"github.com/ccmchain/go-ccmchain/event"
+	"github.com/ccmchain/go-ccmchain/internal/ccmapi"
+	"github.com/ccmchain/go-ccmchain/log"
+	"github.com/ccmchain/go-ccmchain/rlp"
 	"github.com/ccmchain/go-ccmchain/rpc"
 )
 
@@ -38,6 +41,79 @@ var (
 	deadline = 5 * time.Minute // consider a filter inactive if it has not been polled for within deadline
 )
 
+const (
+	// defaultLogsPageSize is the number of logs returned by a single
+	// ccm_getLogsPage request if neither the client nor the node limits it.
+	defaultLogsPageSize = 1000
+
+	// defaultReplayWindow is the number of blocks searched at once when a log
+	// subscription replays historical logs, if the node doesn't cap the range.
+	defaultReplayWindow = 1000
+
+	// errcodeLimitExceeded is the JSON-RPC error code returned if a log
+	// query exceeds the limits configured on the node. Clients exceeding
+	// their call rate are rejected by the RPC server with -32006 instead.
+	errcodeLimitExceeded = -32005
+)
+
+var (
+	errInvalidCursor = errors.New("invalid log cursor")
+	errCursorReorged = errors.New("log cursor invalidated by chain reorganisation")
+	errUnknownBlock  = errors.New("unknown block")
+)
+
+// Config holds the limits imposed on the log queries served by the filter API.
+type Config struct {
+	LogBlockRange uint64 // Maximum number of blocks a single log query may span (0 = unlimited)
+	LogResultCap  int    // Maximum number of logs a single log query may return (0 = unlimited)
+}
+
+// limitError is returned if a log query exceeds the configured limits. If a
+// narrower block range exists that would satisfy the limits, it is reported
+// to the client in the error data.
+type limitError struct {
+	msg      string
+	from, to uint64 // Block range to retry the query with
+	retry    bool   // Whether the retry range is set
+}
+
+func (e *limitError) Error() string {
+	if !e.retry {
+		return e.msg
+	}
+	return fmt.Sprintf("%s, retry with block range [%d, %d]", e.msg, e.from, e.to)
+}
+
+func (e *limitError) ErrorCode() int {
+	return errcodeLimitExceeded
+}
+
+func (e *limitError) ErrorData() interface{} {
+	if !e.retry {
+		return nil
+	}
+	return map[string]hexutil.Uint64{
+		"fromBlock": hexutil.Uint64(e.from),
+		"toBlock":   hexutil.Uint64(e.to),
+	}
+}
+
+// logCursor is the position within a log query from which a paginated search
+// resumes. It is handed out to clients as an opaque RLP encoded token.
+type logCursor struct {
+	Number uint64      // Number of the block to resume from
+	Hash   common.Hash // Hash of the block to resume from, used to detect reorgs
+	Index  uint        // Index within the block of the first log not yet returned
+}
+
+// LogsPage is a batch of logs returned by ccm_getLogsPage, along with the
+// cursor to retrieve the next batch with. The cursor is nil once all the
+// matching logs have been returned.
+type LogsPage struct {
+	Logs   []*types.Log   `json:"logs"`
+	Cursor *hexutil.Bytes `json:"cursor"`
+}
+
 // filter is a helper struct that holds meta information over the filter type
 // and associated subscription in the event system.
 type filter struct {
@@ -59,11 +135,14 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+	config    Config
 }
 
-// NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
+// NewPublicFilterAPI returns a new PublicFilterAPI instance, serving log queries
+// within the given limits.
+func NewPublicFilterAPI(backend Backend, lightMode bool, config Config) *PublicFilterAPI {
 	api := &PublicFilterAPI{
+		config:  config,
 		backend: backend,
 		mux:     backend.EventMux(),
 		chainDb: backend.ChainDb(),
@@ -75,6 +154,21 @@ func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
 	return api
 }
 
+// RPCParamNames names the parameters of the API methods and subscriptions for
+// service discovery.
+func (api *PublicFilterAPI) RPCParamNames() map[string][]string {
+	return map[string][]string{
+		"newPendingTransactions": {"filter"},
+		"logs":                   {"criteria", "lastSeen"},
+		"newFilter":              {"criteria"},
+		"getLogs":                {"criteria"},
+		"getLogsPage":            {"criteria", "cursor", "limit"},
+		"uninstallFilter":        {"id"},
+		"getFilterLogs":          {"id"},
+		"getFilterChanges":       {"id"},
+	}
+}
+
 // timeoutLoop runs every 5 minutes and deletes filters that have not been recently used.
 // Tt is started when the api is created.
 func (api *PublicFilterAPI) timeoutLoop() {
@@ -133,13 +227,47 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	return pendingTxSub.ID
 }
 
+// PendingTxArgs are the options of a newPendingTransactions subscription. Empty
+// address and selector lists match any transaction.
+type PendingTxArgs struct {
+	FullTx    bool             `json:"fullTx"`    // stream full transactions instead of hashes
+	From      []common.Address `json:"from"`      // senders to match
+	To        []common.Address `json:"to"`        // recipients to match
+	Selectors []hexutil.Bytes  `json:"selectors"` // 4 byte method selectors to match
+}
+
+// query converts the arguments into the criteria of the event system.
+func (args *PendingTxArgs) query() (ccmchain.PendingTxQuery, error) {
+	q := ccmchain.PendingTxQuery{From: args.From, To: args.To}
+	for _, sel := range args.Selectors {
+		if len(sel) != 4 {
+			return q, fmt.Errorf("invalid method selector %v, want 4 bytes", sel)
+		}
+		var selector [4]byte
+		copy(selector[:], sel)
+		q.Selectors = append(q.Selectors, selector)
+	}
+	return q, nil
+}
+
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+//
+// Without arguments the hashes of all new transactions are streamed. The optional
+// arguments stream full transactions and/or restrict them to matching senders,
+// recipients and method selectors.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, args *PendingTxArgs) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
+	if args != nil && (args.FullTx || len(args.From) > 0 || len(args.To) > 0 || len(args.Selectors) > 0) {
+		crit, err := args.query()
+		if err != nil {
+			return nil, err
+		}
+		return api.filteredPendingTransactions(notifier, crit, args.FullTx), nil
+	}
 
 	rpcSub := notifier.CreateSubscription()
 
@@ -168,6 +296,38 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// filteredPendingTransactions streams the pending transactions matching crit, either
+// in full or as hashes.
+func (api *PublicFilterAPI) filteredPendingTransactions(notifier *rpc.Notifier, crit ccmchain.PendingTxQuery, fullTx bool) *rpc.Subscription {
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		txs := make(chan []*types.Transaction, 128)
+		pendingTxSub := api.events.SubscribeFilteredPendingTxs(crit, txs)
+
+		for {
+			select {
+			case matched := <-txs:
+				for _, tx := range matched {
+					if fullTx {
+						notifier.Notify(rpcSub.ID, ccmapi.NewRPCPendingTransaction(tx))
+					} else {
+						notifier.Notify(rpcSub.ID, tx.Hash())
+					}
+				}
+			case <-rpcSub.Err():
+				pendingTxSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				pendingTxSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with ccm_getFilterChanges.
 //
@@ -234,12 +394,44 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 }
 
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
-func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+//
+// If the criteria start at a specific block, the matching logs of the blocks
+// already in the chain are replayed first. Alternatively, a subscription may be
+// resumed after the last block seen by the client: if that block was reorganised
+// out of the canonical chain since, its logs and those of its non-canonical
+// ancestors are delivered again marked as removed, before the canonical chain is
+// replayed from the common ancestor. New logs are held back until the replay is
+// done, so that none are lost or delivered twice during the hand-over. Replays
+// are subject to the same block range and result limits as ccm_getLogs.
+func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria, lastSeen *common.Hash) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
-
+	// Figure out which historical logs to deliver ahead of the new ones
+	var (
+		removed []*types.Log
+		begin   = int64(-1)
+	)
+	if lastSeen != nil {
+		var err error
+		if removed, begin, err = api.unwindLogs(ctx, crit, *lastSeen); err != nil {
+			return nil, err
+		}
+	} else if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 {
+		begin = crit.FromBlock.Int64()
+	}
+	// Replayed blocks from the current head on may also show up as new logs if
+	// they are imported while the subscription is being installed
+	var head int64
+	if header, _ := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber); header != nil {
+		head = header.Number.Int64()
+	}
+	if begin >= 0 {
+		if err := api.checkReplay(ctx, crit, begin, head, len(removed)); err != nil {
+			return nil, err
+		}
+	}
 	var (
 		rpcSub      = notifier.CreateSubscription()
 		matchedLogs = make(chan []*types.Log)
@@ -251,18 +443,62 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	}
 
 	go func() {
+		defer logsSub.Unsubscribe()
 
+		for _, removedLog := range removed {
+			notifier.Notify(rpcSub.ID, removedLog)
+		}
+		var (
+			replayed    = make(chan []*types.Log)
+			done        = make(chan error, 1)
+			seen        = make(map[common.Hash]bool) // Replayed blocks that may arrive again as new logs
+			pending     [][]*types.Log               // New logs held back until the replay is done
+			ctx, cancel = context.WithCancel(context.Background())
+		)
+		defer cancel()
+
+		if begin >= 0 {
+			go func() { done <- api.replayLogs(ctx, crit, begin, replayed) }()
+		} else {
+			done <- nil
+		}
+		deliver := func(logs []*types.Log) {
+			for _, l := range logs {
+				if !l.Removed && seen[l.BlockHash] {
+					continue
+				}
+				notifier.Notify(rpcSub.ID, l)
+			}
+		}
 		for {
 			select {
+			case logs := <-replayed:
+				for _, l := range logs {
+					if int64(l.BlockNumber) >= head {
+						seen[l.BlockHash] = true
+					}
+					notifier.Notify(rpcSub.ID, l)
+				}
+			case err := <-done:
+				if err != nil {
+					log.Warn("Failed to replay subscribed logs", "id", rpcSub.ID, "err", err)
+					return
+				}
+				done = nil
+				for _, logs := range pending {
+					deliver(logs)
+				}
+				pending = nil
+
 			case logs := <-matchedLogs:
-				for _, log := range logs {
-					notifier.Notify(rpcSub.ID, &log)
+				if done != nil {
+					pending = append(pending, logs)
+				} else {
+					deliver(logs)
 				}
 			case <-rpcSub.Err(): // client send an unsubscribe request
-				logsSub.Unsubscribe()
 				return
 			case <-notifier.Closed(): // connection dropped
-				logsSub.Unsubscribe()
 				return
 			}
 		}
@@ -271,6 +507,124 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	return rpcSub, nil
 }
 
+// unwindLogs walks back from the block with the given hash to its most recent
+// canonical ancestor, collecting the matching logs of the blocks reorganised out
+// on the way, marked as removed. It also returns the number of the first block
+// after the common ancestor. The walk is bounded by the block range limit of log
+// queries, or the replay window if unlimited.
+func (api *PublicFilterAPI) unwindLogs(ctx context.Context, crit FilterCriteria, hash common.Hash) ([]*types.Log, int64, error) {
+	depth := api.config.LogBlockRange
+	if depth == 0 {
+		depth = defaultReplayWindow
+	}
+	var removed []*types.Log
+	for unwound := uint64(0); ; unwound++ {
+		if unwound > depth {
+			return nil, 0, &limitError{msg: fmt.Sprintf("last seen block is more than %d blocks off the canonical chain", depth)}
+		}
+		header, err := api.backend.HeaderByHash(ctx, hash)
+		if err != nil {
+			return nil, 0, err
+		}
+		if header == nil {
+			return nil, 0, errUnknownBlock
+		}
+		canonical, err := api.backend.HeaderByNumber(ctx, rpc.BlockNumber(header.Number.Int64()))
+		if err != nil {
+			return nil, 0, err
+		}
+		if canonical != nil && canonical.Hash() == hash {
+			return removed, header.Number.Int64() + 1, nil
+		}
+		logs, err := NewBlockFilter(api.backend, hash, crit.Addresses, crit.Topics).Logs(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		// Revert the logs in the opposite order they were emitted in
+		for i := len(logs) - 1; i >= 0; i-- {
+			l := *logs[i]
+			l.Removed = true
+			removed = append(removed, &l)
+		}
+		hash = header.ParentHash
+	}
+}
+
+// checkReplay ensures that replaying the logs matching the given criteria from
+// the given block up to the current head stays within the limits configured for
+// log queries, counting the removed logs to be delivered ahead of them too.
+func (api *PublicFilterAPI) checkReplay(ctx context.Context, crit FilterCriteria, begin, head int64, removed int) error {
+	end := head
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Int64() < end {
+		end = crit.ToBlock.Int64()
+	}
+	if window := api.config.LogBlockRange; window > 0 && end >= begin && uint64(end-begin) >= window {
+		return &limitError{
+			msg:   fmt.Sprintf("replay spans more than %d blocks", window),
+			from:  uint64(end) - window + 1,
+			to:    uint64(end),
+			retry: true,
+		}
+	}
+	limit := api.config.LogResultCap
+	if limit == 0 {
+		return nil
+	}
+	count := removed
+	if count <= limit && begin <= end {
+		filter := NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
+		filter.SetLimit(limit - count + 1)
+
+		logs, err := filter.Logs(ctx)
+		if err != nil {
+			return err
+		}
+		count += len(logs)
+	}
+	if count > limit {
+		return &limitError{msg: fmt.Sprintf("replay returned more than %d results", limit)}
+	}
+	return nil
+}
+
+// replayLogs searches the canonical chain for the logs matching the given
+// criteria, from the given block up to the current head, or the end of the
+// criteria's range if earlier. The logs are streamed into the results channel
+// one window of blocks at a time.
+func (api *PublicFilterAPI) replayLogs(ctx context.Context, crit FilterCriteria, begin int64, results chan<- []*types.Log) error {
+	header, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil || header == nil {
+		return err
+	}
+	end := header.Number.Int64()
+	if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Int64() < end {
+		end = crit.ToBlock.Int64()
+	}
+	window := int64(api.config.LogBlockRange)
+	if window == 0 {
+		window = defaultReplayWindow
+	}
+	for ; begin <= end; begin += window {
+		last := begin + window - 1
+		if last > end {
+			last = end
+		}
+		logs, err := NewRangeFilter(api.backend, begin, last, crit.Addresses, crit.Topics).Logs(ctx)
+		if err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			continue
+		}
+		select {
+		case results <- logs:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
 // FilterCriteria represents a request to create a new filter.
 // Same as ccmchain.FilterQuery but with UnmarshalJSON() mccmod.
 type FilterCriteria ccmchain.FilterQuery
@@ -322,31 +676,182 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 
 // GetLogs returns logs matching the given argument that are stored within the state.
 //
+// If the query spans more blocks or matches more logs than the node allows, an
+// error is returned, reporting the block range to retry the query with if any.
+//
 // https://github.com/ccmchain/wiki/wiki/JSON-RPC#ccm_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+	return api.queryLogs(ctx, crit)
+}
+
+// GetLogsPage returns a batch of logs matching the given argument, starting at
+// the position identified by the given cursor, or at the beginning of the query
+// if no cursor is given. The returned page holds the cursor to resume the query
+// from, until all matching logs have been returned.
+//
+// Unlike GetLogs, queries spanning more blocks than allowed are not rejected,
+// but searched one window of blocks at a time.
+func (api *PublicFilterAPI) GetLogsPage(ctx context.Context, crit FilterCriteria, cursor *hexutil.Bytes, limit *hexutil.Uint) (*LogsPage, error) {
+	// Figure out the number of logs to return in this page
+	size := api.config.LogResultCap
+	if size == 0 {
+		size = defaultLogsPageSize
+	}
+	if limit != nil && *limit > 0 && (api.config.LogResultCap == 0 || int(*limit) < size) {
+		size = int(*limit)
+	}
+	// Decode the position to resume the search from, if any
+	var pos *logCursor
+	if cursor != nil {
+		pos = new(logCursor)
+		if err := rlp.DecodeBytes(*cursor, pos); err != nil {
+			return nil, errInvalidCursor
+		}
+	}
+	var (
+		filter *Filter
+		end    int64
+	)
+	if crit.BlockHash != nil {
+		if pos != nil && pos.Hash != *crit.BlockHash {
+			return nil, errInvalidCursor
+		}
+		filter = NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
+	} else {
+		var begin int64
+		begin, end = api.resolveRange(ctx, crit)
+		if pos != nil {
+			if int64(pos.Number) < begin || (end >= 0 && int64(pos.Number) > end) {
+				return nil, errInvalidCursor
+			}
+			header, err := api.backend.HeaderByNumber(ctx, rpc.BlockNumber(pos.Number))
+			if err != nil {
+				return nil, err
+			}
+			if header == nil || header.Hash() != pos.Hash {
+				return nil, errCursorReorged
+			}
+			begin = int64(pos.Number)
+		}
+		// Only search a single window of blocks if the range is capped
+		last := end
+		if window := api.config.LogBlockRange; window > 0 && begin >= 0 && end >= begin && uint64(end-begin) >= window {
+			last = begin + int64(window) - 1
+		}
+		filter = NewRangeFilter(api.backend, begin, last, crit.Addresses, crit.Topics)
+	}
+	filter.SetLimit(size + 1)
+
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Drop the logs already returned in previous pages
+	if pos != nil {
+		var skip int
+		for skip < len(logs) && logs[skip].BlockNumber == pos.Number && logs[skip].Index < pos.Index {
+			skip++
+		}
+		logs = logs[skip:]
+	}
+	page := &LogsPage{Logs: returnLogs(logs)}
+
+	// Assemble the cursor to the next page, if there's anything left
+	var next *logCursor
+	switch {
+	case len(logs) > size:
+		page.Logs = logs[:size]
+		next = &logCursor{Number: logs[size].BlockNumber, Hash: logs[size].BlockHash, Index: logs[size].Index}
+
+	case crit.BlockHash == nil && end >= 0 && filter.Next() <= end:
+		header, err := api.backend.HeaderByNumber(ctx, rpc.BlockNumber(filter.Next()))
+		if err != nil {
+			return nil, err
+		}
+		if header != nil {
+			next = &logCursor{Number: header.Number.Uint64(), Hash: header.Hash()}
+		}
+	}
+	if next != nil {
+		enc, err := rlp.EncodeToBytes(next)
+		if err != nil {
+			return nil, err
+		}
+		page.Cursor = (*hexutil.Bytes)(&enc)
+	}
+	return page, nil
+}
+
+// queryLogs runs a one-shot log query, enforcing the block range and result
+// limits configured for the API.
+func (api *PublicFilterAPI) queryLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
 	var filter *Filter
 	if crit.BlockHash != nil {
 		// Block filter requested, construct a single-shot filter
 		filter = NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
 	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if crit.FromBlock != nil {
-			begin = crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if crit.ToBlock != nil {
-			end = crit.ToBlock.Int64()
+		begin, end := api.resolveRange(ctx, crit)
+
+		// Reject the query if it spans too many blocks
+		if window := api.config.LogBlockRange; window > 0 && begin >= 0 && end >= begin && uint64(end-begin) >= window {
+			return nil, &limitError{
+				msg:   fmt.Sprintf("query spans more than %d blocks", window),
+				from:  uint64(begin),
+				to:    uint64(begin) + window - 1,
+				retry: true,
+			}
 		}
 		// Construct the range filter
 		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
 	}
+	// Stop the search as soon as the result cap is known to be exceeded
+	limit := api.config.LogResultCap
+	if limit > 0 {
+		filter.SetLimit(limit + 1)
+	}
+	begin := filter.begin
+
 	// Run the filter and return all the logs
 	logs, err := filter.Logs(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return returnLogs(logs), err
+	if limit > 0 && len(logs) > limit {
+		// Suggest the range covering all the blocks that fit within the cap
+		err := &limitError{msg: fmt.Sprintf("query returned more than %d results", limit)}
+		if first := logs[limit].BlockNumber; crit.BlockHash == nil && first > uint64(begin) {
+			err.from, err.to, err.retry = uint64(begin), first-1, true
+		} else {
+			err.msg += ", use ccm_getLogsPage to paginate"
+		}
+		return nil, err
+	}
+	return returnLogs(logs), nil
+}
+
+// resolveRange converts the block range of the given criteria into internal
+// representations, pinning "latest" to the current head block.
+func (api *PublicFilterAPI) resolveRange(ctx context.Context, crit FilterCriteria) (int64, int64) {
+	begin := rpc.LatestBlockNumber.Int64()
+	if crit.FromBlock != nil {
+		begin = crit.FromBlock.Int64()
+	}
+	end := rpc.LatestBlockNumber.Int64()
+	if crit.ToBlock != nil {
+		end = crit.ToBlock.Int64()
+	}
+	if begin == rpc.LatestBlockNumber.Int64() || end == rpc.LatestBlockNumber.Int64() {
+		if header, _ := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber); header != nil {
+			head := header.Number.Int64()
+			if begin == rpc.LatestBlockNumber.Int64() {
+				begin = head
+			}
+			if end == rpc.LatestBlockNumber.Int64() {
+				end = head
+			}
+		}
+	}
+	return begin, end
 }
 
 // UninstallFilter removes the filter with the given filter id.
@@ -378,30 +883,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if !found || f.typ != LogsSubscription {
 		return nil, fmt.Errorf("filter not found")
 	}
-
-	var filter *Filter
-	if f.crit.BlockHash != nil {
-		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *f.crit.BlockHash, f.crit.Addresses, f.crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if f.crit.FromBlock != nil {
-			begin = f.crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if f.crit.ToBlock != nil {
-			end = f.crit.ToBlock.Int64()
-		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
-	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
-	if err != nil {
-		return nil, err
-	}
-	return returnLogs(logs), nil
+	return api.queryLogs(ctx, f.crit)
 }
 
 // GetFilterChanges returns the logs for the filter with the given id since
@@ -17,11 +17,22 @@
 package filters
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"math/big"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/consensus/ccmash"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/event"
+	"github.com/ccmchain/go-ccmchain/params"
 	"github.com/ccmchain/go-ccmchain/rpc"
 )
 
@@ -183,3 +194,277 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 		t.Fatalf("expected 0 topics, got %d topics", len(test7.Topics[2]))
 	}
 }
+
+// newLogsTestBackend creates a backend with a chain of the given length, each
+// block containing two logs emitted by the given address.
+func newLogsTestBackend(blocks int, addr common.Address) *testBackend {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}
+		genesis = core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	)
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ccmash.NewFaker(), db, blocks, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr}, {Address: addr}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	return backend
+}
+
+func TestGetLogsLimits(t *testing.T) {
+	addr := common.Address{0x11}
+	backend := newLogsTestBackend(20, addr)
+
+	crit := FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(20), Addresses: []common.Address{addr}}
+	tests := []struct {
+		config Config
+		logs   int
+		retry  map[string]hexutil.Uint64
+	}{
+		{config: Config{}, logs: 40},
+		{config: Config{LogBlockRange: 20, LogResultCap: 40}, logs: 40},
+		{config: Config{LogBlockRange: 5}, retry: map[string]hexutil.Uint64{"fromBlock": 1, "toBlock": 5}},
+		{config: Config{LogResultCap: 5}, retry: map[string]hexutil.Uint64{"fromBlock": 1, "toBlock": 2}},
+		{config: Config{LogResultCap: 1}},
+	}
+	for i, tt := range tests {
+		api := &PublicFilterAPI{backend: backend, config: tt.config}
+		logs, err := api.GetLogs(context.Background(), crit)
+		if tt.logs > 0 {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+			} else if len(logs) != tt.logs {
+				t.Errorf("test %d: log count mismatch: have %d, want %d", i, len(logs), tt.logs)
+			}
+			continue
+		}
+		lerr, ok := err.(*limitError)
+		if !ok {
+			t.Errorf("test %d: expected limit error, got %v", i, err)
+			continue
+		}
+		if lerr.ErrorCode() != errcodeLimitExceeded {
+			t.Errorf("test %d: error code mismatch: have %d, want %d", i, lerr.ErrorCode(), errcodeLimitExceeded)
+		}
+		if tt.retry == nil {
+			if data := lerr.ErrorData(); data != nil {
+				t.Errorf("test %d: unexpected retry range: %v", i, data)
+			}
+		} else if data := lerr.ErrorData(); !reflect.DeepEqual(data, tt.retry) {
+			t.Errorf("test %d: retry range mismatch: have %v, want %v", i, data, tt.retry)
+		}
+	}
+}
+
+func TestGetLogsPage(t *testing.T) {
+	addr := common.Address{0x11}
+	backend := newLogsTestBackend(20, addr)
+
+	tests := []struct {
+		config Config
+		limit  uint
+		pages  int
+	}{
+		{config: Config{}, pages: 1},
+		{config: Config{LogResultCap: 3}, pages: 14},
+		{config: Config{LogResultCap: 3}, limit: 40, pages: 14},
+		{config: Config{}, limit: 7, pages: 6},
+		{config: Config{LogBlockRange: 4}, pages: 5},
+		{config: Config{LogBlockRange: 4, LogResultCap: 3}, pages: 14},
+	}
+	for i, tt := range tests {
+		var (
+			api    = &PublicFilterAPI{backend: backend, config: tt.config}
+			crit   = FilterCriteria{FromBlock: big.NewInt(1), Addresses: []common.Address{addr}}
+			limit  *hexutil.Uint
+			cursor *hexutil.Bytes
+			logs   []*types.Log
+			pages  int
+		)
+		if tt.limit > 0 {
+			limit = (*hexutil.Uint)(&tt.limit)
+		}
+		for {
+			page, err := api.GetLogsPage(context.Background(), crit, cursor, limit)
+			if err != nil {
+				t.Fatalf("test %d: page %d: unexpected error: %v", i, pages, err)
+			}
+			logs = append(logs, page.Logs...)
+			if pages++; page.Cursor == nil || pages > 100 {
+				break
+			}
+			cursor = page.Cursor
+		}
+		if pages != tt.pages {
+			t.Errorf("test %d: page count mismatch: have %d, want %d", i, pages, tt.pages)
+		}
+		if len(logs) != 40 {
+			t.Fatalf("test %d: log count mismatch: have %d, want %d", i, len(logs), 40)
+		}
+		for j, log := range logs {
+			if log.BlockNumber != uint64(j/2+1) || log.Index != uint(j%2) {
+				t.Errorf("test %d: log %d: position mismatch: have %d/%d, want %d/%d", i, j, log.BlockNumber, log.Index, j/2+1, j%2)
+			}
+		}
+	}
+}
+
+func TestResumeLogsSubscription(t *testing.T) {
+	addr := common.Address{0x11}
+	backend := newLogsTestBackend(20, addr)
+
+	// Fork off two blocks from block 17, which are not part of the canonical chain
+	parent := rawdb.ReadBlock(backend.db, rawdb.ReadCanonicalHash(backend.db, 17), 17)
+	forks, receipts := core.GenerateChain(params.TestChainConfig, parent, ccmash.NewFaker(), backend.db, 2, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr}, {Address: addr}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(17+i), common.Address{0x01}, big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	for i, block := range forks {
+		rawdb.WriteBlock(backend.db, block)
+		rawdb.WriteReceipts(backend.db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("ccm", NewPublicFilterAPI(backend, false, Config{LogBlockRange: 4})); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	type position struct {
+		number  uint64
+		index   uint
+		removed bool
+	}
+	tests := []struct {
+		from     *hexutil.Big
+		lastSeen *common.Hash
+		want     []position
+	}{
+		// Replay from an explicit starting block
+		{
+			from: (*hexutil.Big)(big.NewInt(18)),
+			want: []position{{18, 0, false}, {18, 1, false}, {19, 0, false}, {19, 1, false}, {20, 0, false}, {20, 1, false}},
+		},
+		// Resume after a canonical block
+		{
+			lastSeen: &[]common.Hash{rawdb.ReadCanonicalHash(backend.db, 19)}[0],
+			want:     []position{{20, 0, false}, {20, 1, false}},
+		},
+		// Resume after a reorged block, reverting the orphaned logs first
+		{
+			lastSeen: &[]common.Hash{forks[1].Hash()}[0],
+			want: []position{
+				{19, 1, true}, {19, 0, true}, {18, 1, true}, {18, 0, true},
+				{18, 0, false}, {18, 1, false}, {19, 0, false}, {19, 1, false}, {20, 0, false}, {20, 1, false},
+			},
+		},
+	}
+	for i, tt := range tests {
+		crit := map[string]interface{}{"address": []common.Address{addr}}
+		if tt.from != nil {
+			crit["fromBlock"] = tt.from
+		}
+		args := []interface{}{"logs", crit}
+		if tt.lastSeen != nil {
+			args = append(args, *tt.lastSeen)
+		}
+		logs := make(chan types.Log)
+		sub, err := client.Subscribe(context.Background(), "ccm", logs, args...)
+		if err != nil {
+			t.Fatalf("test %d: failed to subscribe: %v", i, err)
+		}
+		// Wait for the historical logs, followed by a new one
+		for j, want := range append(tt.want, position{21, 0, false}) {
+			if j == len(tt.want) {
+				backend.logsFeed.Send([]*types.Log{{Address: addr, Topics: []common.Hash{}, BlockNumber: 21, BlockHash: common.Hash{0x21}}})
+			}
+			select {
+			case log := <-logs:
+				if have := (position{log.BlockNumber, log.Index, log.Removed}); have != want {
+					t.Errorf("test %d: log %d: position mismatch: have %v, want %v", i, j, have, want)
+				}
+			case err := <-sub.Err():
+				t.Fatalf("test %d: subscription failed: %v", i, err)
+			case <-time.After(time.Second):
+				t.Fatalf("test %d: log %d not delivered", i, j)
+			}
+		}
+		sub.Unsubscribe()
+	}
+	// Resuming after an unknown block must be rejected
+	if _, err := client.Subscribe(context.Background(), "ccm", make(chan types.Log), "logs", map[string]interface{}{}, common.Hash{0xff}); err == nil {
+		t.Fatal("expected error resuming after unknown block")
+	}
+}
+
+func TestLogsSubscriptionReplayLimits(t *testing.T) {
+	addr := common.Address{0x11}
+	backend := newLogsTestBackend(20, addr)
+
+	// Fork off two blocks from block 17, which are not part of the canonical chain
+	parent := rawdb.ReadBlock(backend.db, rawdb.ReadCanonicalHash(backend.db, 17), 17)
+	forks, _ := core.GenerateChain(params.TestChainConfig, parent, ccmash.NewFaker(), backend.db, 2, func(i int, gen *core.BlockGen) {
+		gen.AddUncheckedTx(types.NewTransaction(uint64(17+i), common.Address{0x01}, big.NewInt(1), 1, big.NewInt(1), nil))
+	})
+	for _, block := range forks {
+		rawdb.WriteBlock(backend.db, block)
+	}
+	orphan := forks[1].Hash()
+
+	tests := []struct {
+		config   Config
+		from     int64
+		lastSeen *common.Hash
+		ok       bool
+	}{
+		{config: Config{LogBlockRange: 3}, from: 18, ok: true},
+		{config: Config{LogBlockRange: 3}, from: 17},
+		{config: Config{}, from: 0, ok: true},
+		{config: Config{LogResultCap: 6}, from: 18, ok: true},
+		{config: Config{LogResultCap: 5}, from: 18},
+		{config: Config{LogBlockRange: 3}, lastSeen: &orphan, ok: true},
+		{config: Config{LogBlockRange: 1}, lastSeen: &orphan},
+	}
+
+	for i, tt := range tests {
+		server := rpc.NewServer()
+		if err := server.RegisterName("ccm", NewPublicFilterAPI(backend, false, tt.config)); err != nil {
+			t.Fatal(err)
+		}
+		client := rpc.DialInProc(server)
+
+		crit := map[string]interface{}{"address": []common.Address{addr}}
+		args := []interface{}{"logs", crit}
+		if tt.lastSeen != nil {
+			args = append(args, *tt.lastSeen)
+		} else {
+			crit["fromBlock"] = (*hexutil.Big)(big.NewInt(tt.from))
+		}
+		sub, err := client.Subscribe(context.Background(), "ccm", make(chan types.Log, 64), args...)
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("test %d: failed to subscribe: %v", i, err)
+		case !tt.ok && err == nil:
+			t.Errorf("test %d: replay beyond limits accepted", i)
+		case !tt.ok:
+			if rerr, ok := err.(rpc.Error); !ok || rerr.ErrorCode() != errcodeLimitExceeded {
+				t.Errorf("test %d: error mismatch: have %v, want code %d", i, err, errcodeLimitExceeded)
+			}
+		}
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+		client.Close()
+		server.Stop()
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"math/big"
 
+	ccmchain "github.com/ccmchain/go-ccmchain"
 	"github.com/ccmchain/go-ccmchain/common"
 	"github.com/ccmchain/go-ccmchain/core"
 	"github.com/ccmchain/go-ccmchain/core/bloombits"
@@ -58,6 +59,8 @@ type Filter struct {
 	block      common.Hash // Block hash if filtering a single block
 	begin, end int64       // Range interval if filtering multiple blocks
 
+	limit int // Number of logs after which to stop filtering (0 = unlimited)
+
 	matcher *bloombits.Matcher
 }
 
@@ -114,6 +117,25 @@ func newFilter(backend Backend, addresses []common.Address, topics [][]common.Ha
 	}
 }
 
+// SetLimit sets the number of logs after which the filter stops searching. The
+// limit is only checked at block boundaries, so all the matching logs of the
+// last processed block are always returned, even if they exceed it. Use Next to
+// find out where a limited search stopped.
+func (f *Filter) SetLimit(limit int) {
+	f.limit = limit
+}
+
+// Next returns the number of the first block not yet searched by the filter.
+func (f *Filter) Next() int64 {
+	return f.begin
+}
+
+// limitReached reports whether the given number of matching logs reaches the
+// result limit of the filter.
+func (f *Filter) limitReached(found int) bool {
+	return f.limit > 0 && found >= f.limit
+}
+
 // Logs searches the blockchain for matching log entries, returning all from the
 // first block that contains matches, updating the start of the filter accordingly.
 func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
@@ -154,11 +176,11 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		} else {
 			logs, err = f.indexedLogs(ctx, indexed-1)
 		}
-		if err != nil {
+		if err != nil || f.limitReached(len(logs)) {
 			return logs, err
 		}
 	}
-	rest, err := f.unindexedLogs(ctx, end)
+	rest, err := f.unindexedLogs(ctx, end, len(logs))
 	logs = append(logs, rest...)
 	return logs, err
 }
@@ -204,18 +226,24 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 			}
 			logs = append(logs, found...)
 
+			// Stop early if enough logs were gathered
+			if f.limitReached(len(logs)) {
+				return logs, nil
+			}
+
 		case <-ctx.Done():
 			return logs, ctx.Err()
 		}
 	}
 }
 
-// indexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+// unindexedLogs returns the logs matching the filter criteria based on raw block
+// iteration and bloom matching. The number of logs already gathered by previous
+// search phases is passed in to enforce the result limit.
+func (f *Filter) unindexedLogs(ctx context.Context, end uint64, gathered int) ([]*types.Log, error) {
 	var logs []*types.Log
 
-	for ; f.begin <= int64(end); f.begin++ {
+	for f.begin <= int64(end) {
 		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
 		if header == nil || err != nil {
 			return logs, err
@@ -225,6 +253,12 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 			return logs, err
 		}
 		logs = append(logs, found...)
+		f.begin++
+
+		// Stop early if enough logs were gathered
+		if f.limitReached(gathered + len(logs)) {
+			break
+		}
 	}
 	return logs, nil
 }
@@ -318,6 +352,52 @@ Logs:
 	return ret
 }
 
+// filterTxs creates a slice of transactions matching the given criteria. Recovered
+// senders are memoized in the senders cache, shared across calls for the same txs.
+func filterTxs(txs []*types.Transaction, senders map[common.Hash]common.Address, crit ccmchain.PendingTxQuery) []*types.Transaction {
+	var ret []*types.Transaction
+	for _, tx := range txs {
+		if len(crit.To) > 0 && (tx.To() == nil || !includes(crit.To, *tx.To())) {
+			continue
+		}
+		if len(crit.Selectors) > 0 && !includesSelector(crit.Selectors, tx.Data()) {
+			continue
+		}
+		if len(crit.From) > 0 {
+			from, ok := senders[tx.Hash()]
+			if !ok {
+				var signer types.Signer = types.HomesteadSigner{}
+				if tx.Protected() {
+					signer = types.NewEIP155Signer(tx.ChainId())
+				}
+				sender, err := types.Sender(signer, tx)
+				if err != nil {
+					continue
+				}
+				from, senders[tx.Hash()] = sender, sender
+			}
+			if !includes(crit.From, from) {
+				continue
+			}
+		}
+		ret = append(ret, tx)
+	}
+	return ret
+}
+
+// includesSelector reports whether the call data starts with one of the selectors.
+func includesSelector(selectors [][4]byte, data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	for _, sel := range selectors {
+		if sel[0] == data[0] && sel[1] == data[1] && sel[2] == data[2] && sel[3] == data[3] {
+			return true
+		}
+	}
+	return false
+}
+
 func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
 	if len(addresses) > 0 {
 		var included bool
@@ -53,6 +53,9 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// FilteredPendingTransactionsSubscription queries full pending transactions
+	// matching a set of criteria as they enter the pending state
+	FilteredPendingTransactionsSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -82,6 +85,8 @@ type subscription struct {
 	logs      chan []*types.Log
 	hashes    chan []common.Hash
 	headers   chan *types.Header
+	txsCrit   ccmchain.PendingTxQuery
+	txs       chan []*types.Transaction
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -175,6 +180,7 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.txs:
 			}
 		}
 
@@ -314,6 +320,24 @@ func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscript
 	return es.subscribe(sub)
 }
 
+// SubscribeFilteredPendingTxs creates a subscription that writes the transactions
+// entering the transaction pool which match the given criteria.
+func (es *EventSystem) SubscribeFilteredPendingTxs(crit ccmchain.PendingTxQuery, txs chan []*types.Transaction) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       FilteredPendingTransactionsSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		txsCrit:   crit,
+		txs:       txs,
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 // broadcast event to filters that match criteria.
@@ -355,6 +379,14 @@ func (es *EventSystem) broadcast(filters filterIndex, ev interface{}) {
 		for _, f := range filters[PendingTransactionsSubscription] {
 			f.hashes <- hashes
 		}
+		if len(filters[FilteredPendingTransactionsSubscription]) > 0 {
+			senders := make(map[common.Hash]common.Address)
+			for _, f := range filters[FilteredPendingTransactionsSubscription] {
+				if matched := filterTxs(e.Txs, senders, f.txsCrit); len(matched) > 0 {
+					f.txs <- matched
+				}
+			}
+		}
 	case core.ChainEvent:
 		for _, f := range filters[BlocksSubscription] {
 			f.headers <- e.Block.Header()
@@ -18,6 +18,7 @@ package filters
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -32,6 +33,7 @@ import (
 	"github.com/ccmchain/go-ccmchain/core/bloombits"
 	"github.com/ccmchain/go-ccmchain/core/rawdb"
 	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/crypto"
 	"github.com/ccmchain/go-ccmchain/ccmdb"
 	"github.com/ccmchain/go-ccmchain/event"
 	"github.com/ccmchain/go-ccmchain/params"
@@ -167,7 +169,7 @@ func TestBlockSubscription(t *testing.T) {
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
 		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api         = NewPublicFilterAPI(backend, false)
+		api         = NewPublicFilterAPI(backend, false, Config{})
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, ccmash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
@@ -224,7 +226,7 @@ func TestPendingTxFilter(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, Config{})
 
 		transactions = []*types.Transaction{
 			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
@@ -273,6 +275,69 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestFilteredPendingTxSubscription tests that pending transaction subscriptions
+// only deliver the transactions matching their sender, recipient and selector criteria.
+func TestFilteredPendingTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db         = rawdb.NewMemoryDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		es         = NewEventSystem(mux, backend, false)
+
+		key1, _  = crypto.GenerateKey()
+		key2, _  = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key1.PublicKey)
+		target   = common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268")
+		other    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		selector = [4]byte{0xa9, 0x05, 0x9c, 0xbb}
+		signer   = types.NewEIP155Signer(big.NewInt(1))
+		call     = append(selector[:], make([]byte, 64)...)
+	)
+	sign := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address, data []byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, new(big.Int), 0, new(big.Int), data), signer, key)
+		return tx
+	}
+	var (
+		match        = sign(key1, 0, target, call)
+		wrongSender  = sign(key2, 0, target, call)
+		wrongTarget  = sign(key1, 1, other, call)
+		wrongMethod  = sign(key1, 2, target, []byte{0x01, 0x02, 0x03, 0x04})
+		shortData    = sign(key1, 3, target, selector[:3])
+		transactions = []*types.Transaction{wrongSender, match, wrongTarget, wrongMethod, shortData}
+	)
+	crit := ccmchain.PendingTxQuery{
+		From:      []common.Address{sender},
+		To:        []common.Address{target},
+		Selectors: [][4]byte{selector},
+	}
+	txs := make(chan []*types.Transaction, 1)
+	sub := es.SubscribeFilteredPendingTxs(crit, txs)
+	defer sub.Unsubscribe()
+
+	txFeed.Send(core.NewTxsEvent{Txs: transactions})
+	select {
+	case matched := <-txs:
+		if len(matched) != 1 || matched[0].Hash() != match.Hash() {
+			t.Fatalf("wrong transactions delivered: have %d, want %x", len(matched), match.Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("matching transaction not delivered")
+	}
+	// Events without any match must not be delivered.
+	txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{wrongSender, wrongTarget}})
+	select {
+	case matched := <-txs:
+		t.Fatalf("unexpected delivery of %d transactions", len(matched))
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 // TestLogFilterCreation test whccmer a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -284,7 +349,7 @@ func TestLogFilterCreation(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, Config{})
 
 		testCases = []struct {
 			crit    FilterCriteria
@@ -333,7 +398,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, Config{})
 	)
 
 	// different situations where log filter creation should fail.
@@ -360,7 +425,7 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, Config{})
 		blockHash  = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
 	)
 
@@ -390,7 +455,7 @@ func TestLogFilter(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, Config{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -509,7 +574,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
 		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
-		api        = NewPublicFilterAPI(backend, false)
+		api        = NewPublicFilterAPI(backend, false, Config{})
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -0,0 +1,321 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccm
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+
+	"github.com/ccmchain/go-ccmchain/ccmdb"
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/crypto"
+	"github.com/ccmchain/go-ccmchain/log"
+	"github.com/ccmchain/go-ccmchain/metrics"
+	"github.com/ccmchain/go-ccmchain/rlp"
+)
+
+var (
+	traceCacheHitMeter        = metrics.NewRegisteredMeter("ccm/tracecache/hit", nil)
+	traceCacheMissMeter       = metrics.NewRegisteredMeter("ccm/tracecache/miss", nil)
+	traceCacheEvictMeter      = metrics.NewRegisteredMeter("ccm/tracecache/evict", nil)
+	traceCacheInvalidateMeter = metrics.NewRegisteredMeter("ccm/tracecache/invalidate", nil)
+	traceCacheSizeGauge       = metrics.NewRegisteredGauge("ccm/tracecache/size", nil)
+	traceCacheEntriesGauge    = metrics.NewRegisteredGauge("ccm/tracecache/entries", nil)
+)
+
+// traceCacheKeyLength is the length of the database keys of cached traces: the
+// hash of the traced transaction, of the tracer name and of its configuration.
+const traceCacheKeyLength = 3 * common.HashLength
+
+// traceCacheEntry is a cached trace result, as stored in the database.
+type traceCacheEntry struct {
+	BlockHash common.Hash // Block the traced transaction was included in
+	Result    []byte      // JSON encoded result of the tracer
+}
+
+// traceCacheItem is the in-memory bookkeeping of a cached trace result, used to
+// evict the least recently used results once the cache is full.
+type traceCacheItem struct {
+	key  string
+	size uint64
+}
+
+// traceCache is an on-disk cache of transaction trace results, keyed by the hash
+// of the transaction, the name of the tracer and the hash of its configuration.
+// The cache is limited in size, evicting the least recently used results first.
+// Results are bound to the block the transaction was traced in, so reorgs don't
+// serve stale traces.
+//
+// The recency of the results is only tracked in memory, so after a restart the
+// results loaded from disk are evicted in an arbitrary order.
+type traceCache struct {
+	db    ccmdb.Database // Database table holding the cached results
+	limit uint64         // Maximum total size of the cached results in bytes
+
+	items map[string]*list.Element // Cached results indexed by database key
+	order *list.List               // Cached results, most recently used first
+	size  uint64                   // Total size of the cached results in bytes
+	lock  sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newTraceCache creates a trace cache on top of the given database, limited to
+// the given size in bytes, indexing all the results already cached on disk.
+func newTraceCache(db ccmdb.Database, limit uint64) *traceCache {
+	cache := &traceCache{
+		db:    rawdb.NewTable(db, string(rawdb.TraceCachePrefix)),
+		limit: limit,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+	it := cache.db.NewIterator()
+	for it.Next() {
+		key := it.Key()[len(rawdb.TraceCachePrefix):]
+		if len(key) != traceCacheKeyLength {
+			continue
+		}
+		cache.insert(string(key), uint64(len(key)+len(it.Value())))
+	}
+	it.Release()
+
+	cache.evict()
+	log.Info("Loaded trace result cache", "entries", cache.order.Len(), "size", common.StorageSize(cache.size), "limit", common.StorageSize(limit))
+	return cache
+}
+
+// deleteTraceCache drops all the results left on disk by a trace cache, so they
+// don't linger around once the cache is disabled.
+func deleteTraceCache(db ccmdb.Database) {
+	var (
+		it      = db.NewIteratorWithPrefix(rawdb.TraceCachePrefix)
+		batch   = db.NewBatch()
+		entries int
+		size    common.StorageSize
+	)
+	defer it.Release()
+
+	for it.Next() {
+		batch.Delete(it.Key())
+		entries++
+		size += common.StorageSize(len(it.Key()) + len(it.Value()))
+
+		if batch.ValueSize() > ccmdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Error("Failed to delete trace result cache", "err", err)
+				return
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to delete trace result cache", "err", err)
+		return
+	}
+	if entries > 0 {
+		log.Info("Deleted disabled trace result cache", "entries", entries, "size", size)
+	}
+}
+
+// traceCacheKey assembles the database key of a trace result. The tracer name
+// and configuration are hashed, as they may be arbitrarily long.
+func traceCacheKey(txHash common.Hash, config *TraceConfig) []byte {
+	var (
+		tracer  string
+		options interface{}
+	)
+	if config != nil {
+		if config.Tracer != nil {
+			tracer = *config.Tracer
+		}
+		options = struct {
+			LogConfig    interface{}
+			TracerConfig json.RawMessage
+		}{config.LogConfig, config.TracerConfig}
+	}
+	blob, _ := json.Marshal(options)
+
+	key := make([]byte, 0, traceCacheKeyLength)
+	key = append(key, txHash.Bytes()...)
+	key = append(key, crypto.Keccak256([]byte(tracer))...)
+	key = append(key, crypto.Keccak256(blob)...)
+	return key
+}
+
+// get retrieves the cached result of tracing a transaction included in the
+// given block. A nil cache never contains any results.
+func (c *traceCache) get(txHash common.Hash, blockHash common.Hash, config *TraceConfig) (json.RawMessage, bool) {
+	if c == nil {
+		return nil, false
+	}
+	key := traceCacheKey(txHash, config)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.items[string(key)]
+	if !ok {
+		traceCacheMissMeter.Mark(1)
+		return nil, false
+	}
+	var entry traceCacheEntry
+	blob, err := c.db.Get(key)
+	if err == nil {
+		err = rlp.DecodeBytes(blob, &entry)
+	}
+	if err != nil || entry.BlockHash != blockHash {
+		// The result is corrupted or belongs to a reorged block, drop it
+		c.remove(elem)
+		traceCacheInvalidateMeter.Mark(1)
+		traceCacheMissMeter.Mark(1)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	traceCacheHitMeter.Mark(1)
+	return entry.Result, true
+}
+
+// put caches the result of tracing a transaction included in the given block,
+// evicting the least recently used results if the cache grows too large.
+func (c *traceCache) put(txHash common.Hash, blockHash common.Hash, config *TraceConfig, result interface{}) {
+	if c == nil {
+		return
+	}
+	res, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	blob, err := rlp.EncodeToBytes(&traceCacheEntry{BlockHash: blockHash, Result: res})
+	if err != nil {
+		return
+	}
+	key := traceCacheKey(txHash, config)
+	size := uint64(len(key) + len(blob))
+	if size > c.limit {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.db.Put(key, blob); err != nil {
+		log.Warn("Failed to cache trace result", "tx", txHash, "err", err)
+		return
+	}
+	if elem, ok := c.items[string(key)]; ok {
+		c.remove(elem)
+	}
+	c.insert(string(key), size)
+	c.evict()
+}
+
+// invalidate drops all the cached results of a transaction, regardless of the
+// tracer that produced them.
+func (c *traceCache) invalidate(txHash common.Hash) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	it := c.db.NewIteratorWithPrefix(txHash.Bytes())
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()[len(rawdb.TraceCachePrefix):]
+		if elem, ok := c.items[string(key)]; ok {
+			c.remove(elem)
+			traceCacheInvalidateMeter.Mark(1)
+		}
+	}
+}
+
+// start begins invalidating the cached results of the transactions included in
+// blocks reorged out of the canonical chain.
+func (c *traceCache) start(chain *core.BlockChain) {
+	c.quit = make(chan struct{})
+	c.wg.Add(1)
+	go c.loop(chain)
+}
+
+// stop terminates the reorg invalidation loop of the cache.
+func (c *traceCache) stop() {
+	if c == nil {
+		return
+	}
+	close(c.quit)
+	c.wg.Wait()
+}
+
+// loop drops the cached results of transactions whose blocks are moved to a side
+// chain, freeing up space for results that are still valid.
+func (c *traceCache) loop(chain *core.BlockChain) {
+	defer c.wg.Done()
+
+	sideCh := make(chan core.ChainSideEvent, 16)
+	sideSub := chain.SubscribeChainSideEvent(sideCh)
+	defer sideSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-sideCh:
+			for _, tx := range ev.Block.Transactions() {
+				c.invalidate(tx.Hash())
+			}
+		case <-sideSub.Err():
+			return
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// insert adds a new result to the in-memory index as the most recently used.
+func (c *traceCache) insert(key string, size uint64) {
+	c.items[key] = c.order.PushFront(&traceCacheItem{key: key, size: size})
+	c.size += size
+	c.updateGauges()
+}
+
+// remove deletes a cached result both from the index and from disk.
+func (c *traceCache) remove(elem *list.Element) {
+	item := c.order.Remove(elem).(*traceCacheItem)
+	delete(c.items, item.key)
+	c.size -= item.size
+	c.updateGauges()
+
+	if err := c.db.Delete([]byte(item.key)); err != nil {
+		log.Warn("Failed to delete cached trace result", "err", err)
+	}
+}
+
+// evict drops the least recently used results until the cache fits its limit.
+func (c *traceCache) evict() {
+	for c.size > c.limit {
+		c.remove(c.order.Back())
+		traceCacheEvictMeter.Mark(1)
+	}
+}
+
+// updateGauges reports the current size of the cache to the metrics system.
+func (c *traceCache) updateGauges() {
+	traceCacheSizeGauge.Update(int64(c.size))
+	traceCacheEntriesGauge.Update(int64(c.order.Len()))
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+)
+
+// Tests that cached trace results are keyed by the tracer and its configuration,
+// and bound to the block the transaction was traced in.
+func TestTraceCacheLookup(t *testing.T) {
+	var (
+		cache  = newTraceCache(rawdb.NewMemoryDatabase(), 1024*1024)
+		tx     = common.HexToHash("0x01")
+		block  = common.HexToHash("0xb1")
+		tracer = "callTracer"
+	)
+	cache.put(tx, block, nil, map[string]int{"gas": 1})
+
+	if res, ok := cache.get(tx, block, nil); !ok || string(res) != `{"gas":1}` {
+		t.Fatalf("cached result mismatch: have %s/%v, want %s", res, ok, `{"gas":1}`)
+	}
+	if _, ok := cache.get(tx, block, &TraceConfig{Tracer: &tracer}); ok {
+		t.Fatalf("result of a different tracer returned")
+	}
+	if _, ok := cache.get(tx, block, &TraceConfig{TracerConfig: json.RawMessage(`{"diffMode":true}`)}); ok {
+		t.Fatalf("result of a different tracer config returned")
+	}
+	// Reorged results must be dropped on access
+	if _, ok := cache.get(tx, common.HexToHash("0xb2"), nil); ok {
+		t.Fatalf("result of a reorged block returned")
+	}
+	if _, ok := cache.get(tx, block, nil); ok {
+		t.Fatalf("reorged result not dropped")
+	}
+}
+
+// Tests that the cache evicts the least recently used results once it exceeds
+// its size limit, and that it reloads the results from disk after a restart.
+func TestTraceCacheEviction(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		block = common.HexToHash("0xb1")
+		txs   = []common.Hash{common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")}
+	)
+	// Measure the size of a single entry and allow two of them
+	probe := newTraceCache(rawdb.NewMemoryDatabase(), 1024*1024)
+	probe.put(txs[0], block, nil, "result")
+	cache := newTraceCache(db, 2*probe.size)
+
+	cache.put(txs[0], block, nil, "result")
+	cache.put(txs[1], block, nil, "result")
+	if _, ok := cache.get(txs[0], block, nil); !ok {
+		t.Fatalf("result 0 missing")
+	}
+	cache.put(txs[2], block, nil, "result")
+
+	if _, ok := cache.get(txs[1], block, nil); ok {
+		t.Fatalf("least recently used result not evicted")
+	}
+	for _, i := range []int{0, 2} {
+		if _, ok := cache.get(txs[i], block, nil); !ok {
+			t.Fatalf("result %d evicted", i)
+		}
+	}
+	// Reopen the cache and check the results were persisted
+	cache = newTraceCache(db, 2*probe.size)
+	if cache.order.Len() != 2 || cache.size != 2*probe.size {
+		t.Fatalf("reloaded cache mismatch: have %d entries/%d bytes, want %d/%d", cache.order.Len(), cache.size, 2, 2*probe.size)
+	}
+	for _, i := range []int{0, 2} {
+		if _, ok := cache.get(txs[i], block, nil); !ok {
+			t.Fatalf("result %d not persisted", i)
+		}
+	}
+	// Invalidating a transaction should drop all its results
+	tracer := "callTracer"
+	cache.put(txs[0], block, &TraceConfig{Tracer: &tracer}, "result")
+	cache.invalidate(txs[0])
+
+	if _, ok := cache.get(txs[0], block, nil); ok {
+		t.Fatalf("invalidated result returned")
+	}
+	if _, ok := cache.get(txs[0], block, &TraceConfig{Tracer: &tracer}); ok {
+		t.Fatalf("invalidated result returned")
+	}
+	if _, ok := cache.get(txs[2], block, nil); !ok {
+		t.Fatalf("unrelated result invalidated")
+	}
+}
+
+// Tests that the results of a disabled cache are deleted from disk, leaving the
+// rest of the database intact.
+func TestTraceCacheDelete(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	db.Put([]byte("unrelated"), []byte{0x01})
+
+	cache := newTraceCache(db, 1024*1024)
+	for i := byte(1); i <= 3; i++ {
+		cache.put(common.Hash{i}, common.HexToHash("0xb1"), nil, "result")
+	}
+	deleteTraceCache(db)
+
+	it := db.NewIteratorWithPrefix(rawdb.TraceCachePrefix)
+	defer it.Release()
+	if it.Next() {
+		t.Fatalf("cached result %x left on disk", it.Key())
+	}
+	if ok, _ := db.Has([]byte("unrelated")); !ok {
+		t.Fatalf("unrelated data deleted")
+	}
+	if cache = newTraceCache(db, 1024*1024); cache.order.Len() != 0 {
+		t.Fatalf("reloaded cache not empty: %d entries", cache.order.Len())
+	}
+}
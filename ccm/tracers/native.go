@@ -0,0 +1,48 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+
+	"github.com/ccmchain/go-ccmchain/ccm/tracers/native"
+	"github.com/ccmchain/go-ccmchain/core/state"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+)
+
+// init registers the native ports of the built in JavaScript tracers, replacing
+// them under the same names.
+func init() {
+	Register("callTracer", func(vm.Context, *state.StateDB, json.RawMessage) (TxTracer, error) {
+		return native.NewCallTracer(), nil
+	})
+	Register("prestateTracer", func(ctx vm.Context, statedb *state.StateDB, config json.RawMessage) (TxTracer, error) {
+		var cfg native.PrestateTracerConfig
+		if len(config) > 0 {
+			if err := json.Unmarshal(config, &cfg); err != nil {
+				return nil, err
+			}
+		}
+		return native.NewPrestateTracer(statedb.Copy(), statedb, ctx.Coinbase, cfg), nil
+	})
+	Register("4byteTracer", func(vm.Context, *state.StateDB, json.RawMessage) (TxTracer, error) {
+		return native.NewFourByteTracer(), nil
+	})
+	Register("noopTracer", func(vm.Context, *state.StateDB, json.RawMessage) (TxTracer, error) {
+		return native.NewNoopTracer(), nil
+	})
+}
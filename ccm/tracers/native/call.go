@@ -0,0 +1,347 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package native is a collection of tracers written in Go, running without the
+// overhead of a JavaScript runtime.
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/log"
+)
+
+// callFrame is a single call made during the execution of a transaction, along
+// with all the calls made from within it.
+type callFrame struct {
+	Type    string // CALL, CALLCODE, DELEGATECALL, STATICCALL, CREATE, CREATE2 or SELFDESTRUCT
+	From    common.Address
+	To      common.Address
+	Value   *big.Int // Nil for calls not transferring value (DELEGATECALL, STATICCALL)
+	Gas     uint64
+	GasUsed uint64
+	Input   []byte
+	Output  []byte
+	Error   string
+	Calls   []*callFrame
+
+	gasIn     uint64   // Gas available to the parent before opening the frame
+	gasCost   uint64   // Cost of the opcode opening the frame
+	gasKnown  bool     // Whether the gas allowance of the frame is known
+	usedKnown bool     // Whether the gas used by the frame is known
+	outOff    *big.Int // Memory offset the call output is copied to
+	outLen    *big.Int // Memory size the call output is copied to
+}
+
+// callFrameJSON is the JSON encoding of a call frame, with the same fields as
+// the output of the JavaScript call tracer.
+type callFrameJSON struct {
+	Type    string           `json:"type"`
+	From    *common.Address  `json:"from,omitempty"`
+	To      *common.Address  `json:"to,omitempty"`
+	Value   *hexutil.Big     `json:"value,omitempty"`
+	Gas     *hexutil.Uint64  `json:"gas,omitempty"`
+	GasUsed *hexutil.Uint64  `json:"gasUsed,omitempty"`
+	Input   *hexutil.Bytes   `json:"input,omitempty"`
+	Output  *hexutil.Bytes   `json:"output,omitempty"`
+	Error   string           `json:"error,omitempty"`
+	Calls   []*callFrameJSON `json:"calls,omitempty"`
+}
+
+// toJSON converts the call frame and all its inner calls into their JSON
+// encoding, omitting the same fields the JavaScript call tracer does.
+func (call *callFrame) toJSON(root bool) *callFrameJSON {
+	enc := &callFrameJSON{Type: call.Type, Error: call.Error}
+	if call.Type == "SELFDESTRUCT" {
+		return enc
+	}
+	var (
+		from  = call.From
+		to    = call.To
+		input = hexutil.Bytes(call.Input)
+	)
+	enc.From, enc.Input = &from, &input
+	if root || call.Error == "" || (call.Type != "CREATE" && call.Type != "CREATE2") {
+		enc.To = &to
+	}
+	if call.Value != nil {
+		enc.Value = (*hexutil.Big)(call.Value)
+	}
+	if call.gasKnown {
+		gas := hexutil.Uint64(call.Gas)
+		enc.Gas = &gas
+	}
+	if call.usedKnown {
+		gasUsed := hexutil.Uint64(call.GasUsed)
+		enc.GasUsed = &gasUsed
+	}
+	if call.Error == "" && (root || call.usedKnown) {
+		output := hexutil.Bytes(call.Output)
+		enc.Output = &output
+	}
+	for _, inner := range call.Calls {
+		enc.Calls = append(enc.Calls, inner.toJSON(false))
+	}
+	return enc
+}
+
+// callTracer reconstructs the tree of calls made during the execution of a
+// transaction from the opcodes executed by the EVM.
+type callTracer struct {
+	callstack []*callFrame // Frames currently being executed, the first is the root
+	descended bool         // Whether an inner call was just entered
+}
+
+// newCallTracer creates a tracer collecting the call tree of a transaction.
+func newCallTracer() *callTracer {
+	return &callTracer{}
+}
+
+// CaptureStart implements the vm.Tracer interface to initialize the root frame.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	root := &callFrame{
+		Type:      "CALL",
+		From:      from,
+		To:        to,
+		Input:     common.CopyBytes(input),
+		Gas:       gas,
+		gasKnown:  true,
+		usedKnown: true,
+	}
+	if create {
+		root.Type = "CREATE"
+	}
+	if value != nil {
+		root.Value = new(big.Int).Set(value)
+	}
+	t.callstack = []*callFrame{root}
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to track the calls entered
+// and exited by the EVM.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// Capture any errors immediately
+	if err != nil {
+		return t.CaptureFault(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+	}
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		// A new contract is being created, add to the call stack
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    contract.Address(),
+			Input:   memorySlice(memory, stack.Back(1), stack.Back(2)),
+			Value:   new(big.Int).Set(stack.Back(0)),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		// A contract is being self destructed, gather that as a subcall too
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, &callFrame{
+			Type:  op.String(),
+			From:  contract.Address(),
+			To:    common.BigToAddress(stack.Back(0)),
+			Value: new(big.Int).Set(env.StateDB.GetBalance(contract.Address())),
+		})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := common.BigToAddress(stack.Back(1))
+		if isPrecompiled(env, to) {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		call := &callFrame{
+			Type:    op.String(),
+			From:    contract.Address(),
+			To:      to,
+			Input:   memorySlice(memory, stack.Back(2+off), stack.Back(3+off)),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  new(big.Int).Set(stack.Back(4 + off)),
+			outLen:  new(big.Int).Set(stack.Back(5 + off)),
+		}
+		if off == 1 {
+			call.Value = new(big.Int).Set(stack.Back(2))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve its true allowance. We
+	// need to extract it from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	if t.descended {
+		if depth >= len(t.callstack) {
+			call := t.callstack[len(t.callstack)-1]
+			call.Gas, call.gasKnown = gas, true
+		}
+		t.descended = false
+	}
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = vm.ErrExecutionReverted.Error()
+		return nil
+	}
+	// If an existing call is returning, pop it off the call stack
+	if depth == len(t.callstack)-1 {
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := stack.Back(0)
+		switch call.Type {
+		case "CREATE", "CREATE2":
+			// Retrieve the contract address and the deployed code
+			call.GasUsed, call.usedKnown = call.gasIn-call.gasCost-gas, true
+			if ret.Sign() != 0 {
+				call.To = common.BigToAddress(ret)
+				call.Output = env.StateDB.GetCode(call.To)
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		default:
+			// Retrieve the gas usage and the call output
+			if call.gasKnown {
+				call.GasUsed, call.usedKnown = call.gasIn-call.gasCost+call.Gas-gas, true
+				if ret.Sign() != 0 {
+					call.Output = memorySlice(memory, call.outOff, call.outLen)
+				} else if call.Error == "" {
+					call.Error = "internal failure"
+				}
+			}
+		}
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface to mark the current call as
+// failed and return to its parent.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return nil
+	}
+	// Pop off the just failed call, consuming all its gas
+	call := t.callstack[len(t.callstack)-1]
+	call.Error = err.Error()
+	if call.gasKnown {
+		call.GasUsed, call.usedKnown = call.Gas, true
+	}
+	if len(t.callstack) == 1 {
+		// The root call failed, leave it in the stack
+		return nil
+	}
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	parent := t.callstack[len(t.callstack)-1]
+	parent.Calls = append(parent.Calls, call)
+	return nil
+}
+
+// CaptureEnd implements the vm.Tracer interface to finalize the root frame.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) error {
+	root := t.callstack[0]
+	root.GasUsed = gasUsed
+	if err != nil && root.Error == "" {
+		root.Error = err.Error()
+	}
+	if root.Error == "" {
+		root.Output = common.CopyBytes(output)
+	}
+	return nil
+}
+
+// root returns the outermost call of the traced transaction.
+func (t *callTracer) root() *callFrame {
+	if len(t.callstack) == 0 {
+		return nil
+	}
+	return t.callstack[0]
+}
+
+// CallTracer is a native port of the JavaScript call tracer, reporting the tree
+// of calls made during the execution of a transaction.
+type CallTracer struct {
+	*callTracer
+	interrupter
+}
+
+// NewCallTracer creates a tracer collecting the call tree of a transaction.
+func NewCallTracer() *CallTracer {
+	return &CallTracer{callTracer: newCallTracer()}
+}
+
+// CaptureState implements the vm.Tracer interface to track the calls entered
+// and exited by the EVM, aborting it if the tracer was stopped.
+func (t *CallTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.interrupted(env) {
+		return nil
+	}
+	return t.callTracer.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+}
+
+// GetResult returns the JSON encoded call tree of the traced transaction.
+func (t *CallTracer) GetResult() (json.RawMessage, error) {
+	if err := t.reason(); err != nil {
+		return nil, err
+	}
+	root := t.root()
+	if root == nil {
+		return nil, errors.New("no execution traced")
+	}
+	return json.Marshal(root.toJSON(true))
+}
+
+// isPrecompiled reports whether the given address is a precompiled contract
+// in the context of the current block.
+func isPrecompiled(env *vm.EVM, addr common.Address) bool {
+	precompiles := vm.PrecompiledContractsHomestead
+	if env.ChainConfig().IsByzantium(env.BlockNumber) {
+		precompiles = vm.PrecompiledContractsByzantium
+	}
+	_, ok := precompiles[addr]
+	return ok
+}
+
+// memorySlice returns a copy of the given memory region, or nil if it's out of
+// the bounds of the current memory.
+func memorySlice(memory *vm.Memory, offset, size *big.Int) []byte {
+	if !offset.IsInt64() || !size.IsInt64() || size.Sign() == 0 {
+		return nil
+	}
+	off, n := offset.Int64(), size.Int64()
+	if off < 0 || n < 0 || int64(memory.Len()) < off+n {
+		log.Warn("Tracer accessed out of bound memory", "available", memory.Len(), "offset", off, "size", n)
+		return nil
+	}
+	return memory.Get(off, n)
+}
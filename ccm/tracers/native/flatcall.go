@@ -0,0 +1,164 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+)
+
+// FlatCallAction is the input of a traced call, creation or self destruction,
+// in the flat (Parity style) trace format. Only the fields relevant to the type
+// of the trace are set.
+type FlatCallAction struct {
+	CallType      string          `json:"callType,omitempty"`
+	From          *common.Address `json:"from,omitempty"`
+	To            *common.Address `json:"to,omitempty"`
+	Gas           *hexutil.Uint64 `json:"gas,omitempty"`
+	Input         *hexutil.Bytes  `json:"input,omitempty"`
+	Init          *hexutil.Bytes  `json:"init,omitempty"`
+	Value         *hexutil.Big    `json:"value,omitempty"`
+	Address       *common.Address `json:"address,omitempty"`
+	RefundAddress *common.Address `json:"refundAddress,omitempty"`
+	Balance       *hexutil.Big    `json:"balance,omitempty"`
+}
+
+// FlatCallResult is the outcome of a successful call or creation in the flat
+// trace format.
+type FlatCallResult struct {
+	Address *common.Address `json:"address,omitempty"`
+	Code    *hexutil.Bytes  `json:"code,omitempty"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+}
+
+// FlatCallFrame is a single call of a transaction in the flat trace format. The
+// position of the call within the call tree is identified by its trace address,
+// the path of call indices leading to it from the outermost call.
+type FlatCallFrame struct {
+	Action              FlatCallAction  `json:"action"`
+	BlockHash           *common.Hash    `json:"blockHash,omitempty"`
+	BlockNumber         *uint64         `json:"blockNumber,omitempty"`
+	Error               string          `json:"error,omitempty"`
+	Result              *FlatCallResult `json:"result"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     *common.Hash    `json:"transactionHash,omitempty"`
+	TransactionPosition *uint64         `json:"transactionPosition,omitempty"`
+	Type                string          `json:"type"`
+}
+
+// FlatCallTracer is a native tracer reporting all the calls, creations and self
+// destructions of a transaction as a flat list in the Parity trace format. The
+// block and transaction fields of the frames are left for the caller to fill,
+// as the tracer has no knowledge of them.
+type FlatCallTracer struct {
+	*callTracer
+}
+
+// NewFlatCallTracer creates a tracer reporting the calls of a transaction in
+// the flat trace format.
+func NewFlatCallTracer() *FlatCallTracer {
+	return &FlatCallTracer{callTracer: newCallTracer()}
+}
+
+// Output returns the return data of the outermost call, or nil if it failed.
+func (t *FlatCallTracer) Output() []byte {
+	if root := t.root(); root != nil {
+		return root.Output
+	}
+	return nil
+}
+
+// GetResult returns the calls of the traced transaction, depth first.
+func (t *FlatCallTracer) GetResult() ([]*FlatCallFrame, error) {
+	root := t.root()
+	if root == nil {
+		return nil, errors.New("no execution traced")
+	}
+	return flatten(root, nil, nil), nil
+}
+
+// flatten appends the given call and all its inner calls, depth first, to the
+// list of flat frames.
+func flatten(call *callFrame, traceAddress []int, frames []*FlatCallFrame) []*FlatCallFrame {
+	frame := &FlatCallFrame{
+		Subtraces:    len(call.Calls),
+		TraceAddress: append([]int{}, traceAddress...),
+	}
+	var (
+		from    = call.From
+		to      = call.To
+		gas     = hexutil.Uint64(call.Gas)
+		input   = hexutil.Bytes(call.Input)
+		output  = hexutil.Bytes(call.Output)
+		value   = new(hexutil.Big)
+		failure = call.Error != ""
+	)
+	if call.Value != nil {
+		value = (*hexutil.Big)(call.Value)
+	}
+	switch call.Type {
+	case "CREATE", "CREATE2":
+		frame.Type = "create"
+		frame.Action = FlatCallAction{From: &from, Gas: &gas, Init: &input, Value: value}
+		if !failure {
+			frame.Result = &FlatCallResult{Address: &to, Code: &output, GasUsed: hexutil.Uint64(call.GasUsed)}
+		}
+	case "SELFDESTRUCT":
+		frame.Type = "suicide"
+		frame.Action = FlatCallAction{Address: &from, RefundAddress: &to, Balance: value}
+	default:
+		frame.Type = "call"
+		frame.Action = FlatCallAction{CallType: strings.ToLower(call.Type), From: &from, To: &to, Gas: &gas, Input: &input, Value: value}
+		if !failure {
+			frame.Result = &FlatCallResult{GasUsed: hexutil.Uint64(call.GasUsed), Output: &output}
+		}
+	}
+	if failure {
+		frame.Error = flatCallError(call.Error)
+	}
+	frames = append(frames, frame)
+	for i, inner := range call.Calls {
+		frames = flatten(inner, append(traceAddress, i), frames)
+	}
+	return frames
+}
+
+// flatCallError converts an EVM error message into its flat trace format
+// equivalent, leaving unknown errors untouched.
+func flatCallError(err string) string {
+	switch {
+	case err == vm.ErrExecutionReverted.Error():
+		return "Reverted"
+	case err == vm.ErrOutOfGas.Error(), err == vm.ErrCodeStoreOutOfGas.Error():
+		return "Out of gas"
+	case err == vm.ErrDepth.Error():
+		return "Out of stack"
+	case strings.HasPrefix(err, "invalid opcode"):
+		return "Bad instruction"
+	case strings.HasSuffix(err, "invalid jump destination"):
+		return "Bad jump destination"
+	case strings.HasPrefix(err, "stack underflow"):
+		return "Stack underflow"
+	}
+	return err
+}
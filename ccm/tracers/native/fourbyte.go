@@ -0,0 +1,110 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+)
+
+// FourByteTracer is a native port of the JavaScript 4byte tracer, collecting the
+// method identifiers of all the calls made by a transaction along with the size
+// of the supplied data, so a reversed signature can be matched against it.
+//
+// The result is a map from "<identifier>-<data size>" to the number of calls:
+//
+//	{
+//	  "0x27dc297e-128": 1,
+//	  "0x38cc4831-0": 2
+//	}
+type FourByteTracer struct {
+	interrupter
+	ids map[string]int
+}
+
+// NewFourByteTracer creates a tracer collecting the method identifiers called
+// by a transaction.
+func NewFourByteTracer() *FourByteTracer {
+	return &FourByteTracer{ids: make(map[string]int)}
+}
+
+// store records a call to the given method identifier with the given data size.
+func (t *FourByteTracer) store(id []byte, size uint64) {
+	t.ids[fmt.Sprintf("%#x-%d", id, size)]++
+}
+
+// CaptureStart implements the vm.Tracer interface to record the identifier of
+// the outermost call.
+func (t *FourByteTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	if len(input) >= 4 {
+		t.store(input[:4], uint64(len(input)-4))
+	}
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to record the identifiers of
+// all the inner calls.
+func (t *FourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.interrupted(env) {
+		return nil
+	}
+	// Skip any opcodes that are not internal calls
+	var off int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		off = 3 // gas, addr, value, inOffset, inSize, outOffset, outSize
+	case vm.DELEGATECALL, vm.STATICCALL:
+		off = 2 // gas, addr, inOffset, inSize, outOffset, outSize
+	default:
+		return nil
+	}
+	// Skip any pre-compile invocations, those are just fancy opcodes
+	if isPrecompiled(env, common.BigToAddress(stack.Back(1))) {
+		return nil
+	}
+	size := stack.Back(off + 1)
+	if !size.IsUint64() || size.Uint64() < 4 {
+		return nil
+	}
+	if id := memorySlice(memory, stack.Back(off), big.NewInt(4)); len(id) == 4 {
+		t.store(id, size.Uint64()-4)
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface.
+func (t *FourByteTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the vm.Tracer interface.
+func (t *FourByteTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the JSON encoded method identifiers called.
+func (t *FourByteTracer) GetResult() (json.RawMessage, error) {
+	if err := t.reason(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(t.ids)
+}
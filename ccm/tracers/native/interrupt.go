@@ -0,0 +1,53 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"sync/atomic"
+
+	"github.com/ccmchain/go-ccmchain/core/vm"
+)
+
+// interrupter implements stopping a native tracer mid-execution. Once stopped,
+// the EVM running the traced transaction is aborted at the next opcode.
+type interrupter struct {
+	stopped uint32 // Atomic flag to signal execution interruption
+	stopErr error  // Error to report as the tracing result once stopped
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (i *interrupter) Stop(err error) {
+	i.stopErr = err
+	atomic.StoreUint32(&i.stopped, 1)
+}
+
+// interrupted reports whether the tracer was stopped, aborting the EVM if so.
+func (i *interrupter) interrupted(env *vm.EVM) bool {
+	if atomic.LoadUint32(&i.stopped) == 0 {
+		return false
+	}
+	env.Cancel()
+	return true
+}
+
+// reason returns the error the tracer was stopped with, or nil if it wasn't.
+func (i *interrupter) reason() error {
+	if atomic.LoadUint32(&i.stopped) == 0 {
+		return nil
+	}
+	return i.stopErr
+}
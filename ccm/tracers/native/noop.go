@@ -0,0 +1,67 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+)
+
+// NoopTracer is a native port of the JavaScript noop tracer, doing nothing but
+// satisfying the tracer interfaces. It's mostly useful to measure the overhead
+// of tracing itself.
+type NoopTracer struct {
+	interrupter
+}
+
+// NewNoopTracer creates a tracer which does nothing.
+func NewNoopTracer() *NoopTracer {
+	return new(NoopTracer)
+}
+
+// CaptureStart implements the vm.Tracer interface.
+func (t *NoopTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface.
+func (t *NoopTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	t.interrupted(env)
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface.
+func (t *NoopTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the vm.Tracer interface.
+func (t *NoopTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns an empty JSON object.
+func (t *NoopTracer) GetResult() (json.RawMessage, error) {
+	if err := t.reason(); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(`{}`), nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/crypto"
+)
+
+// PrestateAccount is the state of an account before the execution of a traced
+// transaction. Only the storage slots accessed by the transaction are included.
+type PrestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// PoststateAccount is the state of an account after the execution of a traced
+// transaction. Only the fields and storage slots modified are included.
+type PoststateAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *uint64                     `json:"nonce,omitempty"`
+	Code    *hexutil.Bytes              `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// PrestateDiff is the result of the prestate tracer in diff mode, containing the
+// accounts modified by a transaction. Accounts created by the transaction are
+// missing from the pre state, accounts deleted are missing from the post state.
+type PrestateDiff struct {
+	Pre  map[common.Address]*PrestateAccount  `json:"pre"`
+	Post map[common.Address]*PoststateAccount `json:"post"`
+}
+
+// PrestateTracerConfig are the options of the prestate tracer.
+type PrestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // Report the modified state instead of the accessed one
+}
+
+// PrestateTracer is a native port of the JavaScript prestate tracer, reporting
+// the state accessed by a transaction as it was before its execution, which is
+// sufficient to replay the transaction on top of a custom genesis. In diff mode,
+// it reports instead the accounts modified by the transaction, both before and
+// after its execution.
+type PrestateTracer struct {
+	accessSet
+	interrupter
+
+	pre      vm.StateDB // State before the execution of the transaction
+	post     vm.StateDB // State the transaction is executed on
+	config   PrestateTracerConfig
+	coinbase common.Address
+	started  bool // Whether the first opcode of the transaction was executed
+}
+
+// NewPrestateTracer creates a tracer reporting the state accessed by a
+// transaction. The pre state must be a copy of the post state made before the
+// transaction is executed on the latter.
+func NewPrestateTracer(pre, post vm.StateDB, coinbase common.Address, config PrestateTracerConfig) *PrestateTracer {
+	return &PrestateTracer{
+		accessSet: newAccessSet(),
+		pre:       pre,
+		post:      post,
+		config:    config,
+		coinbase:  coinbase,
+	}
+}
+
+// CaptureStart implements the vm.Tracer interface to mark the sender and the
+// recipient of the transaction as accessed.
+func (t *PrestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.touch(from)
+	t.touch(to)
+	if t.config.DiffMode {
+		t.touch(t.coinbase)
+	}
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to mark all the accounts and
+// storage slots an opcode accesses.
+func (t *PrestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.interrupted(env) || err != nil {
+		return nil
+	}
+	if !t.started {
+		t.touch(contract.Address())
+		t.started = true
+	}
+	switch op {
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH, vm.SELFDESTRUCT:
+		t.touch(common.BigToAddress(stack.Back(0)))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.touch(common.BigToAddress(stack.Back(1)))
+	case vm.CREATE:
+		caller := contract.Address()
+		t.touch(crypto.CreateAddress(caller, env.StateDB.GetNonce(caller)))
+	case vm.CREATE2:
+		code := memorySlice(memory, stack.Back(1), stack.Back(2))
+		t.touch(crypto.CreateAddress2(contract.Address(), common.BigToHash(stack.Back(3)), crypto.Keccak256(code)))
+	case vm.SLOAD, vm.SSTORE:
+		t.touchSlot(contract.Address(), common.BigToHash(stack.Back(0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface.
+func (t *PrestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the vm.Tracer interface.
+func (t *PrestateTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the JSON encoded accessed state, or the modified state in
+// diff mode. It must be called after the transaction is fully executed.
+func (t *PrestateTracer) GetResult() (json.RawMessage, error) {
+	if err := t.reason(); err != nil {
+		return nil, err
+	}
+	if t.config.DiffMode {
+		return json.Marshal(t.diff())
+	}
+	prestate := make(map[common.Address]*PrestateAccount)
+	for addr := range t.accounts {
+		account := &PrestateAccount{
+			Balance: (*hexutil.Big)(new(big.Int).Set(t.pre.GetBalance(addr))),
+			Nonce:   t.pre.GetNonce(addr),
+			Code:    t.pre.GetCode(addr),
+			Storage: make(map[common.Hash]common.Hash),
+		}
+		for slot := range t.slots[addr] {
+			account.Storage[slot] = t.pre.GetState(addr, slot)
+		}
+		prestate[addr] = account
+	}
+	return json.Marshal(prestate)
+}
+
+// diff compares the accessed accounts between the state before and after the
+// execution of the traced transaction, returning all the modified ones.
+func (t *PrestateTracer) diff() *PrestateDiff {
+	diff := &PrestateDiff{
+		Pre:  make(map[common.Address]*PrestateAccount),
+		Post: make(map[common.Address]*PoststateAccount),
+	}
+	for addr := range t.accounts {
+		var (
+			existed = t.pre.Exist(addr)
+			exists  = t.post.Exist(addr) && !t.post.HasSuicided(addr)
+		)
+		if !existed && !exists {
+			continue
+		}
+		pre := &PrestateAccount{
+			Balance: (*hexutil.Big)(new(big.Int).Set(t.pre.GetBalance(addr))),
+			Nonce:   t.pre.GetNonce(addr),
+			Code:    t.pre.GetCode(addr),
+			Storage: make(map[common.Hash]common.Hash),
+		}
+		post := &PoststateAccount{Storage: make(map[common.Hash]common.Hash)}
+		modified := !existed || !exists
+
+		if exists {
+			if balance := t.post.GetBalance(addr); balance.Cmp(pre.Balance.ToInt()) != 0 {
+				post.Balance, modified = (*hexutil.Big)(new(big.Int).Set(balance)), true
+			}
+			if nonce := t.post.GetNonce(addr); nonce != pre.Nonce {
+				post.Nonce, modified = &nonce, true
+			}
+			if code := hexutil.Bytes(t.post.GetCode(addr)); !bytes.Equal(code, pre.Code) {
+				post.Code, modified = &code, true
+			}
+		}
+		for slot := range t.slots[addr] {
+			var from, to common.Hash
+			if existed {
+				from = t.pre.GetState(addr, slot)
+			}
+			if exists {
+				to = t.post.GetState(addr, slot)
+			}
+			if from != to {
+				if from != (common.Hash{}) {
+					pre.Storage[slot] = from
+				}
+				if exists {
+					post.Storage[slot] = to
+				}
+				modified = true
+			}
+		}
+		if !modified {
+			continue
+		}
+		if existed {
+			diff.Pre[addr] = pre
+		}
+		if exists {
+			diff.Post[addr] = post
+		}
+	}
+	return diff
+}
@@ -0,0 +1,240 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/crypto"
+)
+
+// ValueDiff is the change of a single account field or storage slot caused by
+// a transaction. From is nil if the value was created, To is nil if it was
+// deleted, both are nil if the value is unchanged.
+type ValueDiff struct {
+	From interface{}
+	To   interface{}
+}
+
+// MarshalJSON encodes the value change in the Parity stateDiff format.
+func (d *ValueDiff) MarshalJSON() ([]byte, error) {
+	switch {
+	case d.From == nil && d.To == nil:
+		return []byte(`"="`), nil
+	case d.From == nil:
+		return json.Marshal(map[string]interface{}{"+": d.To})
+	case d.To == nil:
+		return json.Marshal(map[string]interface{}{"-": d.From})
+	default:
+		return json.Marshal(map[string]interface{}{"*": map[string]interface{}{"from": d.From, "to": d.To}})
+	}
+}
+
+// AccountDiff is the set of changes made by a transaction to a single account.
+type AccountDiff struct {
+	Balance *ValueDiff                 `json:"balance"`
+	Code    *ValueDiff                 `json:"code"`
+	Nonce   *ValueDiff                 `json:"nonce"`
+	Storage map[common.Hash]*ValueDiff `json:"storage"`
+}
+
+// StateDiff is the set of accounts modified by a transaction.
+type StateDiff map[common.Address]*AccountDiff
+
+// accessSet is a set of accounts and storage slots accessed by a transaction.
+type accessSet struct {
+	accounts map[common.Address]struct{}
+	slots    map[common.Address]map[common.Hash]struct{}
+}
+
+// newAccessSet creates an empty set of accessed state.
+func newAccessSet() accessSet {
+	return accessSet{
+		accounts: make(map[common.Address]struct{}),
+		slots:    make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// touch marks an account as accessed.
+func (s accessSet) touch(addr common.Address) {
+	s.accounts[addr] = struct{}{}
+}
+
+// touchSlot marks a storage slot as accessed.
+func (s accessSet) touchSlot(addr common.Address, slot common.Hash) {
+	s.touch(addr)
+	if s.slots[addr] == nil {
+		s.slots[addr] = make(map[common.Hash]struct{})
+	}
+	s.slots[addr][slot] = struct{}{}
+}
+
+// StateDiffTracer is a native tracer collecting the accounts and storage slots
+// touched by a transaction, to be diffed between the states before and after
+// its execution.
+type StateDiffTracer struct {
+	accessSet
+}
+
+// NewStateDiffTracer creates a tracer collecting the state touched by a
+// transaction. The coinbase of the block is always considered touched, as it
+// receives the transaction fees.
+func NewStateDiffTracer(coinbase common.Address) *StateDiffTracer {
+	t := &StateDiffTracer{accessSet: newAccessSet()}
+	t.touch(coinbase)
+	return t
+}
+
+// CaptureStart implements the vm.Tracer interface to mark the sender and the
+// recipient of the transaction as touched.
+func (t *StateDiffTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.touch(from)
+	t.touch(to)
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to mark all the accounts and
+// storage slots an opcode may modify as touched.
+func (t *StateDiffTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if err != nil {
+		return nil
+	}
+	caller := contract.Address()
+
+	switch op {
+	case vm.CALL:
+		t.touch(caller)
+		t.touch(common.BigToAddress(stack.Back(1)))
+	case vm.CREATE:
+		t.touch(caller)
+		t.touch(crypto.CreateAddress(caller, env.StateDB.GetNonce(caller)))
+	case vm.CREATE2:
+		code := memorySlice(memory, stack.Back(1), stack.Back(2))
+		t.touch(caller)
+		t.touch(crypto.CreateAddress2(caller, common.BigToHash(stack.Back(3)), crypto.Keccak256(code)))
+	case vm.SELFDESTRUCT:
+		t.touch(caller)
+		t.touch(common.BigToAddress(stack.Back(0)))
+	case vm.SSTORE:
+		t.touchSlot(caller, common.BigToHash(stack.Back(0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface.
+func (t *StateDiffTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the vm.Tracer interface.
+func (t *StateDiffTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) error {
+	return nil
+}
+
+// Diff compares the touched accounts between the state before and after the
+// execution of the traced transaction, returning all the modified ones.
+func (t *StateDiffTracer) Diff(pre, post vm.StateDB) StateDiff {
+	diff := make(StateDiff)
+	for addr := range t.accounts {
+		var (
+			existed = pre.Exist(addr)
+			exists  = post.Exist(addr) && !post.HasSuicided(addr)
+		)
+		var account *AccountDiff
+		switch {
+		case !existed && !exists:
+			continue
+		case !existed:
+			account = t.diffAccount(addr, nil, post)
+		case !exists:
+			account = t.diffAccount(addr, pre, nil)
+		default:
+			account = t.diffAccount(addr, pre, post)
+		}
+		if account != nil {
+			diff[addr] = account
+		}
+	}
+	return diff
+}
+
+// diffAccount compares the fields and touched storage of an account between two
+// states, either of which may be nil if the account doesn't exist in it. Nil is
+// returned if the account was not modified.
+func (t *StateDiffTracer) diffAccount(addr common.Address, pre, post vm.StateDB) *AccountDiff {
+	account := &AccountDiff{
+		Balance: new(ValueDiff),
+		Code:    new(ValueDiff),
+		Nonce:   new(ValueDiff),
+		Storage: make(map[common.Hash]*ValueDiff),
+	}
+	switch {
+	case pre == nil:
+		// The account was created, report all its fields as added
+		account.Balance.To = (*hexutil.Big)(new(big.Int).Set(post.GetBalance(addr)))
+		account.Code.To = hexutil.Bytes(post.GetCode(addr))
+		account.Nonce.To = hexutil.Uint64(post.GetNonce(addr))
+		for slot := range t.slots[addr] {
+			if value := post.GetState(addr, slot); value != (common.Hash{}) {
+				account.Storage[slot] = &ValueDiff{To: value}
+			}
+		}
+		return account
+
+	case post == nil:
+		// The account was deleted, report all its fields as removed
+		account.Balance.From = (*hexutil.Big)(new(big.Int).Set(pre.GetBalance(addr)))
+		account.Code.From = hexutil.Bytes(pre.GetCode(addr))
+		account.Nonce.From = hexutil.Uint64(pre.GetNonce(addr))
+		for slot := range t.slots[addr] {
+			if value := pre.GetState(addr, slot); value != (common.Hash{}) {
+				account.Storage[slot] = &ValueDiff{From: value}
+			}
+		}
+		return account
+	}
+	// The account existed before and after, report only the modified fields
+	var changed bool
+	if from, to := pre.GetBalance(addr), post.GetBalance(addr); from.Cmp(to) != 0 {
+		account.Balance = &ValueDiff{From: (*hexutil.Big)(new(big.Int).Set(from)), To: (*hexutil.Big)(new(big.Int).Set(to))}
+		changed = true
+	}
+	if from, to := pre.GetCode(addr), post.GetCode(addr); !bytes.Equal(from, to) {
+		account.Code = &ValueDiff{From: hexutil.Bytes(from), To: hexutil.Bytes(to)}
+		changed = true
+	}
+	if from, to := pre.GetNonce(addr), post.GetNonce(addr); from != to {
+		account.Nonce = &ValueDiff{From: hexutil.Uint64(from), To: hexutil.Uint64(to)}
+		changed = true
+	}
+	for slot := range t.slots[addr] {
+		if from, to := pre.GetState(addr, slot), post.GetState(addr, slot); from != to {
+			account.Storage[slot] = &ValueDiff{From: from, To: to}
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return account
+}
@@ -0,0 +1,324 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ccmchain/go-ccmchain/ccm/tracers/native"
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/core/state"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/rlp"
+	"github.com/ccmchain/go-ccmchain/tests"
+)
+
+// forEachCallTracerTest loads all the datasets of the call tracer test harness
+// and runs the given test function against each of them in parallel.
+func forEachCallTracerTest(t *testing.T, run func(t *testing.T, test *callTracerTest)) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			run(t, test)
+		})
+	}
+}
+
+// runCallTracerTest executes the transaction of a call tracer test case on top
+// of its prestate, traced by the tracer created by the given constructor.
+func runCallTracerTest(t *testing.T, test *callTracerTest, newTracer func(ctx vm.Context, statedb *state.StateDB) vm.Tracer) *types.Transaction {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc)
+
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: newTracer(context, statedb)})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	return tx
+}
+
+// newTestTracer creates a tracer by name for a call tracer test, failing the
+// test if it cannot be created.
+func newTestTracer(t *testing.T, name string, ctx vm.Context, statedb *state.StateDB, config json.RawMessage) TxTracer {
+	tracer, err := NewTracer(name, ctx, statedb, config)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", name, err)
+	}
+	return tracer
+}
+
+// Tests that the native call tracer produces the same results as the JavaScript
+// one over the call tracer test suite.
+func TestNativeCallTracer(t *testing.T) {
+	forEachCallTracerTest(t, func(t *testing.T, test *callTracerTest) {
+		var tracer TxTracer
+		runCallTracerTest(t, test, func(ctx vm.Context, statedb *state.StateDB) vm.Tracer {
+			tracer = newTestTracer(t, "callTracer", ctx, statedb, nil)
+			if _, ok := tracer.(*native.CallTracer); !ok {
+				t.Fatalf("tracer type mismatch: have %T, want %T", tracer, new(native.CallTracer))
+			}
+			return tracer
+		})
+		res, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("failed to retrieve trace result: %v", err)
+		}
+		ret := new(callTrace)
+		if err := json.Unmarshal(res, ret); err != nil {
+			t.Fatalf("failed to unmarshal trace result: %v", err)
+		}
+		if !reflect.DeepEqual(ret, test.Result) {
+			t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", ret, test.Result)
+		}
+	})
+}
+
+// Tests that the native prestate tracer reports the state the transactions of
+// the call tracer test suite were generated from, which is the output of the
+// JavaScript prestate tracer.
+func TestNativePrestateTracer(t *testing.T) {
+	forEachCallTracerTest(t, func(t *testing.T, test *callTracerTest) {
+		var tracer TxTracer
+		runCallTracerTest(t, test, func(ctx vm.Context, statedb *state.StateDB) vm.Tracer {
+			tracer = newTestTracer(t, "prestateTracer", ctx, statedb, nil)
+			return tracer
+		})
+		res, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("failed to retrieve trace result: %v", err)
+		}
+		prestate := make(map[common.Address]*native.PrestateAccount)
+		if err := json.Unmarshal(res, &prestate); err != nil {
+			t.Fatalf("failed to unmarshal trace result: %v", err)
+		}
+		for addr, want := range test.Genesis.Alloc {
+			have, ok := prestate[addr]
+			if !ok {
+				t.Fatalf("account %x missing from prestate", addr)
+			}
+			if have.Balance.ToInt().Cmp(want.Balance) != 0 {
+				t.Errorf("account %x balance mismatch: have %v, want %v", addr, have.Balance.ToInt(), want.Balance)
+			}
+			if have.Nonce != want.Nonce {
+				t.Errorf("account %x nonce mismatch: have %d, want %d", addr, have.Nonce, want.Nonce)
+			}
+			if !reflect.DeepEqual([]byte(have.Code), want.Code) && len(have.Code)+len(want.Code) > 0 {
+				t.Errorf("account %x code mismatch: have %x, want %x", addr, have.Code, want.Code)
+			}
+			for slot, value := range want.Storage {
+				if have.Storage[slot] != value {
+					t.Errorf("account %x slot %x mismatch: have %x, want %x", addr, slot, have.Storage[slot], value)
+				}
+			}
+		}
+		// Any account accessed beyond the etalon must not have existed
+		for addr, have := range prestate {
+			if _, ok := test.Genesis.Alloc[addr]; ok {
+				continue
+			}
+			if have.Balance.ToInt().Sign() != 0 || have.Nonce != 0 || len(have.Code) != 0 {
+				t.Errorf("unexpected account %x in prestate: %+v", addr, have)
+			}
+		}
+	})
+}
+
+// Tests that the native prestate tracer in diff mode reports the state changes
+// made by the transactions of the call tracer test suite.
+func TestNativePrestateTracerDiff(t *testing.T) {
+	forEachCallTracerTest(t, func(t *testing.T, test *callTracerTest) {
+		var (
+			tracer  TxTracer
+			statedb *state.StateDB
+		)
+		tx := runCallTracerTest(t, test, func(ctx vm.Context, db *state.StateDB) vm.Tracer {
+			tracer, statedb = newTestTracer(t, "prestateTracer", ctx, db, json.RawMessage(`{"diffMode": true}`)), db
+			return tracer
+		})
+		res, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("failed to retrieve trace result: %v", err)
+		}
+		diff := new(native.PrestateDiff)
+		if err := json.Unmarshal(res, diff); err != nil {
+			t.Fatalf("failed to unmarshal trace result: %v", err)
+		}
+		// The pre state must match the etalon, the post state the final state
+		for addr, have := range diff.Pre {
+			want, ok := test.Genesis.Alloc[addr]
+			if !ok {
+				t.Errorf("unexpected account %x in pre state", addr)
+				continue
+			}
+			if have.Balance.ToInt().Cmp(want.Balance) != 0 || have.Nonce != want.Nonce {
+				t.Errorf("account %x pre state mismatch: have %v/%d, want %v/%d", addr, have.Balance.ToInt(), have.Nonce, want.Balance, want.Nonce)
+			}
+		}
+		for addr, have := range diff.Post {
+			if have.Balance != nil && have.Balance.ToInt().Cmp(statedb.GetBalance(addr)) != 0 {
+				t.Errorf("account %x post balance mismatch: have %v, want %v", addr, have.Balance.ToInt(), statedb.GetBalance(addr))
+			}
+			if have.Nonce != nil && *have.Nonce != statedb.GetNonce(addr) {
+				t.Errorf("account %x post nonce mismatch: have %d, want %d", addr, *have.Nonce, statedb.GetNonce(addr))
+			}
+			for slot, value := range have.Storage {
+				if stored := statedb.GetState(addr, slot); stored != value {
+					t.Errorf("account %x post slot %x mismatch: have %x, want %x", addr, slot, value, stored)
+				}
+			}
+		}
+		// The sender always pays for the transaction and bumps its nonce
+		signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+		from, _ := signer.Sender(tx)
+		if diff.Post[from] == nil || diff.Post[from].Nonce == nil || *diff.Post[from].Nonce != diff.Pre[from].Nonce+1 {
+			t.Errorf("sender nonce change missing from diff")
+		}
+	})
+}
+
+// Tests that the simpler native tracers produce the same results as their
+// JavaScript counterparts over the call tracer test suite.
+func TestNativeTracersMatchJavaScript(t *testing.T) {
+	for _, name := range []string{"4byteTracer", "noopTracer"} {
+		name := name // capture range variable
+		t.Run(name, func(t *testing.T) {
+			forEachCallTracerTest(t, func(t *testing.T, test *callTracerTest) {
+				var (
+					nativeTracer TxTracer
+					jsTracer     *Tracer
+				)
+				runCallTracerTest(t, test, func(ctx vm.Context, statedb *state.StateDB) vm.Tracer {
+					nativeTracer = newTestTracer(t, name, ctx, statedb, nil)
+					return nativeTracer
+				})
+				runCallTracerTest(t, test, func(vm.Context, *state.StateDB) vm.Tracer {
+					var err error
+					if jsTracer, err = New(name); err != nil {
+						t.Fatalf("failed to create JavaScript %s: %v", name, err)
+					}
+					return jsTracer
+				})
+				var have, want interface{}
+				for _, res := range []struct {
+					tracer TxTracer
+					out    *interface{}
+				}{{nativeTracer, &have}, {jsTracer, &want}} {
+					blob, err := res.tracer.GetResult()
+					if err != nil {
+						t.Fatalf("failed to retrieve trace result: %v", err)
+					}
+					if err := json.Unmarshal(blob, res.out); err != nil {
+						t.Fatalf("failed to unmarshal trace result: %v", err)
+					}
+				}
+				if !reflect.DeepEqual(have, want) {
+					t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", have, want)
+				}
+			})
+		})
+	}
+}
+
+// Tests that stopping a native tracer aborts the execution and reports the
+// reason as the tracing result.
+func TestNativeTracerStop(t *testing.T) {
+	forEachCallTracerTest(t, func(t *testing.T, test *callTracerTest) {
+		var tracer TxTracer
+		runCallTracerTest(t, test, func(ctx vm.Context, statedb *state.StateDB) vm.Tracer {
+			tracer = newTestTracer(t, "callTracer", ctx, statedb, nil)
+			tracer.Stop(errors.New("stopped"))
+			return tracer
+		})
+		if _, err := tracer.GetResult(); err == nil || err.Error() != "stopped" {
+			t.Fatalf("stop error mismatch: have %v, want %v", err, "stopped")
+		}
+	})
+}
+
+// Tests that custom native tracers can be registered and take precedence over
+// the JavaScript tracers of the same name.
+func TestRegisterNativeTracer(t *testing.T) {
+	var created bool
+	Register("unigramTracer", func(vm.Context, *state.StateDB, json.RawMessage) (TxTracer, error) {
+		created = true
+		return native.NewNoopTracer(), nil
+	})
+	if _, err := NewTracer("unigramTracer", vm.Context{}, nil, nil); err != nil {
+		t.Fatalf("failed to create registered tracer: %v", err)
+	}
+	if !created {
+		t.Fatalf("registered tracer not created")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("duplicate registration did not panic")
+		}
+	}()
+	Register("unigramTracer", func(vm.Context, *state.StateDB, json.RawMessage) (TxTracer, error) {
+		return nil, nil
+	})
+}
@@ -14,18 +14,78 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
 
-// Package tracers is a collection of JavaScript transaction tracers.
+// Package tracers is a collection of JavaScript and native Go transaction tracers.
 package tracers
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/ccmchain/go-ccmchain/ccm/tracers/internal/tracers"
+	"github.com/ccmchain/go-ccmchain/core/state"
+	"github.com/ccmchain/go-ccmchain/core/vm"
 )
 
-// all contains all the built in JavaScript tracers by name.
-var all = make(map[string]string)
+// TxTracer is a transaction tracer producing a JSON result once the execution
+// is done, which can be stopped while the transaction is still running. Both
+// the JavaScript and the native tracers implement it.
+type TxTracer interface {
+	vm.Tracer
+
+	// GetResult returns the result of the tracing. It must be called only after
+	// the transaction was fully executed.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the tracing at the first opportune moment, making the
+	// tracer report the given error as its result.
+	Stop(err error)
+}
+
+// Constructor creates a native tracer for a transaction executed in the given
+// context on top of the given state, configured by the optional, tracer specific
+// JSON config.
+type Constructor func(ctx vm.Context, statedb *state.StateDB, config json.RawMessage) (TxTracer, error)
+
+var (
+	// all contains all the built in JavaScript tracers by name.
+	all = make(map[string]string)
+
+	// natives contains all the registered native tracers by name.
+	natives     = make(map[string]Constructor)
+	nativesLock sync.RWMutex
+)
+
+// Register makes a native tracer available by the provided name, taking
+// precedence over any JavaScript tracer of the same name. If Register is called
+// twice with the same name, it panics.
+func Register(name string, ctor Constructor) {
+	nativesLock.Lock()
+	defer nativesLock.Unlock()
+
+	if _, ok := natives[name]; ok {
+		panic(fmt.Sprintf("native tracer %q already registered", name))
+	}
+	natives[name] = ctor
+}
+
+// NewTracer creates a tracer for a transaction executed in the given context,
+// on top of the given state. The tracer is looked up by name among the native
+// ones first, falling back to the built in JavaScript tracers, or to compiling
+// the given code as a custom JavaScript tracer. The config is only supported by
+// native tracers and is ignored otherwise.
+func NewTracer(code string, ctx vm.Context, statedb *state.StateDB, config json.RawMessage) (TxTracer, error) {
+	nativesLock.RLock()
+	ctor, ok := natives[code]
+	nativesLock.RUnlock()
+
+	if ok {
+		return ctor(ctx, statedb, config)
+	}
+	return New(code)
+}
 
 // camel converts a snake cased input string into a camel cased output.
 func camel(str string) string {
@@ -27,6 +27,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ccmchain/go-ccmchain/ccm/tracers/native"
 	"github.com/ccmchain/go-ccmchain/common"
 	"github.com/ccmchain/go-ccmchain/common/hexutil"
 	"github.com/ccmchain/go-ccmchain/common/math"
@@ -182,7 +183,7 @@ func TestPrestateTracerCreate2(t *testing.T) {
 		t.Fatalf("failed to prepare transaction for tracing: %v", err)
 	}
 	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
-	if _, _, _, err = st.TransitionDb(); err != nil {
+	if _, err = st.TransitionDb(); err != nil {
 		t.Fatalf("failed to execute transaction: %v", err)
 	}
 	// Retrieve the trace result and compare against the etalon
@@ -256,7 +257,7 @@ func TestCallTracer(t *testing.T) {
 				t.Fatalf("failed to prepare transaction for tracing: %v", err)
 			}
 			st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
-			if _, _, _, err = st.TransitionDb(); err != nil {
+			if _, err = st.TransitionDb(); err != nil {
 				t.Fatalf("failed to execute transaction: %v", err)
 			}
 			// Retrieve the trace result and compare against the etalon
@@ -275,3 +276,133 @@ func TestCallTracer(t *testing.T) {
 		})
 	}
 }
+
+// flatCallTrace is the subset of a flat call trace checked against the nested
+// call traces of the test suite.
+type flatCallTrace struct {
+	Type         string
+	CallType     string
+	From         common.Address
+	To           common.Address
+	Failed       bool
+	Subtraces    int
+	TraceAddress []int
+}
+
+// flattenCallTrace converts a nested call trace into its expected flat traces.
+func flattenCallTrace(call *callTrace, traceAddress []int, traces []flatCallTrace) []flatCallTrace {
+	trace := flatCallTrace{
+		From:         call.From,
+		To:           call.To,
+		Failed:       call.Error != "",
+		Subtraces:    len(call.Calls),
+		TraceAddress: append([]int{}, traceAddress...),
+	}
+	switch call.Type {
+	case "CREATE", "CREATE2":
+		trace.Type = "create"
+	case "SELFDESTRUCT":
+		trace.Type = "suicide"
+	default:
+		trace.Type, trace.CallType = "call", strings.ToLower(call.Type)
+	}
+	traces = append(traces, trace)
+	for i := range call.Calls {
+		traces = flattenCallTrace(&call.Calls[i], append(traceAddress, i), traces)
+	}
+	return traces
+}
+
+// Tests that the native flat call tracer produces the same call hierarchy as the
+// JavaScript call tracer over the call tracer test suite.
+func TestFlatCallTracer(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			tx := new(types.Transaction)
+			if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+				t.Fatalf("failed to parse testcase input: %v", err)
+			}
+			signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+			origin, _ := signer.Sender(tx)
+
+			context := vm.Context{
+				CanTransfer: core.CanTransfer,
+				Transfer:    core.Transfer,
+				Origin:      origin,
+				Coinbase:    test.Context.Miner,
+				BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+				Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+				Difficulty:  (*big.Int)(test.Context.Difficulty),
+				GasLimit:    uint64(test.Context.GasLimit),
+				GasPrice:    tx.GasPrice(),
+			}
+			statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc)
+
+			tracer := native.NewFlatCallTracer()
+			evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+			msg, err := tx.AsMessage(signer)
+			if err != nil {
+				t.Fatalf("failed to prepare transaction for tracing: %v", err)
+			}
+			st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+			if _, err = st.TransitionDb(); err != nil {
+				t.Fatalf("failed to execute transaction: %v", err)
+			}
+			frames, err := tracer.GetResult()
+			if err != nil {
+				t.Fatalf("failed to retrieve trace result: %v", err)
+			}
+			have := make([]flatCallTrace, 0, len(frames))
+			for _, frame := range frames {
+				trace := flatCallTrace{
+					Type:         frame.Type,
+					CallType:     frame.Action.CallType,
+					Failed:       frame.Error != "",
+					Subtraces:    frame.Subtraces,
+					TraceAddress: frame.TraceAddress,
+				}
+				switch frame.Type {
+				case "create":
+					trace.From = *frame.Action.From
+					if frame.Result != nil {
+						trace.To = *frame.Result.Address
+					}
+				case "suicide":
+					trace.From, trace.To = *frame.Action.Address, *frame.Action.RefundAddress
+				default:
+					trace.From, trace.To = *frame.Action.From, *frame.Action.To
+				}
+				have = append(have, trace)
+			}
+			want := flattenCallTrace(test.Result, nil, nil)
+			for i := range want {
+				// Failed creations don't report the address of the contract
+				if want[i].Type == "create" && want[i].Failed {
+					want[i].To = common.Address{}
+				}
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", have, want)
+			}
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/ccmchain/go-ccmchain"
 	"github.com/ccmchain/go-ccmchain/common"
@@ -278,6 +279,29 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// BlockReceipts returns the receipts of all transactions in the block with the
+// given number, in transaction order. If number is nil, the latest known block
+// is used.
+func (ec *Client) BlockReceipts(ctx context.Context, number *big.Int) ([]*types.Receipt, error) {
+	var r []*types.Receipt
+	err := ec.c.CallContext(ctx, &r, "ccm_getBlockReceipts", toBlockNumArg(number))
+	if err == nil && r == nil {
+		return nil, ccmchain.NotFound
+	}
+	return r, err
+}
+
+// BlockReceiptsByHash returns the receipts of all transactions in the block with
+// the given hash, in transaction order.
+func (ec *Client) BlockReceiptsByHash(ctx context.Context, hash common.Hash) ([]*types.Receipt, error) {
+	var r []*types.Receipt
+	err := ec.c.CallContext(ctx, &r, "ccm_getBlockReceipts", rpc.BlockNumberOrHashWithHash(hash, false))
+	if err == nil && r == nil {
+		return nil, ccmchain.NotFound
+	}
+	return r, err
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -386,14 +410,41 @@ func (ec *Client) FilterLogs(ctx context.Context, q ccmchain.FilterQuery) ([]typ
 
 // SubscribeFilterLogs subscribes to the results of a streaming filter query.
 func (ec *Client) SubscribeFilterLogs(ctx context.Context, q ccmchain.FilterQuery, ch chan<- types.Log) (ccmchain.Subscription, error) {
-	arg, err := toFilterArg(q)
+	arg, err := toSubscriptionFilterArg(q)
 	if err != nil {
 		return nil, err
 	}
 	return ec.c.EthSubscribe(ctx, ch, "logs", arg)
 }
 
-func toFilterArg(q ccmchain.FilterQuery) (interface{}, error) {
+// ResumeFilterLogs subscribes to the results of a streaming filter query, picking
+// up after the block with the given hash, the last one seen by a previous
+// subscription. Logs of blocks since reorganised out of the canonical chain are
+// delivered again with their Removed flag set, followed by the logs of all the
+// canonical blocks after the common ancestor, before switching to new logs.
+func (ec *Client) ResumeFilterLogs(ctx context.Context, q ccmchain.FilterQuery, lastSeen common.Hash, ch chan<- types.Log) (ccmchain.Subscription, error) {
+	arg, err := toSubscriptionFilterArg(q)
+	if err != nil {
+		return nil, err
+	}
+	return ec.c.EthSubscribe(ctx, ch, "logs", arg, lastSeen)
+}
+
+// toSubscriptionFilterArg converts a filter query into log subscription criteria.
+// Subscriptions replay the logs of all blocks from an explicit starting block,
+// so an unset one only asks for new logs.
+func toSubscriptionFilterArg(q ccmchain.FilterQuery) (interface{}, error) {
+	arg, err := toFilterArg(q)
+	if err != nil {
+		return nil, err
+	}
+	if q.BlockHash == nil && q.FromBlock == nil {
+		arg["fromBlock"] = "latest"
+	}
+	return arg, nil
+}
+
+func toFilterArg(q ccmchain.FilterQuery) (map[string]interface{}, error) {
 	arg := map[string]interface{}{
 		"address": q.Addresses,
 		"topics":  q.Topics,
@@ -452,7 +503,36 @@ func (ec *Client) PendingTransactionCount(ctx context.Context) (uint, error) {
 	return uint(num), err
 }
 
-// TODO: SubscribePendingTransactions (needs server side)
+// SubscribePendingTransactions subscribes to notifications about transactions
+// entering the pending state.
+func (ec *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (ccmchain.Subscription, error) {
+	return ec.SubscribeFilteredPendingTransactions(ctx, ccmchain.PendingTxQuery{}, ch)
+}
+
+// SubscribeFilteredPendingTransactions subscribes to notifications about transactions
+// entering the pending state which match the given query.
+func (ec *Client) SubscribeFilteredPendingTransactions(ctx context.Context, q ccmchain.PendingTxQuery, ch chan<- *types.Transaction) (ccmchain.Subscription, error) {
+	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions", toPendingTxArg(q))
+}
+
+// SubscribePendingTransactionHashes subscribes to notifications about the hashes
+// of transactions entering the pending state.
+func (ec *Client) SubscribePendingTransactionHashes(ctx context.Context, ch chan<- common.Hash) (ccmchain.Subscription, error) {
+	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions")
+}
+
+func toPendingTxArg(q ccmchain.PendingTxQuery) interface{} {
+	selectors := make([]hexutil.Bytes, len(q.Selectors))
+	for i := range q.Selectors {
+		selectors[i] = q.Selectors[i][:]
+	}
+	return map[string]interface{}{
+		"fullTx":    true,
+		"from":      q.From,
+		"to":        q.To,
+		"selectors": selectors,
+	}
+}
 
 // Contract Calling
 
@@ -466,7 +546,86 @@ func (ec *Client) CallContract(ctx context.Context, msg ccmchain.CallMsg, blockN
 	var hex hexutil.Bytes
 	err := ec.c.CallContext(ctx, &hex, "ccm_call", toCallArg(msg), toBlockNumArg(blockNumber))
 	if err != nil {
-		return nil, err
+		return nil, toRevertError(err)
+	}
+	return hex, nil
+}
+
+// toRevertError converts a JSON-RPC error reporting a reverted execution into
+// a *ccmchain.RevertError, leaving all other errors untouched.
+func toRevertError(err error) error {
+	ec, ok := err.(rpc.Error)
+	if !ok || ec.ErrorCode() != 3 {
+		return err
+	}
+	de, ok := err.(rpc.DataError)
+	if !ok {
+		return err
+	}
+	hex, ok := de.ErrorData().(string)
+	if !ok {
+		return err
+	}
+	data, decErr := hexutil.Decode(hex)
+	if decErr != nil {
+		return err
+	}
+	reason := strings.TrimPrefix(strings.TrimPrefix(ec.Error(), "execution reverted"), ": ")
+	return &ccmchain.RevertError{Reason: reason, Data: data}
+}
+
+// OverrideAccount specifies the state of an account to be overridden during
+// a contract call.
+type OverrideAccount struct {
+	// Nonce sets the nonce of the account. The override is only applied
+	// when it is set to a non-zero value.
+	Nonce uint64
+	// Code sets the contract code. The override is applied when the code is
+	// non-nil, i.e. setting empty code is possible using an empty slice.
+	Code []byte
+	// Balance sets the account balance.
+	Balance *big.Int
+	// State replaces the complete storage. The override is applied when the
+	// given map is non-nil. Using an empty map wipes the entire contract
+	// storage during the call.
+	State map[common.Hash]common.Hash
+	// StateDiff overrides individual storage slots.
+	StateDiff map[common.Hash]common.Hash
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a OverrideAccount) MarshalJSON() ([]byte, error) {
+	type acc struct {
+		Nonce     hexutil.Uint64               `json:"nonce,omitempty"`
+		Code      *hexutil.Bytes               `json:"code,omitempty"`
+		Balance   *hexutil.Big                 `json:"balance,omitempty"`
+		State     *map[common.Hash]common.Hash `json:"state,omitempty"`
+		StateDiff map[common.Hash]common.Hash  `json:"stateDiff,omitempty"`
+	}
+	output := acc{
+		Nonce:     hexutil.Uint64(a.Nonce),
+		Balance:   (*hexutil.Big)(a.Balance),
+		StateDiff: a.StateDiff,
+	}
+	if a.Code != nil {
+		code := hexutil.Bytes(a.Code)
+		output.Code = &code
+	}
+	// An empty but non-nil state must still be sent to wipe the storage
+	if a.State != nil {
+		output.State = &a.State
+	}
+	return json.Marshal(output)
+}
+
+// CallContractWithOverrides executes a message call transaction like CallContract,
+// but applies the given account overrides to the state before running the call.
+// The overrides are only visible to this call and are never persisted.
+func (ec *Client) CallContractWithOverrides(ctx context.Context, msg ccmchain.CallMsg, blockNumber *big.Int, overrides map[common.Address]OverrideAccount) ([]byte, error) {
+	var hex hexutil.Bytes
+	err := ec.c.CallContext(ctx, &hex, "ccm_call", toCallArg(msg), toBlockNumArg(blockNumber), overrides)
+	if err != nil {
+		return nil, toRevertError(err)
 	}
 	return hex, nil
 }
@@ -477,7 +636,7 @@ func (ec *Client) PendingCallContract(ctx context.Context, msg ccmchain.CallMsg)
 	var hex hexutil.Bytes
 	err := ec.c.CallContext(ctx, &hex, "ccm_call", toCallArg(msg), "pending")
 	if err != nil {
-		return nil, err
+		return nil, toRevertError(err)
 	}
 	return hex, nil
 }
@@ -500,7 +659,7 @@ func (ec *Client) EstimateGas(ctx context.Context, msg ccmchain.CallMsg) (uint64
 	var hex hexutil.Uint64
 	err := ec.c.CallContext(ctx, &hex, "ccm_estimateGas", toCallArg(msg))
 	if err != nil {
-		return 0, err
+		return 0, toRevertError(err)
 	}
 	return uint64(hex), nil
 }
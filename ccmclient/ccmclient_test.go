@@ -17,6 +17,7 @@
 package ccmclient
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -48,7 +49,7 @@ var (
 	_ = ccmchain.GasPricer(&Client{})
 	_ = ccmchain.LogFilterer(&Client{})
 	_ = ccmchain.PendingStateReader(&Client{})
-	// _ = ccmchain.PendingStateEventer(&Client{})
+	_ = ccmchain.PendingStateEventer(&Client{})
 	_ = ccmchain.PendingContractCaller(&Client{})
 )
 
@@ -171,7 +172,11 @@ var (
 func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 	// Generate test chain.
 	genesis, blocks := generateTestChain()
+	return startTestBackend(t, genesis, blocks), blocks
+}
 
+// startTestBackend starts a node serving the given chain.
+func startTestBackend(t *testing.T, genesis *core.Genesis, blocks []*types.Block) *node.Node {
 	// Start Ccmchain service.
 	var ccmservice *ccm.Ccmchain
 	n, err := node.New(&node.Config{})
@@ -189,7 +194,7 @@ func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 	if _, err := ccmservice.BlockChain().InsertChain(blocks[1:]); err != nil {
 		t.Fatalf("can't import test blocks: %v", err)
 	}
-	return n, blocks
+	return n
 }
 
 func generateTestChain() (*core.Genesis, []*types.Block) {
@@ -335,3 +340,211 @@ func TestChainID(t *testing.T) {
 		t.Fatalf("ChainID returned wrong number: %+v", id)
 	}
 }
+
+func TestCallContractWithOverrides(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	// Contract returning the content of storage slot 0:
+	//   PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	var (
+		contract = common.Address{0xc0, 0xde}
+		code     = common.FromHex("0x60005460005260206000f3")
+		slot     = common.Hash{}
+		value    = common.HexToHash("0x2a")
+	)
+	tests := map[string]struct {
+		override OverrideAccount
+		want     common.Hash
+	}{
+		"code_only": {
+			override: OverrideAccount{Code: code},
+			want:     common.Hash{},
+		},
+		"full_state": {
+			override: OverrideAccount{Code: code, State: map[common.Hash]common.Hash{slot: value}},
+			want:     value,
+		},
+		"state_diff": {
+			override: OverrideAccount{Code: code, StateDiff: map[common.Hash]common.Hash{slot: value}},
+			want:     value,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			msg := ccmchain.CallMsg{From: testAddr, To: &contract}
+			got, err := ec.CallContractWithOverrides(context.Background(), msg, nil, map[common.Address]OverrideAccount{contract: tt.override})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if common.BytesToHash(got) != tt.want {
+				t.Fatalf("result mismatch: have %x, want %x", got, tt.want)
+			}
+		})
+	}
+	// The overrides must not leak into subsequent calls
+	got, err := ec.CallContract(context.Background(), ccmchain.CallMsg{From: testAddr, To: &contract}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("override leaked into plain call: %x", got)
+	}
+}
+
+func TestCallContractRevert(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	// Contract reverting with the payload appended to its code:
+	//   PUSH1 100 PUSH1 12 PUSH1 0 CODECOPY PUSH1 100 PUSH1 0 REVERT
+	var (
+		contract = common.Address{0xc0, 0xde}
+		reason   = common.FromHex("0x08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000004" +
+			"626f6f6d00000000000000000000000000000000000000000000000000000000")
+		code      = append(common.FromHex("0x6064600c60003960646000fd"), reason...)
+		overrides = map[common.Address]OverrideAccount{contract: {Code: code}}
+	)
+	msg := ccmchain.CallMsg{From: testAddr, To: &contract}
+	_, err := ec.CallContractWithOverrides(context.Background(), msg, nil, overrides)
+	revert, ok := err.(*ccmchain.RevertError)
+	if !ok {
+		t.Fatalf("expected revert error, got %v", err)
+	}
+	if revert.Reason != "boom" {
+		t.Errorf("reason mismatch: have %q, want %q", revert.Reason, "boom")
+	}
+	if !bytes.Equal(revert.Data, reason) {
+		t.Errorf("data mismatch: have %x, want %x", revert.Data, reason)
+	}
+	if have, want := revert.Error(), "execution reverted: boom"; have != want {
+		t.Errorf("message mismatch: have %q, want %q", have, want)
+	}
+}
+
+func TestBlockReceipts(t *testing.T) {
+	// Generate a chain with a transaction, which the shared test chain lacks.
+	db := rawdb.NewMemoryDatabase()
+	genesis := &core.Genesis{
+		Config: params.AllEthashProtocolChanges,
+		Alloc:  core.GenesisAlloc{testAddr: {Balance: testBalance}},
+	}
+	generate := func(i int, g *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), testAddr, new(big.Int), params.TxGas, new(big.Int), nil), types.HomesteadSigner{}, testKey)
+		g.AddTx(tx)
+	}
+	gblock := genesis.ToBlock(db)
+	chain, _ := core.GenerateChain(genesis.Config, gblock, ccmash.NewFaker(), db, 1, generate)
+	chain = append([]*types.Block{gblock}, chain...)
+
+	backend := startTestBackend(t, genesis, chain)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	receipts, err := ec.BlockReceipts(context.Background(), big.NewInt(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	txs := chain[1].Transactions()
+	if len(receipts) != len(txs) {
+		t.Fatalf("receipt count mismatch: have %d, want %d", len(receipts), len(txs))
+	}
+	for i, receipt := range receipts {
+		if receipt.TxHash != txs[i].Hash() {
+			t.Errorf("receipt %d: tx hash mismatch: have %x, want %x", i, receipt.TxHash, txs[i].Hash())
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			t.Errorf("receipt %d: status mismatch: have %d, want %d", i, receipt.Status, types.ReceiptStatusSuccessful)
+		}
+		if receipt.GasUsed != params.TxGas {
+			t.Errorf("receipt %d: gas used mismatch: have %d, want %d", i, receipt.GasUsed, params.TxGas)
+		}
+	}
+	if _, err := ec.BlockReceipts(context.Background(), big.NewInt(1000000000)); err != ccmchain.NotFound {
+		t.Fatalf("error mismatch for future block: have %v, want %v", err, ccmchain.NotFound)
+	}
+	// Retrieve the same receipts by block hash
+	byHash, err := ec.BlockReceiptsByHash(context.Background(), chain[1].Hash())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(byHash, receipts) {
+		t.Errorf("receipts by hash mismatch: have %v, want %v", byHash, receipts)
+	}
+	if _, err := ec.BlockReceiptsByHash(context.Background(), common.HexToHash("0xdeadbeef")); err != ccmchain.NotFound {
+		t.Fatalf("error mismatch for unknown block: have %v, want %v", err, ccmchain.NotFound)
+	}
+}
+
+func TestSubscribePendingTransactions(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	hashes := make(chan common.Hash, 2)
+	hashSub, err := ec.SubscribePendingTransactionHashes(context.Background(), hashes)
+	if err != nil {
+		t.Fatalf("can't subscribe to pending hashes: %v", err)
+	}
+	defer hashSub.Unsubscribe()
+
+	var (
+		target = common.Address{0xaa}
+		query  = ccmchain.PendingTxQuery{From: []common.Address{testAddr}, To: []common.Address{target}}
+		txs    = make(chan *types.Transaction, 2)
+	)
+	txSub, err := ec.SubscribeFilteredPendingTransactions(context.Background(), query, txs)
+	if err != nil {
+		t.Fatalf("can't subscribe to full pending transactions: %v", err)
+	}
+	defer txSub.Unsubscribe()
+
+	// Send one transaction to the filtered recipient and one elsewhere.
+	signer := types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainID)
+	sent := make([]*types.Transaction, 2)
+	for i, to := range []common.Address{{0xbb}, target} {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), to, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, testKey)
+		if err := ec.SendTransaction(context.Background(), tx); err != nil {
+			t.Fatalf("can't send transaction %d: %v", i, err)
+		}
+		sent[i] = tx
+	}
+	for i := range sent {
+		select {
+		case hash := <-hashes:
+			if hash != sent[i].Hash() {
+				t.Errorf("hash %d mismatch: have %x, want %x", i, hash, sent[i].Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("pending hash %d not delivered", i)
+		}
+	}
+	select {
+	case tx := <-txs:
+		if tx.Hash() != sent[1].Hash() {
+			t.Errorf("full transaction mismatch: have %x, want %x", tx.Hash(), sent[1].Hash())
+		}
+		if *tx.To() != target || tx.Value().Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("full transaction fields mismatch: to %x, value %v", tx.To(), tx.Value())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("matching full transaction not delivered")
+	}
+	select {
+	case tx := <-txs:
+		t.Fatalf("unexpected transaction %x delivered", tx.Hash())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
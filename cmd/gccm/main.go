@@ -93,6 +93,9 @@ var (
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
+		utils.AccountHistoryFlag,
+		utils.AddressTxIndexFlag,
+		utils.AddressTxIndexInternalFlag,
 		utils.LightServeFlag,
 		utils.LightLegacyServFlag,
 		utils.LightIngressFlag,
@@ -109,6 +112,7 @@ var (
 		utils.CacheTrieFlag,
 		utils.CacheGCFlag,
 		utils.CacheNoPrefetchFlag,
+		utils.CacheTraceFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
@@ -156,21 +160,49 @@ var (
 		utils.RPCPortFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
+		utils.RPCPathPrefixFlag,
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLListenAddrFlag,
 		utils.GraphQLPortFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.GraphQLPathPrefixFlag,
+		utils.GraphQLMaxDepthFlag,
+		utils.GraphQLMaxCostFlag,
+		utils.GraphQLTimeoutFlag,
+		utils.GraphQLPersistedQueriesFlag,
+		utils.GraphQLPersistedOnlyFlag,
 		utils.RPCApiFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.WSPathPrefixFlag,
+		utils.BinaryEnabledFlag,
+		utils.BinaryListenAddrFlag,
+		utils.BinaryPortFlag,
+		utils.BinaryApiFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCap,
+		utils.RPCGlobalLogBlockRange,
+		utils.RPCGlobalLogResultCap,
+		utils.RPCBatchItemLimitFlag,
+		utils.RPCBatchResponseSizeFlag,
+		utils.RPCResponseSizeFlag,
+		utils.RPCMethodTimeoutsFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+		utils.RPCMethodWeightsFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCAuditLogFlag,
+		utils.RPCAuditMethodsFlag,
+		utils.RPCAuditSampleFlag,
+		utils.RPCAuditRedactFlag,
+		utils.RPCAuditMaxSizeFlag,
+		utils.RPCAuditMaxFilesFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -80,6 +80,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.SyncModeFlag,
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
+			utils.AccountHistoryFlag,
+			utils.AddressTxIndexFlag,
+			utils.AddressTxIndexInternalFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
@@ -150,6 +153,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.CacheTrieFlag,
 			utils.CacheGCFlag,
 			utils.CacheNoPrefetchFlag,
+			utils.CacheTraceFlag,
 		},
 	},
 	{
@@ -171,18 +175,46 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
 			utils.RPCGlobalGasCap,
+			utils.RPCGlobalLogBlockRange,
+			utils.RPCGlobalLogResultCap,
+			utils.RPCBatchItemLimitFlag,
+			utils.RPCBatchResponseSizeFlag,
+			utils.RPCResponseSizeFlag,
+			utils.RPCMethodTimeoutsFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCRateBurstFlag,
+			utils.RPCMethodWeightsFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCAuditLogFlag,
+			utils.RPCAuditMethodsFlag,
+			utils.RPCAuditSampleFlag,
+			utils.RPCAuditRedactFlag,
+			utils.RPCAuditMaxSizeFlag,
+			utils.RPCAuditMaxFilesFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCPathPrefixFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSPathPrefixFlag,
+			utils.BinaryEnabledFlag,
+			utils.BinaryListenAddrFlag,
+			utils.BinaryPortFlag,
+			utils.BinaryApiFlag,
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLListenAddrFlag,
 			utils.GraphQLPortFlag,
 			utils.GraphQLCORSDomainFlag,
 			utils.GraphQLVirtualHostsFlag,
+			utils.GraphQLPathPrefixFlag,
+			utils.GraphQLMaxDepthFlag,
+			utils.GraphQLMaxCostFlag,
+			utils.GraphQLTimeoutFlag,
+			utils.GraphQLPersistedQueriesFlag,
+			utils.GraphQLPersistedOnlyFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
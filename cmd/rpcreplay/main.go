@@ -0,0 +1,249 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of go-ccmchain.
+//
+// go-ccmchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ccmchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ccmchain. If not, see <http://www.gnu.org/licenses/>.
+
+// rpcreplay replays the calls recorded in RPC audit logs against a node and
+// reports the answers which differ from the recorded ones.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+var (
+	urlFlag     = flag.String("url", "", "RPC endpoint of the node to replay the calls against")
+	methodsFlag = flag.String("methods", "", "comma separated list of methods to replay, '*' suffix matching prefixes (default all)")
+	limitFlag   = flag.Int("limit", 0, "maximum number of calls to replay (0 = no limit)")
+	timeoutFlag = flag.Duration("timeout", 30*time.Second, "timeout of a single replayed call")
+	quietFlag   = flag.Bool("quiet", false, "only print the summary, not the differing answers")
+	unsafeFlag  = flag.Bool("unsafe", false, "also replay the calls changing the state of the node (transactions, admin_*, miner_*, ...)")
+)
+
+// stateChangingMethods are the methods not replayed unless explicitly allowed,
+// as replaying them would resubmit transactions or reconfigure the node.
+var stateChangingMethods = []string{
+	"ccm_sendRawTransaction",
+	"ccm_sendTransaction",
+	"ccm_submitWork",
+	"ccm_submitHashrate",
+	"admin_*",
+	"miner_*",
+	"personal_*",
+	"debug_setHead",
+}
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "-url <endpoint> [-methods <list>] [-limit <n>] <audit log> [<audit log> ...]")
+		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr, `
+Replays the calls recorded in the given RPC audit logs, in order, against the
+node at the given endpoint and prints the calls whose answers differ from the
+recorded ones. Calls with redacted parameters and subscriptions are skipped,
+as are calls changing the state of the node unless -unsafe is given.
+Exits with status 1 if any answer differed.`)
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *urlFlag == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	client, err := rpc.Dial(*urlFlag)
+	if err != nil {
+		die(err)
+	}
+	defer client.Close()
+
+	r := &replayer{
+		client:  client,
+		timeout: *timeoutFlag,
+		limit:   *limitFlag,
+		out:     os.Stdout,
+	}
+	if *methodsFlag != "" {
+		r.methods = strings.Split(*methodsFlag, ",")
+	}
+	if *quietFlag {
+		r.out = nil
+	}
+	if !*unsafeFlag {
+		r.denied = stateChangingMethods
+	}
+	for _, path := range flag.Args() {
+		if err := r.replayFile(path); err != nil {
+			die(err)
+		}
+	}
+	fmt.Printf("Replayed %d calls: %d matched, %d differed, %d skipped\n", r.replayed, r.replayed-r.differed, r.differed, r.skipped)
+	if r.differed > 0 {
+		os.Exit(1)
+	}
+}
+
+// replayer replays recorded calls against an RPC endpoint, counting the answers
+// which differ from the recorded ones.
+type replayer struct {
+	client  *rpc.Client
+	methods []string      // methods to replay, by name or prefix if ending in '*'
+	denied  []string      // methods never replayed, even if selected
+	timeout time.Duration // timeout of a single call
+	limit   int           // maximum number of calls to replay
+	out     io.Writer     // destination of the differences, if reported
+
+	replayed int
+	differed int
+	skipped  int
+}
+
+// replayFile replays the calls recorded in an audit log file.
+func (r *replayer) replayFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for line := 1; r.limit == 0 || r.replayed < r.limit; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(data) == 0 {
+			return nil
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+		var rec rpc.AuditRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("%s:%d: invalid audit record: %v", path, line, err)
+		}
+		if err := r.replay(&rec); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+	}
+	return nil
+}
+
+// replay replays a single recorded call, reporting it if the answer differs.
+func (r *replayer) replay(rec *rpc.AuditRecord) error {
+	if !r.selected(rec.Method) || rec.Redacted || strings.HasSuffix(rec.Method, "_subscribe") || strings.HasSuffix(rec.Method, "_unsubscribe") {
+		r.skipped++
+		return nil
+	}
+	var args []interface{}
+	if len(rec.Params) > 0 && string(rec.Params) != "null" {
+		var params []json.RawMessage
+		if err := json.Unmarshal(rec.Params, &params); err != nil {
+			return fmt.Errorf("invalid parameters of %s: %v", rec.Method, err)
+		}
+		for _, param := range params {
+			args = append(args, param)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	var result json.RawMessage
+	err := r.client.CallContext(ctx, &result, rec.Method, args...)
+	r.replayed++
+
+	if diff := compare(rec, result, err); diff != "" {
+		r.differed++
+		if r.out != nil {
+			fmt.Fprintf(r.out, "%s %s (recorded %s)\n%s\n", rec.Method, rec.Params, rec.Time.Format(time.RFC3339), diff)
+		}
+	}
+	return nil
+}
+
+// selected reports whether calls to the method are to be replayed.
+func (r *replayer) selected(method string) bool {
+	if matches(r.denied, method) {
+		return false
+	}
+	return len(r.methods) == 0 || matches(r.methods, method)
+}
+
+// matches reports whether the method is in the list, by name or by prefix if
+// the list entry ends in '*'.
+func matches(list []string, method string) bool {
+	for _, m := range list {
+		m = strings.TrimSpace(m)
+		if m == method || strings.HasSuffix(m, "*") && strings.HasPrefix(method, strings.TrimSuffix(m, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare describes how the answer to a replayed call differs from the recorded
+// one, returning an empty string if they match. Results are compared as decoded
+// JSON values, ignoring formatting.
+func compare(rec *rpc.AuditRecord, result json.RawMessage, err error) string {
+	if rec.Error != nil || err != nil {
+		want, have := "no error", "no error"
+		if rec.Error != nil {
+			want = fmt.Sprintf("error %d: %s", rec.Error.Code, rec.Error.Message)
+		}
+		if err != nil {
+			have = fmt.Sprintf("error: %v", err)
+			if ec, ok := err.(rpc.Error); ok {
+				have = fmt.Sprintf("error %d: %v", ec.ErrorCode(), err)
+			}
+		}
+		if want == have {
+			return ""
+		}
+		if rec.Error == nil {
+			want = "result " + string(rec.Result)
+		}
+		if err == nil {
+			have = "result " + string(result)
+		}
+		return fmt.Sprintf("  recorded: %s\n  replayed: %s", want, have)
+	}
+	var want, have interface{}
+	if len(rec.Result) > 0 {
+		if err := json.Unmarshal(rec.Result, &want); err != nil {
+			return fmt.Sprintf("  invalid recorded result: %v", err)
+		}
+	}
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &have); err != nil {
+			return fmt.Sprintf("  invalid replayed result: %v", err)
+		}
+	}
+	if reflect.DeepEqual(want, have) {
+		return ""
+	}
+	return fmt.Sprintf("  recorded: %s\n  replayed: %s", rec.Result, result)
+}
+
+func die(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of go-ccmchain.
+//
+// go-ccmchain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ccmchain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ccmchain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+type testService struct {
+	base int
+}
+
+func (s *testService) Add(a int) int { return s.base + a }
+
+func (s *testService) Fail() error { return errors.New("failed") }
+
+func newTestServer(t *testing.T, base int, audit *rpc.AuditLogger) *rpc.Server {
+	server := rpc.NewServer()
+	if err := server.RegisterName("test", &testService{base}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("personal", &testService{base}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.RegisterName("miner", &testService{base}); err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuditLogger(audit)
+	return server
+}
+
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpcreplay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Record a few calls on a node
+	path := filepath.Join(dir, "audit.log")
+	audit, err := rpc.NewAuditLogger(rpc.AuditConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := newTestServer(t, 0, audit)
+	client := rpc.DialInProc(recorder)
+	for _, arg := range []int{0, 1} {
+		if err := client.Call(new(int), "test_add", arg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client.Call(nil, "test_fail")
+	client.Call(new(int), "personal_add", 1)
+	client.Call(new(int), "miner_add", 1)
+	client.Close()
+	recorder.Stop()
+	audit.Close()
+
+	// Replay them on a node answering identically, and on one that doesn't
+	tests := []struct {
+		base     int
+		methods  []string
+		unsafe   bool
+		replayed int
+		differed int
+		skipped  int
+	}{
+		{base: 0, replayed: 3, differed: 0, skipped: 2},
+		{base: 1, replayed: 3, differed: 2, skipped: 2},
+		{base: 1, methods: []string{"test_f*"}, replayed: 1, differed: 0, skipped: 4},
+
+		// State changing methods are only replayed if explicitly allowed
+		{base: 1, methods: []string{"miner_*"}, replayed: 0, differed: 0, skipped: 5},
+		{base: 1, methods: []string{"miner_*"}, unsafe: true, replayed: 1, differed: 1, skipped: 4},
+	}
+	for i, tt := range tests {
+		server := newTestServer(t, tt.base, nil)
+		r := &replayer{client: rpc.DialInProc(server), methods: tt.methods, timeout: time.Second}
+		if !tt.unsafe {
+			r.denied = stateChangingMethods
+		}
+		if err := r.replayFile(path); err != nil {
+			t.Fatalf("test %d: replay failed: %v", i, err)
+		}
+		if r.replayed != tt.replayed || r.differed != tt.differed || r.skipped != tt.skipped {
+			t.Errorf("test %d: replayed/differed/skipped mismatch: have %d/%d/%d, want %d/%d/%d",
+				i, r.replayed, r.differed, r.skipped, tt.replayed, tt.differed, tt.skipped)
+		}
+		r.client.Close()
+		server.Stop()
+	}
+}
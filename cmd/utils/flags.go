@@ -46,6 +46,7 @@ import (
 	"github.com/ccmchain/go-ccmchain/ccmdb"
 	"github.com/ccmchain/go-ccmchain/ccmstats"
 	"github.com/ccmchain/go-ccmchain/graphql"
+	"github.com/ccmchain/go-ccmchain/internal/ccmapi"
 	"github.com/ccmchain/go-ccmchain/les"
 	"github.com/ccmchain/go-ccmchain/log"
 	"github.com/ccmchain/go-ccmchain/metrics"
@@ -201,6 +202,18 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	AccountHistoryFlag = cli.BoolFlag{
+		Name:  "index.accounthistory",
+		Usage: "Index the balance and nonce history of all accounts (requires --gcmode=archive)",
+	}
+	AddressTxIndexFlag = cli.BoolFlag{
+		Name:  "index.addresstxs",
+		Usage: "Index the transactions sent by and to every address",
+	}
+	AddressTxIndexInternalFlag = cli.BoolFlag{
+		Name:  "index.addresstxs.internal",
+		Usage: "Also index the targets of internal calls by replaying every block (requires --gcmode=archive)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -383,6 +396,10 @@ var (
 		Name:  "cache.noprefetch",
 		Usage: "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
 	}
+	CacheTraceFlag = cli.IntFlag{
+		Name:  "cache.trace",
+		Usage: "Megabytes of disk space to use for caching tracing results (0 = disabled, deleting any cached results)",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -482,6 +499,75 @@ var (
 		Name:  "rpc.gascap",
 		Usage: "Sets a cap on gas that can be used in ccm_call/estimateGas",
 	}
+	RPCGlobalLogBlockRange = cli.Uint64Flag{
+		Name:  "rpc.logblockrange",
+		Usage: "Sets a cap on the number of blocks a ccm_getLogs query may span (0 = no cap)",
+	}
+	RPCGlobalLogResultCap = cli.IntFlag{
+		Name:  "rpc.logresultcap",
+		Usage: "Sets a cap on the number of logs a ccm_getLogs query may return (0 = no cap)",
+	}
+	RPCBatchItemLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of calls in a JSON-RPC batch request (0 = no limit)",
+		Value: node.DefaultConfig.RPCLimits.BatchItems,
+	}
+	RPCBatchResponseSizeFlag = cli.IntFlag{
+		Name:  "rpc.batchresponsesize",
+		Usage: "Maximum total size in bytes of a JSON-RPC batch response (0 = no limit)",
+		Value: node.DefaultConfig.RPCLimits.BatchResponseSize,
+	}
+	RPCResponseSizeFlag = cli.IntFlag{
+		Name:  "rpc.responsesize",
+		Usage: "Maximum size in bytes of a single JSON-RPC call result (0 = no limit)",
+		Value: node.DefaultConfig.RPCLimits.ResponseSize,
+	}
+	RPCMethodTimeoutsFlag = cli.StringFlag{
+		Name:  "rpc.methodtimeouts",
+		Usage: "Comma separated list of per-method execution timeouts (e.g. ccm_call=5s,debug_traceTransaction=1m)",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpc.ratelimit",
+		Usage: "Call tokens granted per second to each RPC client, identified by IP or token subject (0 = no limit)",
+	}
+	RPCRateBurstFlag = cli.IntFlag{
+		Name:  "rpc.rateburst",
+		Usage: "Maximum call tokens an RPC client may accumulate (0 = one second worth of tokens)",
+	}
+	RPCMethodWeightsFlag = cli.StringFlag{
+		Name:  "rpc.methodweights",
+		Usage: "Comma separated list of per-method call token costs, '*' suffix matching prefixes (e.g. ccm_getLogs=10,debug_trace*=20)",
+	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to a hex-encoded HS256 secret required to authenticate HTTP and WebSocket RPC requests (generated if missing)",
+	}
+	RPCAuditLogFlag = cli.StringFlag{
+		Name:  "rpc.auditlog",
+		Usage: "Path of a rotating file recording RPC calls and their answers (empty = disabled)",
+	}
+	RPCAuditMethodsFlag = cli.StringFlag{
+		Name:  "rpc.auditmethods",
+		Usage: "Comma separated list of RPC methods to record, '*' suffix matching prefixes (empty = all)",
+	}
+	RPCAuditSampleFlag = cli.Float64Flag{
+		Name:  "rpc.auditsample",
+		Usage: "Fraction of the RPC calls to record (0 = all)",
+	}
+	RPCAuditRedactFlag = cli.StringFlag{
+		Name:  "rpc.auditredact",
+		Usage: "Comma separated list of RPC methods whose parameters are not recorded, besides personal_*",
+	}
+	RPCAuditMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.auditmaxsize",
+		Usage: "Size in megabytes at which the RPC audit log is rotated (0 = never)",
+		Value: node.DefaultConfig.RPCAudit.MaxSize / (1024 * 1024),
+	}
+	RPCAuditMaxFilesFlag = cli.IntFlag{
+		Name:  "rpc.auditmaxfiles",
+		Usage: "Number of rotated RPC audit log files kept",
+		Value: node.DefaultConfig.RPCAudit.MaxFiles,
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ccmstats",
@@ -533,6 +619,11 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCPathPrefixFlag = cli.StringFlag{
+		Name:  "rpcprefix",
+		Usage: "URL path prefix under which the HTTP-RPC interface is served",
+		Value: "",
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -557,6 +648,30 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSPathPrefixFlag = cli.StringFlag{
+		Name:  "wsprefix",
+		Usage: "URL path prefix under which the WS-RPC interface is served (shares the HTTP-RPC port if --wsaddr and --wsport match it)",
+		Value: "",
+	}
+	BinaryEnabledFlag = cli.BoolFlag{
+		Name:  "binrpc",
+		Usage: "Enable the binary framed RPC server (rlp+tcp://)",
+	}
+	BinaryListenAddrFlag = cli.StringFlag{
+		Name:  "binrpc.addr",
+		Usage: "Binary RPC server listening interface",
+		Value: node.DefaultBinaryHost,
+	}
+	BinaryPortFlag = cli.IntFlag{
+		Name:  "binrpc.port",
+		Usage: "Binary RPC server listening port",
+		Value: node.DefaultBinaryPort,
+	}
+	BinaryApiFlag = cli.StringFlag{
+		Name:  "binrpc.api",
+		Usage: "API's offered over the binary RPC interface",
+		Value: "",
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable the GraphQL server",
@@ -581,6 +696,34 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	GraphQLPathPrefixFlag = cli.StringFlag{
+		Name:  "graphql.prefix",
+		Usage: "URL path prefix under which GraphQL is served if sharing the HTTP-RPC port",
+		Value: node.DefaultConfig.GraphQLPathPrefix,
+	}
+	GraphQLMaxDepthFlag = cli.IntFlag{
+		Name:  "graphql.maxdepth",
+		Usage: "Maximum field nesting depth of GraphQL operations (0 = unlimited)",
+		Value: node.DefaultConfig.GraphQLMaxDepth,
+	}
+	GraphQLMaxCostFlag = cli.IntFlag{
+		Name:  "graphql.maxcost",
+		Usage: "Maximum estimated cost of GraphQL operations, counting resolved fields weighted by expense (0 = unlimited)",
+		Value: node.DefaultConfig.GraphQLMaxCost,
+	}
+	GraphQLTimeoutFlag = cli.DurationFlag{
+		Name:  "graphql.timeout",
+		Usage: "Maximum execution time of GraphQL operations (0 = unlimited)",
+		Value: node.DefaultConfig.GraphQLTimeout,
+	}
+	GraphQLPersistedQueriesFlag = cli.StringFlag{
+		Name:  "graphql.persisted",
+		Usage: "Path to a JSON file mapping persisted query IDs to GraphQL documents",
+	}
+	GraphQLPersistedOnlyFlag = cli.BoolFlag{
+		Name:  "graphql.persistedonly",
+		Usage: "Only execute the persisted GraphQL queries",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -908,6 +1051,82 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCPathPrefixFlag.Name) {
+		cfg.HTTPPathPrefix = ctx.GlobalString(RPCPathPrefixFlag.Name)
+	}
+}
+
+// setRPCLimits configures the resource limits of the RPC endpoints from the set
+// command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchItemLimitFlag.Name) {
+		cfg.RPCLimits.BatchItems = ctx.GlobalInt(RPCBatchItemLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseSizeFlag.Name) {
+		cfg.RPCLimits.BatchResponseSize = ctx.GlobalInt(RPCBatchResponseSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseSizeFlag.Name) {
+		cfg.RPCLimits.ResponseSize = ctx.GlobalInt(RPCResponseSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodTimeoutsFlag.Name) {
+		timeouts := make(map[string]time.Duration)
+		for _, entry := range splitAndTrim(ctx.GlobalString(RPCMethodTimeoutsFlag.Name)) {
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				Fatalf("Invalid --%s entry %q, expected method=duration", RPCMethodTimeoutsFlag.Name, entry)
+			}
+			timeout, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+			if err != nil {
+				Fatalf("Invalid --%s timeout for %s: %v", RPCMethodTimeoutsFlag.Name, parts[0], err)
+			}
+			timeouts[strings.TrimSpace(parts[0])] = timeout
+		}
+		cfg.RPCLimits.MethodTimeouts = timeouts
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCLimits.RateLimit = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCLimits.RateBurst = ctx.GlobalInt(RPCRateBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodWeightsFlag.Name) {
+		weights := make(map[string]int)
+		for _, entry := range splitAndTrim(ctx.GlobalString(RPCMethodWeightsFlag.Name)) {
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 {
+				Fatalf("Invalid --%s entry %q, expected method=weight", RPCMethodWeightsFlag.Name, entry)
+			}
+			weight, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil || weight < 0 {
+				Fatalf("Invalid --%s weight for %s: %q", RPCMethodWeightsFlag.Name, parts[0], parts[1])
+			}
+			weights[strings.TrimSpace(parts[0])] = weight
+		}
+		cfg.RPCLimits.MethodWeights = weights
+	}
+}
+
+// setRPCAudit configures the audit log of the RPC endpoints from the set command
+// line flags.
+func setRPCAudit(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCAuditLogFlag.Name) {
+		cfg.RPCAudit.Path = ctx.GlobalString(RPCAuditLogFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAuditMethodsFlag.Name) {
+		cfg.RPCAudit.Methods = splitAndTrim(ctx.GlobalString(RPCAuditMethodsFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCAuditSampleFlag.Name) {
+		cfg.RPCAudit.SampleRate = ctx.GlobalFloat64(RPCAuditSampleFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAuditRedactFlag.Name) {
+		cfg.RPCAudit.Redact = splitAndTrim(ctx.GlobalString(RPCAuditRedactFlag.Name))
+	}
+	if ctx.GlobalIsSet(RPCAuditMaxSizeFlag.Name) {
+		cfg.RPCAudit.MaxSize = ctx.GlobalInt(RPCAuditMaxSizeFlag.Name) * 1024 * 1024
+	}
+	if ctx.GlobalIsSet(RPCAuditMaxFilesFlag.Name) {
+		cfg.RPCAudit.MaxFiles = ctx.GlobalInt(RPCAuditMaxFilesFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -926,6 +1145,24 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(GraphQLPathPrefixFlag.Name) {
+		cfg.GraphQLPathPrefix = ctx.GlobalString(GraphQLPathPrefixFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLMaxDepthFlag.Name) {
+		cfg.GraphQLMaxDepth = ctx.GlobalInt(GraphQLMaxDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLMaxCostFlag.Name) {
+		cfg.GraphQLMaxCost = ctx.GlobalInt(GraphQLMaxCostFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLTimeoutFlag.Name) {
+		cfg.GraphQLTimeout = ctx.GlobalDuration(GraphQLTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLPersistedQueriesFlag.Name) {
+		cfg.GraphQLPersistedQueries = ctx.GlobalString(GraphQLPersistedQueriesFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLPersistedOnlyFlag.Name) {
+		cfg.GraphQLPersistedOnly = ctx.GlobalBool(GraphQLPersistedOnlyFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -946,6 +1183,26 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSPathPrefixFlag.Name) {
+		cfg.WSPathPrefix = ctx.GlobalString(WSPathPrefixFlag.Name)
+	}
+}
+
+// setBinary creates the binary RPC listener interface string from the set
+// command line flags, returning empty if the binary endpoint is disabled.
+func setBinary(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(BinaryEnabledFlag.Name) && cfg.BinaryHost == "" {
+		cfg.BinaryHost = "127.0.0.1"
+		if ctx.GlobalIsSet(BinaryListenAddrFlag.Name) {
+			cfg.BinaryHost = ctx.GlobalString(BinaryListenAddrFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(BinaryPortFlag.Name) {
+		cfg.BinaryPort = ctx.GlobalInt(BinaryPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(BinaryApiFlag.Name) {
+		cfg.BinaryModules = splitAndTrim(ctx.GlobalString(BinaryApiFlag.Name))
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -1154,6 +1411,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setBinary(ctx, cfg)
+	setRPCLimits(ctx, cfg)
+	setRPCAudit(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1161,6 +1421,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
 		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
@@ -1431,6 +1694,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ccm.Config) {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	if ctx.GlobalIsSet(AccountHistoryFlag.Name) {
+		cfg.AccountHistory = ctx.GlobalBool(AccountHistoryFlag.Name)
+	}
+	if ctx.GlobalIsSet(AddressTxIndexFlag.Name) {
+		cfg.AddressTxIndex = ctx.GlobalBool(AddressTxIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(AddressTxIndexInternalFlag.Name) {
+		cfg.AddressTxIndexInternal = ctx.GlobalBool(AddressTxIndexInternalFlag.Name)
+	}
 	cfg.NoPrefetch = ctx.GlobalBool(CacheNoPrefetchFlag.Name)
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
@@ -1454,9 +1726,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ccm.Config) {
 	if ctx.GlobalIsSet(EVMInterpreterFlag.Name) {
 		cfg.EVMInterpreter = ctx.GlobalString(EVMInterpreterFlag.Name)
 	}
+	if ctx.GlobalIsSet(CacheTraceFlag.Name) {
+		cfg.TraceCache = ctx.GlobalInt(CacheTraceFlag.Name)
+	}
 	if ctx.GlobalIsSet(RPCGlobalGasCap.Name) {
 		cfg.RPCGasCap = new(big.Int).SetUint64(ctx.GlobalUint64(RPCGlobalGasCap.Name))
 	}
+	if ctx.GlobalIsSet(RPCGlobalLogBlockRange.Name) {
+		cfg.RPCLogBlockRange = ctx.GlobalUint64(RPCGlobalLogBlockRange.Name)
+	}
+	if ctx.GlobalIsSet(RPCGlobalLogResultCap.Name) {
+		cfg.RPCLogResultCap = ctx.GlobalInt(RPCGlobalLogResultCap.Name)
+	}
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -1568,17 +1849,26 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 }
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
-func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) {
+// If the endpoint is the node's HTTP RPC one, GraphQL is served under the given path prefix of that listener.
+func RegisterGraphQLService(stack *node.Node, endpoint string, prefix string, cors, vhosts []string, timeouts rpc.HTTPTimeouts, limits graphql.Limits) {
+	mounted := endpoint == stack.HTTPEndpoint()
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-		// Try to construct the GraphQL service backed by a full node
-		var ccmServ *ccm.Ccmchain
+		// Try to construct the GraphQL service backed by a full node, then by a light node
+		var (
+			backend ccmapi.Backend
+			ccmServ *ccm.Ccmchain
+			lesServ *les.LightCcmchain
+		)
 		if err := ctx.Service(&ccmServ); err == nil {
-			return graphql.New(ccmServ.APIBackend, endpoint, cors, vhosts, timeouts)
+			backend = ccmServ.APIBackend
+		} else if err := ctx.Service(&lesServ); err == nil {
+			backend = lesServ.ApiBackend
 		}
-		// Try to construct the GraphQL service backed by a light node
-		var lesServ *les.LightCcmchain
-		if err := ctx.Service(&lesServ); err == nil {
-			return graphql.New(lesServ.ApiBackend, endpoint, cors, vhosts, timeouts)
+		if backend != nil {
+			if mounted {
+				return graphql.NewMounted(ctx, backend, prefix, cors, vhosts, limits)
+			}
+			return graphql.New(backend, endpoint, cors, vhosts, timeouts, limits)
 		}
 		// Well, this should not have happened, bail out
 		return nil, errors.New("no Ccmchain service")
@@ -1587,6 +1877,28 @@ func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []st
 	}
 }
 
+// MakeGraphQLLimits assembles the limits of the GraphQL service from the node
+// configuration, loading the persisted queries if any are configured.
+func MakeGraphQLLimits(cfg *node.Config) graphql.Limits {
+	limits := graphql.Limits{
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxCost:       cfg.GraphQLMaxCost,
+		Timeout:       cfg.GraphQLTimeout,
+		PersistedOnly: cfg.GraphQLPersistedOnly,
+	}
+	if cfg.GraphQLPersistedQueries != "" {
+		queries, err := graphql.LoadPersistedQueries(cfg.GraphQLPersistedQueries)
+		if err != nil {
+			Fatalf("Failed to load persisted GraphQL queries: %v", err)
+		}
+		limits.PersistedQueries = queries
+	}
+	if limits.PersistedOnly && len(limits.PersistedQueries) == 0 {
+		Fatalf("GraphQL restricted to persisted queries, but none are configured")
+	}
+	return limits
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
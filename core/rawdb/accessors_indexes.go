@@ -129,3 +129,31 @@ func WriteBloomBits(db ccmdb.KeyValueWriter, bit uint, section uint64, head comm
 		log.Crit("Failed to store bloom bits", "err", err)
 	}
 }
+
+// ReadAccountHistory retrieves the compressed change bit vector of an account,
+// identified by the hash of its address, within the given section.
+func ReadAccountHistory(db ccmdb.KeyValueReader, account common.Hash, section uint64, head common.Hash) ([]byte, error) {
+	return db.Get(accountHistoryKey(account, section, head))
+}
+
+// WriteAccountHistory stores the compressed change bit vector of an account,
+// identified by the hash of its address, within the given section.
+func WriteAccountHistory(db ccmdb.KeyValueWriter, account common.Hash, section uint64, head common.Hash, bits []byte) {
+	if err := db.Put(accountHistoryKey(account, section, head), bits); err != nil {
+		log.Crit("Failed to store account history", "err", err)
+	}
+}
+
+// ReadAddressTxs retrieves the encoded transaction postings of an address within
+// the given section.
+func ReadAddressTxs(db ccmdb.KeyValueReader, address common.Address, section uint64, head common.Hash) ([]byte, error) {
+	return db.Get(addressTxsKey(address, section, head))
+}
+
+// WriteAddressTxs stores the encoded transaction postings of an address within
+// the given section.
+func WriteAddressTxs(db ccmdb.KeyValueWriter, address common.Address, section uint64, head common.Hash, postings []byte) {
+	if err := db.Put(addressTxsKey(address, section, head), postings); err != nil {
+		log.Crit("Failed to store address transactions", "err", err)
+	}
+}
@@ -242,7 +242,10 @@ func InspectDatabase(db ccmdb.Database) error {
 		txlookupSize    common.StorageSize
 		preimageSize    common.StorageSize
 		bloomBitsSize   common.StorageSize
+		accHistorySize  common.StorageSize
+		addressTxsSize  common.StorageSize
 		cliqueSnapsSize common.StorageSize
+		traceCacheSize  common.StorageSize
 
 		// Ancient store statistics
 		ancientHeaders  common.StorageSize
@@ -285,12 +288,18 @@ func InspectDatabase(db ccmdb.Database) error {
 			preimageSize += size
 		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
 			bloomBitsSize += size
+		case bytes.HasPrefix(key, accountHistoryPrefix) && len(key) == (len(accountHistoryPrefix)+8+2*common.HashLength):
+			accHistorySize += size
+		case bytes.HasPrefix(key, addressTxsPrefix) && len(key) == (len(addressTxsPrefix)+common.AddressLength+8+common.HashLength):
+			addressTxsSize += size
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
 			cliqueSnapsSize += size
 		case bytes.HasPrefix(key, []byte("cht-")) && len(key) == 4+common.HashLength:
 			chtTrieNodes += size
 		case bytes.HasPrefix(key, []byte("blt-")) && len(key) == 4+common.HashLength:
 			bloomTrieNodes += size
+		case bytes.HasPrefix(key, TraceCachePrefix):
+			traceCacheSize += size
 		case len(key) == common.HashLength:
 			trieSize += size
 		default:
@@ -330,9 +339,12 @@ func InspectDatabase(db ccmdb.Database) error {
 		{"Key-Value store", "Block hash->number", hashNumPairing.String()},
 		{"Key-Value store", "Transaction index", txlookupSize.String()},
 		{"Key-Value store", "Bloombit index", bloomBitsSize.String()},
+		{"Key-Value store", "Account history index", accHistorySize.String()},
+		{"Key-Value store", "Address transaction index", addressTxsSize.String()},
 		{"Key-Value store", "Trie nodes", trieSize.String()},
 		{"Key-Value store", "Trie preimages", preimageSize.String()},
 		{"Key-Value store", "Clique snapshots", cliqueSnapsSize.String()},
+		{"Key-Value store", "Trace cache", traceCacheSize.String()},
 		{"Key-Value store", "Singleton metadata", metadata.String()},
 		{"Ancient store", "Headers", ancientHeaders.String()},
 		{"Ancient store", "Bodies", ancientBodies.String()},
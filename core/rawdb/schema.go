@@ -53,11 +53,18 @@ var (
 	txLookupPrefix  = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 
+	accountHistoryPrefix = []byte("a") // accountHistoryPrefix + account hash + section (uint64 big endian) + hash -> account change bits
+	addressTxsPrefix     = []byte("x") // addressTxsPrefix + address + section (uint64 big endian) + hash -> address transaction postings
+
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ccmchain-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
-	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	BloomBitsIndexPrefix      = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	AccountHistoryIndexPrefix = []byte("iA") // AccountHistoryIndexPrefix is the data table of the account history indexer to track its progress
+	AddressTxsIndexPrefix     = []byte("iX") // AddressTxsIndexPrefix is the data table of the address transaction indexer to track its progress
+
+	TraceCachePrefix = []byte("traceCache-") // TraceCachePrefix is the data table of the tracing result cache
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -155,6 +162,22 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// accountHistoryKey = accountHistoryPrefix + account hash + section (uint64 big endian) + hash
+func accountHistoryKey(account common.Hash, section uint64, hash common.Hash) []byte {
+	key := append(append(accountHistoryPrefix, account.Bytes()...), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(accountHistoryPrefix)+common.HashLength:], section)
+
+	return append(key, hash.Bytes()...)
+}
+
+// addressTxsKey = addressTxsPrefix + address + section (uint64 big endian) + hash
+func addressTxsKey(address common.Address, section uint64, hash common.Hash) []byte {
+	key := append(append(addressTxsPrefix, address.Bytes()...), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(addressTxsPrefix)+common.AddressLength:], section)
+
+	return append(key, hash.Bytes()...)
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
@@ -0,0 +1,394 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// maxQueryCost caps cost estimates, keeping their arithmetic clear of overflows.
+const maxQueryCost = math.MaxInt32
+
+// fieldCosts are the estimated costs of resolving fields notably more expensive
+// than loading a single object from the database. All other fields cost 1.
+var fieldCosts = map[string]int{
+	"call":        20,
+	"estimateGas": 100,
+	"simulate":    50,
+	"trace":       50,
+	"logs":        10,
+}
+
+// replayFields are fields re-executing all the transactions preceding their own
+// in its block. Their cost is charged for every transaction of a typical block,
+// so that resolving them for all the transactions of a block costs quadratically.
+var replayFields = map[string]bool{
+	"trace": true,
+}
+
+// listSizes are the estimated number of items returned by list fields, which
+// multiply the cost of their selections. The number of items returned by blocks
+// is derived from its arguments instead.
+var listSizes = map[string]int{
+	"ommers":       2,
+	"transactions": 200,
+	"receipts":     200,
+	"logs":         100,
+	"calls":        10,
+	"storageDiff":  20,
+}
+
+// costSelection is a field, fragment spread or inline fragment selected in a
+// GraphQL document, as far as cost estimation is concerned.
+type costSelection struct {
+	field    string            // Name of the selected field, empty for fragments
+	args     map[string]string // Scalar literals or $variables passed to the field
+	spread   string            // Name of the spread fragment, empty otherwise
+	children []*costSelection  // Selection set of the field or inline fragment
+}
+
+// costDocument is a GraphQL document parsed for cost estimation.
+type costDocument struct {
+	operations map[string][]*costSelection // Selections of the operations by name
+	fragments  map[string][]*costSelection // Selections of the fragments by name
+}
+
+// queryCost estimates the cost of executing the named operation of a GraphQL
+// document, that is the number of fields resolved weighted by their expense.
+// Lists are assumed to hold a typical number of items, except the requested
+// range of blocks, for which the current head number is used as the default
+// upper bound. Estimates are capped at maxQueryCost.
+func queryCost(query, operationName string, variables map[string]interface{}, head func() uint64) (int, error) {
+	doc, err := parseCostDocument(query)
+	if err != nil {
+		return 0, err
+	}
+	sels, ok := doc.operations[operationName]
+	if !ok && operationName == "" && len(doc.operations) == 1 {
+		for _, sels = range doc.operations {
+			ok = true
+		}
+	}
+	if !ok {
+		return 0, fmt.Errorf("unknown operation %q", operationName)
+	}
+	est := &costEstimator{doc: doc, vars: variables, head: head, visiting: make(map[string]bool)}
+	return est.cost(sels, 1), nil
+}
+
+// costEstimator accumulates the cost of selection sets.
+type costEstimator struct {
+	doc      *costDocument
+	vars     map[string]interface{}
+	head     func() uint64
+	visiting map[string]bool // Fragments being expanded, to break cycles
+}
+
+// cost returns the cost of resolving a selection set for the given number of
+// parent objects.
+func (e *costEstimator) cost(sels []*costSelection, items int) int {
+	total := 0
+	for _, sel := range sels {
+		switch {
+		case sel.spread != "":
+			if e.visiting[sel.spread] {
+				continue
+			}
+			e.visiting[sel.spread] = true
+			total = addCost(total, e.cost(e.doc.fragments[sel.spread], items))
+			delete(e.visiting, sel.spread)
+
+		case sel.field == "":
+			// Inline fragments might all apply, count each of them
+			total = addCost(total, e.cost(sel.children, items))
+
+		default:
+			weight, ok := fieldCosts[sel.field]
+			if !ok {
+				weight = 1
+			}
+			if replayFields[sel.field] {
+				weight = mulCost(weight, listSizes["transactions"])
+			}
+			total = addCost(total, mulCost(items, weight))
+			if len(sel.children) > 0 {
+				total = addCost(total, e.cost(sel.children, mulCost(items, e.listSize(sel))))
+			}
+		}
+		if total >= maxQueryCost {
+			return maxQueryCost
+		}
+	}
+	return total
+}
+
+// listSize returns the number of items the selected field is expected to
+// resolve to.
+func (e *costEstimator) listSize(sel *costSelection) int {
+	if sel.field == "blocks" {
+		from, ok := e.number(sel.args["from"])
+		if !ok {
+			from = 0
+		}
+		to, ok := e.number(sel.args["to"])
+		if !ok && e.head != nil {
+			to = e.head()
+		}
+		if to < from {
+			return 0
+		}
+		if to-from >= maxQueryCost {
+			return maxQueryCost
+		}
+		return int(to-from) + 1
+	}
+	if size, ok := listSizes[sel.field]; ok {
+		return size
+	}
+	return 1
+}
+
+// number interprets a literal or variable argument as a block number.
+func (e *costEstimator) number(arg string) (uint64, bool) {
+	if arg == "" {
+		return 0, false
+	}
+	if arg[0] == '$' {
+		switch v := e.vars[arg[1:]].(type) {
+		case float64:
+			if v < 0 {
+				return 0, false
+			}
+			if v >= math.MaxUint64 {
+				return math.MaxUint64, true
+			}
+			return uint64(v), true
+		case string:
+			arg = v
+		default:
+			return 0, false
+		}
+	} else if len(arg) > 1 && arg[0] == '"' {
+		arg = arg[1 : len(arg)-1]
+	}
+	n, err := strconv.ParseUint(arg, 0, 64)
+	return n, err == nil
+}
+
+func addCost(a, b int) int {
+	if a+b >= maxQueryCost {
+		return maxQueryCost
+	}
+	return a + b
+}
+
+func mulCost(a, b int) int {
+	if a != 0 && b > maxQueryCost/a {
+		return maxQueryCost
+	}
+	return a * b
+}
+
+// errCostSyntax is returned if a document cannot be parsed for cost estimation.
+var errCostSyntax = errors.New("syntax error in query document")
+
+// costParser is a minimal GraphQL document parser, extracting the selection
+// sets of operations and fragments. Documents are validated against the schema
+// before their cost is estimated, so it is not concerned with reporting errors
+// precisely.
+type costParser struct {
+	*lexer
+}
+
+// parseCostDocument parses the operations and fragments of a GraphQL document.
+func parseCostDocument(query string) (*costDocument, error) {
+	p := &costParser{newLexer(query)}
+
+	doc := &costDocument{
+		operations: make(map[string][]*costSelection),
+		fragments:  make(map[string][]*costSelection),
+	}
+	for p.tok != "" {
+		switch p.tok {
+		case "{":
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations[""] = sels
+
+		case "query", "mutation", "subscription":
+			p.next()
+			name := ""
+			if isNameToken(p.tok) {
+				name = p.tok
+				p.next()
+			}
+			if p.tok == "(" {
+				p.skipBalanced()
+			}
+			p.directives()
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations[name] = sels
+
+		case "fragment":
+			p.next()
+			name := p.tok
+			p.next()
+			if p.tok != "on" {
+				return nil, errCostSyntax
+			}
+			p.next() // "on"
+			p.next() // Type condition
+			p.directives()
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = sels
+
+		default:
+			return nil, errCostSyntax
+		}
+	}
+	return doc, nil
+}
+
+// selectionSet parses a selection set, including its braces.
+func (p *costParser) selectionSet() ([]*costSelection, error) {
+	if p.tok != "{" {
+		return nil, errCostSyntax
+	}
+	p.next()
+
+	var sels []*costSelection
+	for p.tok != "}" {
+		switch {
+		case p.tok == "...":
+			p.next()
+			if p.tok == "on" || p.tok == "@" || p.tok == "{" {
+				if p.tok == "on" {
+					p.next()
+					p.next()
+				}
+				p.directives()
+				children, err := p.selectionSet()
+				if err != nil {
+					return nil, err
+				}
+				sels = append(sels, &costSelection{children: children})
+				continue
+			}
+			if !isNameToken(p.tok) {
+				return nil, errCostSyntax
+			}
+			sels = append(sels, &costSelection{spread: p.tok})
+			p.next()
+			p.directives()
+
+		case isNameToken(p.tok):
+			sel := &costSelection{field: p.tok}
+			if p.next(); p.tok == ":" {
+				p.next()
+				sel.field = p.tok
+				p.next()
+			}
+			if p.tok == "(" {
+				if err := p.arguments(sel); err != nil {
+					return nil, err
+				}
+			}
+			p.directives()
+			if p.tok == "{" {
+				children, err := p.selectionSet()
+				if err != nil {
+					return nil, err
+				}
+				sel.children = children
+			}
+			sels = append(sels, sel)
+
+		default:
+			return nil, errCostSyntax
+		}
+	}
+	p.next()
+	return sels, nil
+}
+
+// arguments parses the argument list of a field, retaining scalar values.
+func (p *costParser) arguments(sel *costSelection) error {
+	sel.args = make(map[string]string)
+	for p.next(); p.tok != ")"; {
+		if !isNameToken(p.tok) {
+			return errCostSyntax
+		}
+		name := p.tok
+		if p.next(); p.tok != ":" {
+			return errCostSyntax
+		}
+		switch p.next(); p.tok {
+		case "{", "[":
+			p.skipBalanced()
+		case "$":
+			p.next()
+			sel.args[name] = "$" + p.tok
+			p.next()
+		case "":
+			return errCostSyntax
+		default:
+			sel.args[name] = p.tok
+			p.next()
+		}
+	}
+	p.next()
+	return nil
+}
+
+// directives skips any directives applied at the current position.
+func (p *costParser) directives() {
+	for p.tok == "@" {
+		p.next() // "@"
+		p.next() // Directive name
+		if p.tok == "(" {
+			p.skipBalanced()
+		}
+	}
+}
+
+// skipBalanced skips the current opening bracket up to and including its
+// matching closing bracket.
+func (p *costParser) skipBalanced() {
+	for depth := 0; p.tok != ""; {
+		switch p.tok {
+		case "{", "(", "[":
+			depth++
+		case "}", ")", "]":
+			depth--
+		}
+		p.next()
+		if depth == 0 {
+			return
+		}
+	}
+}
@@ -20,6 +20,7 @@ package graphql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ccmchain/go-ccmchain"
@@ -40,14 +41,14 @@ var BlockInvariantError = errors.New("Block objects must be instantiated with at
 
 // Account represents an Ccmchain account at a particular block.
 type Account struct {
-	backend     ccmapi.Backend
-	address     common.Address
-	blockNumber rpc.BlockNumber
+	backend       ccmapi.Backend
+	address       common.Address
+	blockNrOrHash rpc.BlockNumberOrHash
 }
 
 // getState fetches the StateDB object for an account.
 func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
-	state, _, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	state, _, err := a.backend.StateAndHeaderByNumberOrHash(ctx, a.blockNrOrHash)
 	return state, err
 }
 
@@ -104,9 +105,9 @@ func (l *Log) Transaction(ctx context.Context) *Transaction {
 
 func (l *Log) Account(ctx context.Context, args BlockNumberArgs) *Account {
 	return &Account{
-		backend:     l.backend,
-		address:     l.log.Address,
-		blockNumber: args.Number(),
+		backend:       l.backend,
+		address:       l.log.Address,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}
 }
 
@@ -207,9 +208,9 @@ func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) (*Account, e
 	}
 
 	return &Account{
-		backend:     t.backend,
-		address:     *to,
-		blockNumber: args.Number(),
+		backend:       t.backend,
+		address:       *to,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}, nil
 }
 
@@ -226,9 +227,9 @@ func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account,
 	from, _ := types.Sender(signer, tx)
 
 	return &Account{
-		backend:     t.backend,
-		address:     from,
-		blockNumber: args.Number(),
+		backend:       t.backend,
+		address:       from,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}, nil
 }
 
@@ -305,9 +306,9 @@ func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs)
 	}
 
 	return &Account{
-		backend:     t.backend,
-		address:     receipt.ContractAddress,
-		blockNumber: args.Number(),
+		backend:       t.backend,
+		address:       receipt.ContractAddress,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}, nil
 }
 
@@ -328,6 +329,62 @@ func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
 	return &ret, nil
 }
 
+func (t *Transaction) Trace(ctx context.Context) (*ExecutionTrace, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	block, err := t.block.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	return traceTransaction(ctx, t.backend, block, int(t.index))
+}
+
+// Receipt represents the receipt of a mined Ccmchain transaction.
+type Receipt struct {
+	transaction *Transaction
+	receipt     *types.Receipt
+}
+
+func (r *Receipt) Transaction(ctx context.Context) *Transaction {
+	return r.transaction
+}
+
+func (r *Receipt) Status(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.Status)
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.GasUsed)
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.CumulativeGasUsed)
+}
+
+func (r *Receipt) CreatedContract(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	return r.transaction.CreatedContract(ctx, args)
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(r.receipt.Bloom.Bytes())
+}
+
+func (r *Receipt) Logs(ctx context.Context) []*Log {
+	ret := make([]*Log, 0, len(r.receipt.Logs))
+	for _, log := range r.receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     r.transaction.backend,
+			transaction: r.transaction,
+			log:         log,
+		})
+	}
+	return ret
+}
+
 type BlockType int
 
 const (
@@ -406,6 +463,15 @@ func (b *Block) resolveHeader(ctx context.Context) (*types.Header, error) {
 	return b.header, nil
 }
 
+// numberOrHash returns the selector used to look up the state of this block,
+// preferring the number when known so pending blocks can be referenced too.
+func (b *Block) numberOrHash() rpc.BlockNumberOrHash {
+	if b.num != nil {
+		return rpc.BlockNumberOrHashWithNumber(*b.num)
+	}
+	return rpc.BlockNumberOrHashWithHash(b.hash, false)
+}
+
 // resolveReceipts returns the list of receipts for this block, fetching them
 // if necessary.
 func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
@@ -638,9 +704,9 @@ func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) (*Account, erro
 	}
 
 	return &Account{
-		backend:     b.backend,
-		address:     block.Coinbase(),
-		blockNumber: args.Number(),
+		backend:       b.backend,
+		address:       block.Coinbase(),
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(args.Number()),
 	}, nil
 }
 
@@ -672,6 +738,35 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 	return &ret, nil
 }
 
+func (b *Block) Receipts(ctx context.Context) (*[]*Receipt, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil || receipts == nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(txs), len(receipts))
+	}
+	ret := make([]*Receipt, 0, len(receipts))
+	for i, receipt := range receipts {
+		ret = append(ret, &Receipt{
+			transaction: &Transaction{
+				backend: b.backend,
+				hash:    txs[i].Hash(),
+				tx:      txs[i],
+				block:   b,
+				index:   uint64(i),
+			},
+			receipt: receipt,
+		})
+	}
+	return &ret, nil
+}
+
 func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
@@ -787,18 +882,10 @@ func (b *Block) Account(ctx context.Context, args struct {
 	if err != nil {
 		return nil, err
 	}
-
-	if b.num == nil {
-		_, err := b.resolveHeader(ctx)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	return &Account{
-		backend:     b.backend,
-		address:     args.Address,
-		blockNumber: *b.num,
+		backend:       b.backend,
+		address:       args.Address,
+		blockNrOrHash: b.numberOrHash(),
 	}, nil
 }
 
@@ -833,49 +920,49 @@ func (c *CallResult) Status() hexutil.Uint64 {
 }
 
 func (b *Block) Call(ctx context.Context, args struct {
-	Data ccmapi.CallArgs
+	Data      ccmapi.CallArgs
+	Overrides *[]AccountOverride
 }) (*CallResult, error) {
 	err := b.onMainChain(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	if b.num == nil {
-		_, err := b.resolveHeader(ctx)
-		if err != nil {
-			return nil, err
-		}
+	result, err := ccmapi.DoCall(ctx, b.backend, args.Data, b.numberOrHash(), toStateOverride(args.Overrides), vm.Config{}, 5*time.Second, b.backend.RPCGasCap())
+	if err != nil {
+		return nil, err
 	}
-
-	result, gas, failed, err := ccmapi.DoCall(ctx, b.backend, args.Data, *b.num, vm.Config{}, 5*time.Second, b.backend.RPCGasCap())
 	status := hexutil.Uint64(1)
-	if failed {
+	if result.Failed() {
 		status = 0
 	}
 	return &CallResult{
-		data:    hexutil.Bytes(result),
-		gasUsed: hexutil.Uint64(gas),
+		data:    result.ReturnData,
+		gasUsed: hexutil.Uint64(result.UsedGas),
 		status:  status,
-	}, err
+	}, nil
 }
 
 func (b *Block) EstimateGas(ctx context.Context, args struct {
-	Data ccmapi.CallArgs
+	Data      ccmapi.CallArgs
+	Overrides *[]AccountOverride
 }) (hexutil.Uint64, error) {
 	err := b.onMainChain(ctx)
 	if err != nil {
 		return hexutil.Uint64(0), err
 	}
+	gas, err := ccmapi.DoEstimateGas(ctx, b.backend, args.Data, b.numberOrHash(), toStateOverride(args.Overrides), b.backend.RPCGasCap())
+	return gas, err
+}
 
-	if b.num == nil {
-		_, err := b.resolveHeader(ctx)
-		if err != nil {
-			return hexutil.Uint64(0), err
-		}
+func (b *Block) Simulate(ctx context.Context, args struct {
+	Data      ccmapi.CallArgs
+	Overrides *[]AccountOverride
+}) (*ExecutionTrace, error) {
+	err := b.onMainChain(ctx)
+	if err != nil {
+		return nil, err
 	}
-
-	gas, err := ccmapi.DoEstimateGas(ctx, b.backend, args.Data, *b.num, b.backend.RPCGasCap())
-	return gas, err
+	return traceCall(ctx, b.backend, args.Data, b.numberOrHash(), toStateOverride(args.Overrides))
 }
 
 type Pending struct {
@@ -909,31 +996,43 @@ func (p *Pending) Account(ctx context.Context, args struct {
 	Address common.Address
 }) *Account {
 	return &Account{
-		backend:     p.backend,
-		address:     args.Address,
-		blockNumber: rpc.PendingBlockNumber,
+		backend:       p.backend,
+		address:       args.Address,
+		blockNrOrHash: rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber),
 	}
 }
 
 func (p *Pending) Call(ctx context.Context, args struct {
-	Data ccmapi.CallArgs
+	Data      ccmapi.CallArgs
+	Overrides *[]AccountOverride
 }) (*CallResult, error) {
-	result, gas, failed, err := ccmapi.DoCall(ctx, p.backend, args.Data, rpc.PendingBlockNumber, vm.Config{}, 5*time.Second, p.backend.RPCGasCap())
+	result, err := ccmapi.DoCall(ctx, p.backend, args.Data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber), toStateOverride(args.Overrides), vm.Config{}, 5*time.Second, p.backend.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
 	status := hexutil.Uint64(1)
-	if failed {
+	if result.Failed() {
 		status = 0
 	}
 	return &CallResult{
-		data:    hexutil.Bytes(result),
-		gasUsed: hexutil.Uint64(gas),
+		data:    result.ReturnData,
+		gasUsed: hexutil.Uint64(result.UsedGas),
 		status:  status,
-	}, err
+	}, nil
 }
 
 func (p *Pending) EstimateGas(ctx context.Context, args struct {
-	Data ccmapi.CallArgs
+	Data      ccmapi.CallArgs
+	Overrides *[]AccountOverride
 }) (hexutil.Uint64, error) {
-	return ccmapi.DoEstimateGas(ctx, p.backend, args.Data, rpc.PendingBlockNumber, p.backend.RPCGasCap())
+	return ccmapi.DoEstimateGas(ctx, p.backend, args.Data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber), toStateOverride(args.Overrides), p.backend.RPCGasCap())
+}
+
+func (p *Pending) Simulate(ctx context.Context, args struct {
+	Data      ccmapi.CallArgs
+	Overrides *[]AccountOverride
+}) (*ExecutionTrace, error) {
+	return traceCall(ctx, p.backend, args.Data, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber), toStateOverride(args.Overrides))
 }
 
 // Resolver is the top-level object in the GraphQL hierarchy.
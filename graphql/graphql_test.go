@@ -17,12 +17,429 @@
 package graphql
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/internal/ccmapi"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
 )
 
 func TestBuildSchema(t *testing.T) {
 	// Make sure the schema can be parsed and matched up to the object model.
-	if _, err := newHandler(nil); err != nil {
+	if _, err := newHandler(nil, nil, Limits{}); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
+
+func TestMountedHandler(t *testing.T) {
+	handler, err := NewHandler(nil, nil, []string{"localhost"}, Limits{})
+	if err != nil {
+		t.Fatalf("Could not construct GraphQL handler: %v", err)
+	}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	// Queries are answered on any path the handler is mounted under
+	resp, err := http.Post(srv.URL+"/graphql", "application/json", strings.NewReader(`{"query": "{ __typename }"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if want := `{"data":{"__typename":"Query"}}`; string(body) != want {
+		t.Errorf("response mismatch: have %s, want %s", body, want)
+	}
+	// Requests for unknown virtual hosts are rejected
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/graphql", strings.NewReader(`{"query": "{ __typename }"}`))
+	req.Host = "example.com"
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status mismatch for unknown vhost: have %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestLexer(t *testing.T) {
+	doc := `query Q($n: Long = -1.5e3) { # comment
+		block(number: $n) { ...F, hash(s: "a\"b", t: """x"y""") }
+	}`
+	want := []string{
+		"query", "Q", "(", "$", "n", ":", "Long", "=", "-1.5e3", ")", "{",
+		"block", "(", "number", ":", "$", "n", ")", "{", "...", "F",
+		"hash", "(", "s", ":", `"a\"b"`, "t", ":", `"""x"y"""`, ")", "}", "}",
+	}
+	var have []string
+	for l := newLexer(doc); l.tok != ""; l.next() {
+		if doc[l.offset:l.offset+len(l.tok)] != l.tok {
+			t.Errorf("token %q: offset %d mismatch", l.tok, l.offset)
+		}
+		have = append(have, l.tok)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("tokens mismatch:\nhave %q\nwant %q", have, want)
+	}
+}
+
+func TestParseOperations(t *testing.T) {
+	tests := []struct {
+		doc string
+		ops []operation
+	}{
+		{`{ block { number } }`, []operation{{kind: "query", offset: -1}}},
+		{`subscription { newHeads { number } }`, []operation{{kind: "subscription", offset: 0}}},
+		{
+			`# subscription {
+			query Q($n: Long = 1) { block(number: $n) { ...F } }
+			fragment F on Block { hash }
+			subscription S { logs(filter: {topics: [["0x00"]]}) { data } }`,
+			[]operation{{kind: "query", name: "Q", offset: 20}, {kind: "subscription", name: "S", offset: 108}},
+		},
+		{`mutation{sendRawTransaction(data: "subscription {")}`, []operation{{kind: "mutation", offset: 0}}},
+	}
+	for i, tt := range tests {
+		if ops := parseOperations(tt.doc); !reflect.DeepEqual(ops, tt.ops) {
+			t.Errorf("test %d: operations mismatch: have %+v, want %+v", i, ops, tt.ops)
+		}
+	}
+}
+
+func TestSubscriptionSetup(t *testing.T) {
+	full, err := graphql.ParseSchema(schema, &Resolver{})
+	if err != nil {
+		t.Fatalf("failed to parse schema: %v", err)
+	}
+	subs, err := newSubscriptions(nil, full, Limits{})
+	if err != nil {
+		t.Fatalf("failed to create subscriptions: %v", err)
+	}
+	addr := common.HexToAddress("0x1100000000000000000000000000000000000000")
+	query, setup, errs := subs.setup(context.Background(), &request{
+		Query:     `subscription S($addr: Address!) { logs(filter: {addresses: [$addr]}) { index } }`,
+		Variables: map[string]interface{}{"addr": addr.Hex()},
+	})
+	if errs != nil {
+		t.Fatalf("failed to set up subscription: %v", errs)
+	}
+	if !strings.HasPrefix(query, "query        S(") {
+		t.Errorf("subscription not rewritten: %s", query)
+	}
+	if !reflect.DeepEqual(setup.fields, []string{"logs"}) {
+		t.Errorf("subscribed fields mismatch: have %v, want [logs]", setup.fields)
+	}
+	if setup.filter.Addresses == nil || !reflect.DeepEqual(*setup.filter.Addresses, []common.Address{addr}) {
+		t.Errorf("filter addresses mismatch: have %v, want [%x]", setup.filter.Addresses, addr)
+	}
+	// Events must be delivered through the rewritten operation
+	query, _, errs = subs.setup(context.Background(), &request{Query: `subscription { newHeads { hash } }`})
+	if errs != nil {
+		t.Fatalf("failed to set up subscription: %v", errs)
+	}
+	header := &types.Header{Number: big.NewInt(1)}
+	res := subs.events.Exec(context.WithValue(context.Background(), subscriptionKey{}, &subscriptionEvent{header: header}), query, "", nil)
+	if want := fmt.Sprintf(`{"newHeads":{"hash":"%s"}}`, header.Hash().Hex()); len(res.Errors) > 0 || string(res.Data) != want {
+		t.Errorf("event result mismatch: have %s %v, want %s", res.Data, res.Errors, want)
+	}
+	// Invalid subscriptions must be rejected
+	for _, doc := range []string{
+		`subscription { newHeads { number } pendingTransactions { hash } }`,
+		`subscription { newHeads { number } } query { gasPrice }`,
+		`subscription { newHeads { unknown } }`,
+	} {
+		if _, _, errs := subs.setup(context.Background(), &request{Query: doc}); errs == nil {
+			t.Errorf("expected error setting up %q", doc)
+		}
+	}
+}
+
+func TestWebsocketProtocol(t *testing.T) {
+	handler, err := newHandler(nil, nil, Limits{})
+	if err != nil {
+		t.Fatalf("could not construct GraphQL handler: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{wsProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	expect := func(id, typ string) json.RawMessage {
+		t.Helper()
+		for {
+			var msg wsMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("failed to read message: %v", err)
+			}
+			if msg.Type == wsConnectionKeepAlive {
+				continue
+			}
+			if msg.ID != id || msg.Type != typ {
+				t.Fatalf("message mismatch: have %s/%s, want %s/%s", msg.ID, msg.Type, id, typ)
+			}
+			return msg.Payload
+		}
+	}
+	start := func(id, query string) {
+		t.Helper()
+		payload, _ := json.Marshal(&request{Query: query})
+		if err := conn.WriteJSON(&wsMessage{ID: id, Type: wsStart, Payload: payload}); err != nil {
+			t.Fatalf("failed to start operation: %v", err)
+		}
+	}
+	if err := conn.WriteJSON(&wsMessage{Type: wsConnectionInit}); err != nil {
+		t.Fatalf("failed to initialise connection: %v", err)
+	}
+	expect("", wsConnectionAck)
+
+	// Queries are answered at once
+	start("1", `{ __typename }`)
+	if data := expect("1", wsData); string(data) != `{"data":{"__typename":"Query"}}` {
+		t.Errorf("query result mismatch: %s", data)
+	}
+	expect("1", wsComplete)
+
+	// Invalid subscriptions are reported and terminated
+	start("2", `subscription { newHeads { number } pendingTransactions { hash } }`)
+	expect("2", wsError)
+	expect("2", wsComplete)
+}
+
+func TestStateOverrideConversion(t *testing.T) {
+	if toStateOverride(nil) != nil {
+		t.Fatal("expected no overrides")
+	}
+	var (
+		addr    = common.Address{0x11}
+		nonce   = hexutil.Uint64(7)
+		balance = (*hexutil.Big)(big.NewInt(1000))
+		slot    = StorageSlot{Slot: common.Hash{0x01}, Value: common.Hash{0x02}}
+	)
+	have := toStateOverride(&[]AccountOverride{{
+		Address:   addr,
+		Nonce:     &nonce,
+		Balance:   balance,
+		StateDiff: &[]StorageSlot{slot},
+	}})
+	want := ccmapi.StateOverride{addr: ccmapi.OverrideAccount{
+		Nonce:     &nonce,
+		Balance:   &balance,
+		StateDiff: &map[common.Hash]common.Hash{slot.Slot: slot.Value},
+	}}
+	if !reflect.DeepEqual(*have, want) {
+		t.Errorf("override mismatch: have %+v, want %+v", *have, want)
+	}
+	// Balances of multiple accounts must not alias each other
+	others := []AccountOverride{
+		{Address: common.Address{0x21}, Balance: (*hexutil.Big)(big.NewInt(1))},
+		{Address: common.Address{0x22}, Balance: (*hexutil.Big)(big.NewInt(2))},
+	}
+	have = toStateOverride(&others)
+	for _, other := range others {
+		balance := (*have)[other.Address].Balance
+		if balance == nil || (*balance).ToInt().Cmp(other.Balance.ToInt()) != 0 {
+			t.Errorf("account %x: balance mismatch: have %v, want %v", other.Address, balance, other.Balance)
+		}
+	}
+}
+
+// Tests that the accounts of blocks looked up by hash are resolved against the
+// state of that block.
+func TestBlockAccountByHash(t *testing.T) {
+	backend, block := newTraceBackend(t)
+	defer backend.chain.Stop()
+
+	b := &Block{backend: backend, hash: block.Hash()}
+	account, err := b.Account(context.Background(), struct{ Address common.Address }{traceSender})
+	if err != nil {
+		t.Fatalf("failed to resolve account: %v", err)
+	}
+	nonce, err := account.TransactionCount(context.Background())
+	if err != nil {
+		t.Fatalf("failed to retrieve nonce: %v", err)
+	}
+	if nonce != 2 {
+		t.Errorf("nonce mismatch: have %d, want 2", nonce)
+	}
+}
+
+func TestQueryCost(t *testing.T) {
+	head := func() uint64 { return 14 }
+	tests := []struct {
+		query string
+		vars  map[string]interface{}
+		cost  int
+	}{
+		{`{ block { number hash } }`, nil, 3},
+		{`{ block { transactions { hash } } }`, nil, 202},
+		{`query($to: Long) { blocks(from: 10, to: $to) { number } }`, map[string]interface{}{"to": "0x13"}, 11},
+		{`{ blocks(from: 5) { number } }`, nil, 11},
+		{`{ blocks(from: 5, to: 4) { number } }`, nil, 1},
+		{`{ pending { estimateGas(data: {}) } }`, nil, 101},
+		{`{ a: block @include(if: true) { ... on Block { ommers { number } } } }`, nil, 4},
+		{
+			`{ block { ...F } }
+			fragment F on Block { transactions { ...T } }
+			fragment T on Transaction { hash trace { gasUsed } }`,
+			nil, 2000402,
+		},
+		{`{ transaction(hash: "0x00") { trace { gasUsed } } }`, nil, 10002},
+		{`{ blocks(from: 0, to: "0xffffffffffffffff") { transactions { hash } } }`, nil, maxQueryCost},
+	}
+	for i, tt := range tests {
+		cost, err := queryCost(tt.query, "", tt.vars, head)
+		if err != nil {
+			t.Errorf("test %d: failed to estimate cost: %v", i, err)
+			continue
+		}
+		if cost != tt.cost {
+			t.Errorf("test %d: cost mismatch: have %d, want %d", i, cost, tt.cost)
+		}
+	}
+	if _, err := queryCost(`query A { gasPrice } query B { gasPrice }`, "", nil, head); err == nil {
+		t.Errorf("expected error for ambiguous operation")
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	typename := "{ __typename }"
+	sum := sha256.Sum256([]byte(typename))
+	hash := hex.EncodeToString(sum[:])
+
+	handler, err := newHandler(nil, nil, Limits{
+		MaxDepth:         4,
+		MaxCost:          500,
+		PersistedQueries: map[string]string{"typename": typename, hash: typename},
+	})
+	if err != nil {
+		t.Fatalf("could not construct GraphQL handler: %v", err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	query := func(method string, body string) *graphql.Response {
+		t.Helper()
+		var (
+			resp *http.Response
+			err  error
+		)
+		if method == http.MethodGet {
+			resp, err = http.Get(server.URL + "/graphql?" + body)
+		} else {
+			resp, err = http.Post(server.URL+"/graphql", "application/json", strings.NewReader(body))
+		}
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		res := new(graphql.Response)
+		if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return res
+	}
+	// Operations within the limits and persisted queries must be executed
+	for _, req := range []struct{ method, body string }{
+		{http.MethodPost, `{"query": "{ __typename }"}`},
+		{http.MethodPost, `{"id": "typename"}`},
+		{http.MethodGet, "id=typename"},
+		{http.MethodGet, "extensions=" + url.QueryEscape(`{"persistedQuery": {"version": 1, "sha256Hash": "`+hash+`"}}`)},
+	} {
+		if res := query(req.method, req.body); len(res.Errors) > 0 || string(res.Data) != `{"__typename":"Query"}` {
+			t.Errorf("%s %s: unexpected result %s %v", req.method, req.body, res.Data, res.Errors)
+		}
+	}
+	// Operations exceeding the limits and unknown queries must be rejected
+	for _, req := range []struct{ body, err string }{
+		{`{"query": "{ block { transactions { block { transactions { hash } } } } }"}`, "exceeds max depth"},
+		{`{"query": "{ block { transactions { trace { gasUsed } } } }"}`, "exceeds max cost"},
+		{`{"id": "unknown"}`, "PersistedQueryNotFound"},
+	} {
+		res := query(http.MethodPost, req.body)
+		if len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Message, req.err) {
+			t.Errorf("%s: expected error %q, have %v", req.body, req.err, res.Errors)
+		}
+	}
+}
+
+func TestPersistedOnly(t *testing.T) {
+	handler, err := newHandler(nil, nil, Limits{
+		PersistedQueries: map[string]string{"typename": "{ __typename }"},
+		PersistedOnly:    true,
+	})
+	if err != nil {
+		t.Fatalf("could not construct GraphQL handler: %v", err)
+	}
+	for _, tt := range []struct {
+		body string
+		data string
+	}{
+		{`{"id": "typename"}`, `{"__typename":"Query"}`},
+		{`{"query": "{ __typename }"}`, `{"__typename":"Query"}`},
+		{`{"query": "{ gasPrice }"}`, ``},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.body)))
+
+		var res graphql.Response
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tt.body, err)
+		}
+		if string(res.Data) != tt.data || (tt.data == "") != (len(res.Errors) > 0) {
+			t.Errorf("%s: unexpected result %s %v", tt.body, res.Data, res.Errors)
+		}
+	}
+	// Registries with invalid queries must be refused
+	if _, err := newHandler(nil, nil, Limits{PersistedQueries: map[string]string{"bad": "{ unknown }"}}); err == nil {
+		t.Errorf("expected error for invalid persisted query")
+	}
+}
+
+func TestLoadPersistedQueries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "graphql-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sum := sha256.Sum256([]byte("{ gasPrice }"))
+	hash := hex.EncodeToString(sum[:])
+
+	path := filepath.Join(dir, "queries.json")
+	ioutil.WriteFile(path, []byte(`{"`+hash+`": "{ gasPrice }", "head": "{ block { number } }"}`), 0600)
+	queries, err := LoadPersistedQueries(path)
+	if err != nil {
+		t.Fatalf("failed to load persisted queries: %v", err)
+	}
+	if want := map[string]string{hash: "{ gasPrice }", "head": "{ block { number } }"}; !reflect.DeepEqual(queries, want) {
+		t.Errorf("persisted queries mismatch: have %v, want %v", queries, want)
+	}
+	ioutil.WriteFile(path, []byte(`{"`+hash+`": "{ syncing { currentBlock } }"}`), 0600)
+	if _, err := LoadPersistedQueries(path); err == nil {
+		t.Errorf("expected error for mismatching hash")
+	}
+}
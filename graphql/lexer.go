@@ -0,0 +1,102 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+// lexer splits a GraphQL document into tokens: names, numbers, strings and
+// punctuators. Whitespace, commas and comments are skipped. Tokens are not
+// validated, that is left to the GraphQL schema.
+type lexer struct {
+	doc    string
+	pos    int
+	tok    string // Current token, empty at the end of the document
+	offset int    // Position of the current token in the document
+}
+
+// newLexer creates a lexer positioned at the first token of the document.
+func newLexer(doc string) *lexer {
+	l := &lexer{doc: doc}
+	l.next()
+	return l
+}
+
+// next advances to the next token of the document, skipping whitespace, commas
+// and comments.
+func (l *lexer) next() {
+	doc := l.doc
+	for l.pos < len(doc) {
+		if c := doc[l.pos]; c == '#' {
+			for l.pos < len(doc) && doc[l.pos] != '\n' && doc[l.pos] != '\r' {
+				l.pos++
+			}
+		} else if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+		} else {
+			break
+		}
+	}
+	if l.pos >= len(doc) {
+		l.tok = ""
+		return
+	}
+	l.offset = l.pos
+	switch c := doc[l.pos]; {
+	case c == '.':
+		for l.pos < len(doc) && doc[l.pos] == '.' {
+			l.pos++
+		}
+	case c == '"':
+		if len(doc) >= l.pos+3 && doc[l.pos:l.pos+3] == `"""` {
+			l.pos += 3
+			for l.pos < len(doc) && (len(doc) < l.pos+3 || doc[l.pos:l.pos+3] != `"""`) {
+				if doc[l.pos] == '\\' {
+					l.pos++
+				}
+				l.pos++
+			}
+			l.pos += 3
+		} else {
+			for l.pos++; l.pos < len(doc) && doc[l.pos] != '"' && doc[l.pos] != '\n'; l.pos++ {
+				if doc[l.pos] == '\\' {
+					l.pos++
+				}
+			}
+			l.pos++
+		}
+		if l.pos > len(doc) {
+			l.pos = len(doc)
+		}
+	case c == '-' || (c >= '0' && c <= '9'):
+		for l.pos++; l.pos < len(doc) && (isNameChar(doc[l.pos]) || doc[l.pos] == '.' || doc[l.pos] == '+' || doc[l.pos] == '-'); l.pos++ {
+		}
+	case isNameChar(c):
+		for l.pos++; l.pos < len(doc) && isNameChar(doc[l.pos]); l.pos++ {
+		}
+	default:
+		l.pos++
+	}
+	l.tok = doc[l.offset:l.pos]
+}
+
+// isNameChar reports whether c may appear in a name or a number.
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// isNameToken reports whether the token is a GraphQL name.
+func isNameToken(tok string) bool {
+	return tok != "" && (tok[0] == '_' || (tok[0] >= 'a' && tok[0] <= 'z') || (tok[0] >= 'A' && tok[0] <= 'Z'))
+}
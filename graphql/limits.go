@@ -0,0 +1,142 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// Limits bounds the GraphQL operations accepted by the service, which are all
+// checked before execution starts.
+//
+// Persisted queries are registered documents that clients may request by ID
+// instead of submitting them in full, using either the id request field or the
+// persistedQuery request extension. If PersistedOnly is set, other documents are
+// rejected, restricting the service to the registered operations.
+type Limits struct {
+	MaxDepth         int               // maximum field nesting depth of an operation, 0 = unlimited
+	MaxCost          int               // maximum estimated cost of an operation, 0 = unlimited
+	Timeout          time.Duration     // maximum execution time of an operation, 0 = unlimited
+	PersistedQueries map[string]string // registered query documents keyed by ID
+	PersistedOnly    bool              // whether to only accept registered query documents
+}
+
+// persistedQueryHash matches persisted query IDs which are the hex encoded
+// SHA-256 hash of their document.
+var persistedQueryHash = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// LoadPersistedQueries reads a registry of persisted queries from a JSON file,
+// holding an object mapping query IDs to GraphQL documents. IDs that look like
+// SHA-256 hashes must match the hash of their document.
+func LoadPersistedQueries(path string) (map[string]string, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var queries map[string]string
+	if err := json.Unmarshal(blob, &queries); err != nil {
+		return nil, fmt.Errorf("invalid persisted query registry %s: %v", path, err)
+	}
+	for id, query := range queries {
+		if persistedQueryHash.MatchString(id) && !hashMatches(id, query) {
+			return nil, fmt.Errorf("persisted query %s does not match its hash", id)
+		}
+	}
+	return queries, nil
+}
+
+// hashMatches reports whether the hex encoded hash is the SHA-256 hash of the
+// query document.
+func hashMatches(hash, query string) bool {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:]) == strings.ToLower(hash)
+}
+
+// limiter enforces the configured limits on GraphQL requests.
+type limiter struct {
+	limits     Limits
+	schema     *graphql.Schema
+	head       func() uint64   // Current head number, to estimate open block ranges
+	registered map[string]bool // Persisted query documents, to accept them in full
+}
+
+// newLimiter creates a limiter for requests executed against the given schema,
+// which must have been parsed with the depth limit already. Persisted queries
+// are validated up front to catch stale registries early.
+func newLimiter(schema *graphql.Schema, limits Limits, head func() uint64) (*limiter, error) {
+	l := &limiter{
+		limits:     limits,
+		schema:     schema,
+		head:       head,
+		registered: make(map[string]bool),
+	}
+	for id, query := range limits.PersistedQueries {
+		if errs := schema.Validate(query); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid persisted query %s: %v", id, errs[0])
+		}
+		l.registered[query] = true
+	}
+	return l, nil
+}
+
+// prepare resolves the document of a request from the persisted queries if it
+// refers to one, and checks that it's within the limits.
+func (l *limiter) prepare(req *request) []*gqlerrors.QueryError {
+	if id := req.persistedID(); id != "" {
+		query, ok := l.limits.PersistedQueries[id]
+		if !ok {
+			return []*gqlerrors.QueryError{gqlerrors.Errorf("PersistedQueryNotFound")}
+		}
+		req.Query = query
+	} else if l.limits.PersistedOnly && !l.registered[req.Query] {
+		return []*gqlerrors.QueryError{gqlerrors.Errorf("PersistedQueryNotSupported")}
+	}
+	if l.limits.MaxCost == 0 {
+		return nil
+	}
+	if errs := l.schema.Validate(req.Query); len(errs) > 0 {
+		return errs
+	}
+	cost, err := queryCost(req.Query, req.OperationName, req.Variables, l.head)
+	if err != nil {
+		return []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}
+	}
+	if cost > l.limits.MaxCost {
+		return []*gqlerrors.QueryError{gqlerrors.Errorf("query cost %d exceeds max cost %d", cost, l.limits.MaxCost)}
+	}
+	return nil
+}
+
+// context derives the context to execute an operation in, bounded by the
+// configured timeout.
+func (l *limiter) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.limits.Timeout > 0 {
+		return context.WithTimeout(ctx, l.limits.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
@@ -34,6 +34,7 @@ const schema string = `
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ccmchain account at a particular block.
@@ -115,6 +116,36 @@ const schema string = `
         # Logs is a list of log entries emitted by this transaction. If the
         # transaction has not yet been mined, this field will be null.
         logs: [Log!]
+        # Trace re-executes the transaction on top of the state it was mined
+        # in, reporting the calls it made and the storage it modified. As the
+        # transactions preceding it in its block are replayed first, its cost
+        # grows with the size of the block. If the transaction has not yet been
+        # mined, this field will be null.
+        trace: ExecutionTrace
+    }
+
+    # Receipt is the outcome of executing a mined transaction.
+    type Receipt {
+        # Transaction is the transaction this receipt belongs to.
+        transaction: Transaction!
+        # Status is the return status of the transaction. This will be 1 if the
+        # transaction succeeded, or 0 if it failed (due to a revert, or due to
+        # running out of gas).
+        status: Long!
+        # GasUsed is the amount of gas that was used processing this transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # this transaction.
+        cumulativeGasUsed: Long!
+        # CreatedContract is the account that was created by a contract creation
+        # transaction. If the transaction was not a contract creation transaction,
+        # this field will be null.
+        createdContract(block: Long): Account
+        # LogsBloom is a bloom filter that can be used to check if the transaction
+        # may have emitted a log entry matching a filter.
+        logsBloom: Bytes!
+        # Logs is a list of log entries emitted by this transaction.
+        logs: [Log!]!
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -197,15 +228,24 @@ const schema string = `
         # transactions are unavailable for this block, or if the index is out of
         # bounds, this field will be null.
         transactionAt(index: Int!): Transaction
+        # Receipts is a list of the receipts of all transactions in this block,
+        # in transaction order. If receipts are unavailable for this block, this
+        # field will be null.
+        receipts: [Receipt!]
         # Logs returns a filtered set of logs from this block.
         logs(filter: BlockFilterCriteria!): [Log!]!
         # Account fetches an Ccmchain account at the current block's state.
         account(address: Address!): Account!
-        # Call executes a local call operation at the current block's state.
-        call(data: CallData!): CallResult
+        # Call executes a local call operation at the current block's state,
+        # with the given accounts overridden beforehand.
+        call(data: CallData!, overrides: [AccountOverride!]): CallResult
         # EstimateGas estimates the amount of gas that will be required for
-        # successful execution of a transaction at the current block's state.
-        estimateGas(data: CallData!): Long!
+        # successful execution of a transaction at the current block's state,
+        # with the given accounts overridden beforehand.
+        estimateGas(data: CallData!, overrides: [AccountOverride!]): Long!
+        # Simulate executes a local call operation at the current block's state,
+        # with the given accounts overridden beforehand, tracing its execution.
+        simulate(data: CallData!, overrides: [AccountOverride!]): ExecutionTrace!
     }
 
     # CallData represents the data associated with a local contract call.
@@ -225,6 +265,88 @@ const schema string = `
         data: Bytes
     }
 
+    # StorageSlot is the value of a single storage slot of an account.
+    input StorageSlot {
+        # Slot is the 32 byte identifier of the storage slot.
+        slot: Bytes32!
+        # Value is the value held in the storage slot.
+        value: Bytes32!
+    }
+
+    # AccountOverride replaces fields of an account for the duration of a local
+    # call operation. All fields but the address are optional.
+    input AccountOverride {
+        # Address is the address of the account to override.
+        address: Address!
+        # Nonce replaces the nonce of the account.
+        nonce: Long
+        # Code replaces the code of the account.
+        code: Bytes
+        # Balance replaces the balance of the account, in wei.
+        balance: BigInt
+        # State replaces the entire storage of the account. It can't be set
+        # along with stateDiff.
+        state: [StorageSlot!]
+        # StateDiff replaces individual storage slots of the account.
+        stateDiff: [StorageSlot!]
+    }
+
+    # CallFrame is a single call made during the execution of a transaction,
+    # along with all the calls made from within it.
+    type CallFrame {
+        # Type is the opcode that made the call: CALL, CALLCODE, DELEGATECALL,
+        # STATICCALL, CREATE, CREATE2 or SELFDESTRUCT.
+        type: String!
+        # From is the address making the call.
+        from: Address
+        # To is the address called, or the one created. It is null for failed
+        # inner contract creations.
+        to: Address
+        # Value is the value, in wei, transferred along with the call, or null
+        # for call types not transferring value.
+        value: BigInt
+        # Gas is the amount of gas available to the call, if known.
+        gas: Long
+        # GasUsed is the amount of gas used by the call, if known.
+        gasUsed: Long
+        # Input is the data sent to the callee.
+        input: Bytes
+        # Output is the data returned by the callee, or null if it failed.
+        output: Bytes
+        # Error is the reason the call failed, or null if it succeeded.
+        error: String
+        # Calls is the list of calls made from within this call, in order.
+        calls: [CallFrame!]!
+    }
+
+    # StorageDiff is the change of a storage slot made by a transaction.
+    type StorageDiff {
+        # Address is the address of the account owning the storage slot.
+        address: Address!
+        # Slot is the 32 byte identifier of the storage slot.
+        slot: Bytes32!
+        # From is the value of the slot before the transaction.
+        from: Bytes32!
+        # To is the value of the slot after the transaction.
+        to: Bytes32!
+    }
+
+    # ExecutionTrace is the outcome of executing a transaction or local call
+    # operation, along with the calls it made and the storage it modified.
+    type ExecutionTrace {
+        # Data is the return data of the execution.
+        data: Bytes!
+        # GasUsed is the amount of gas used by the execution, after any refunds.
+        gasUsed: Long!
+        # Status is the result of the execution - 1 for success or 0 for failure.
+        status: Long!
+        # Call is the outermost call of the execution.
+        call: CallFrame!
+        # StorageDiff is the list of storage slots modified by the execution,
+        # ordered by account address and slot.
+        storageDiff: [StorageDiff!]!
+    }
+
     # CallResult is the result of a local call operation.
     type CallResult {
         # Data is the return data of the called contract.
@@ -284,11 +406,16 @@ const schema string = `
       transactions: [Transaction!]
       # Account fetches an Ccmchain account for the pending state.
       account(address: Address!): Account!
-      # Call executes a local call operation for the pending state.
-      call(data: CallData!): CallResult
+      # Call executes a local call operation for the pending state, with the
+      # given accounts overridden beforehand.
+      call(data: CallData!, overrides: [AccountOverride!]): CallResult
       # EstimateGas estimates the amount of gas that will be required for
-      # successful execution of a transaction for the pending state.
-      estimateGas(data: CallData!): Long!
+      # successful execution of a transaction for the pending state, with the
+      # given accounts overridden beforehand.
+      estimateGas(data: CallData!, overrides: [AccountOverride!]): Long!
+      # Simulate executes a local call operation for the pending state, with
+      # the given accounts overridden beforehand, tracing its execution.
+      simulate(data: CallData!, overrides: [AccountOverride!]): ExecutionTrace!
     }
 
     type Query {
@@ -317,4 +444,17 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscription operations are served over websocket connections, using the
+    # graphql-ws protocol. Every event is delivered as the result of a separate
+    # execution of the subscribed operation.
+    type Subscription {
+        # NewHeads emits each new block added to the head of the canonical chain.
+        newHeads: Block!
+        # Logs emits each log entry matching the provided filter, as the block
+        # containing it is added to the canonical chain.
+        logs(filter: BlockFilterCriteria!): Log!
+        # PendingTransactions emits each transaction added to the pending state.
+        pendingTransactions: Transaction!
+    }
 `
@@ -17,16 +17,18 @@
 package graphql
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 
 	"github.com/ccmchain/go-ccmchain/internal/ccmapi"
 	"github.com/ccmchain/go-ccmchain/log"
+	"github.com/ccmchain/go-ccmchain/node"
 	"github.com/ccmchain/go-ccmchain/p2p"
 	"github.com/ccmchain/go-ccmchain/rpc"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
-	"github.com/graph-gophers/graphql-go/relay"
 )
 
 // Service encapsulates a GraphQL service.
@@ -35,22 +37,45 @@ type Service struct {
 	cors     []string         // Allowed CORS domains
 	vhosts   []string         // Recognised vhosts
 	timeouts rpc.HTTPTimeouts // Timeout settings for HTTP requests.
+	limits   Limits           // Limits of the operations accepted.
 	backend  ccmapi.Backend   // The backend that queries will operate onn.
 	handler  http.Handler     // The `http.Handler` used to answer queries.
 	listener net.Listener     // The listening socket.
+	mounted  bool             // Whether queries are served by the node's HTTP RPC listener.
 }
 
 // New constructs a new GraphQL service instance.
-func New(backend ccmapi.Backend, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) (*Service, error) {
+func New(backend ccmapi.Backend, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts, limits Limits) (*Service, error) {
 	return &Service{
 		endpoint: endpoint,
 		cors:     cors,
 		vhosts:   vhosts,
 		timeouts: timeouts,
+		limits:   limits,
 		backend:  backend,
 	}, nil
 }
 
+// NewMounted constructs a new GraphQL service instance answering queries under
+// the given path of the node's HTTP RPC endpoint, rather than on a listener of
+// its own.
+func NewMounted(ctx *node.ServiceContext, backend ccmapi.Backend, path string, cors, vhosts []string, limits Limits) (*Service, error) {
+	handler, err := NewHandler(backend, cors, vhosts, limits)
+	if err != nil {
+		return nil, err
+	}
+	ctx.RegisterHandler("GraphQL", path, handler)
+
+	return &Service{
+		cors:    cors,
+		vhosts:  vhosts,
+		limits:  limits,
+		backend: backend,
+		handler: handler,
+		mounted: true,
+	}, nil
+}
+
 // Protocols returns the list of protocols exported by this service.
 func (s *Service) Protocols() []p2p.Protocol { return nil }
 
@@ -60,8 +85,11 @@ func (s *Service) APIs() []rpc.API { return nil }
 // Start is called after all services have been constructed and the networking
 // layer was also initialized to spawn any goroutines required by the service.
 func (s *Service) Start(server *p2p.Server) error {
+	if s.mounted {
+		return nil
+	}
 	var err error
-	s.handler, err = newHandler(s.backend)
+	s.handler, err = newHandler(s.backend, s.cors, s.limits)
 	if err != nil {
 		return err
 	}
@@ -73,24 +101,129 @@ func (s *Service) Start(server *p2p.Server) error {
 	return nil
 }
 
-// newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
-func newHandler(backend ccmapi.Backend) (http.Handler, error) {
+// newQueryHandler returns a new `http.Handler` that will answer GraphQL queries
+// within the given limits. Websocket connections from the given origins are
+// also accepted, to serve subscriptions too.
+func newQueryHandler(backend ccmapi.Backend, origins []string, limits Limits) (http.Handler, error) {
 	q := Resolver{backend}
 
-	s, err := graphql.ParseSchema(schema, &q)
+	s, err := graphql.ParseSchema(schema, &q, graphql.MaxDepth(limits.MaxDepth))
 	if err != nil {
 		return nil, err
 	}
-	h := &relay.Handler{Schema: s}
+	head := func() uint64 {
+		if backend == nil {
+			return 0
+		}
+		return backend.CurrentBlock().NumberU64()
+	}
+	limiter, err := newLimiter(s, limits, head)
+	if err != nil {
+		return nil, err
+	}
+	subs, err := newSubscriptions(backend, s, limits)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		h  = &queryHandler{schema: s, limiter: limiter}
+		ws = newWSHandler(subs, limiter, rpc.WebsocketOriginValidator(origins))
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			ws.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}), nil
+}
 
+// NewHandler returns a new `http.Handler` that will answer GraphQL queries and
+// subscriptions on any path within the given limits, guarded by the same CORS
+// and virtual host checks as the HTTP RPC endpoint. It is meant to be mounted
+// on an existing HTTP server.
+func NewHandler(backend ccmapi.Backend, cors, vhosts []string, limits Limits) (http.Handler, error) {
+	gql, err := newQueryHandler(backend, cors, limits)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewHTTPHandler(cors, vhosts, gql), nil
+}
+
+// newHandler returns a new `http.Handler` that will answer GraphQL queries
+// within the given limits. Websocket connections from the given origins are
+// also accepted on the query endpoint, to serve subscriptions too. It
+// additionally exports an interactive query browser on the / endpoint.
+func newHandler(backend ccmapi.Backend, origins []string, limits Limits) (http.Handler, error) {
+	gql, err := newQueryHandler(backend, origins, limits)
+	if err != nil {
+		return nil, err
+	}
 	mux := http.NewServeMux()
 	mux.Handle("/", GraphiQL{})
-	mux.Handle("/graphql", h)
-	mux.Handle("/graphql/", h)
+	mux.Handle("/graphql", gql)
+	mux.Handle("/graphql/", gql)
 	return mux, nil
 }
 
+// queryHandler answers GraphQL queries and mutations submitted over HTTP, as a
+// JSON request body or, to make persisted queries cacheable, as GET parameters.
+type queryHandler struct {
+	schema  *graphql.Schema
+	limiter *limiter
+}
+
+func (h *queryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		params := r.URL.Query()
+		req.ID = params.Get("id")
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if vars := params.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if exts := params.Get("extensions"); exts != "" {
+			if err := json.Unmarshal([]byte(exts), &req.Extensions); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var response *graphql.Response
+	if errs := h.limiter.prepare(&req); errs != nil {
+		response = &graphql.Response{Errors: errs}
+	} else if op := selectOperation(parseOperations(req.Query), req.OperationName); r.Method == http.MethodGet && op != nil && op.kind == "mutation" {
+		// Mutations must not be triggered by GET requests, which might be
+		// followed blindly
+		http.Error(w, "mutations require a POST request", http.StatusMethodNotAllowed)
+		return
+	} else {
+		ctx, cancel := h.limiter.context(r.Context())
+		defer cancel()
+		response = h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	}
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
 // Stop terminates all goroutines belonging to the service, blocking until they
 // are all terminated.
 func (s *Service) Stop() error {
@@ -0,0 +1,378 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain"
+	"github.com/ccmchain/go-ccmchain/ccm/filters"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/internal/ccmapi"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+var (
+	errSubscriptionSetup    = errors.New("subscription setup")
+	errNoSubscriptionEvent  = errors.New("no subscription event")
+	errSubscriptionFields   = errors.New("subscriptions must select exactly one top level field")
+	errMixedSubscriptionDoc = errors.New("subscriptions cannot be mixed with other operations in a document")
+)
+
+// eventSchemaDefinition matches the schema definition of the GraphQL schema,
+// which is swapped for one rooting queries at the Subscription type to execute
+// subscription operations against events.
+var eventSchemaDefinition = regexp.MustCompile(`schema\s*{[^}]*}`)
+
+// request is a GraphQL operation to execute, as submitted by a client either in
+// full or by the ID of a persisted query.
+type request struct {
+	ID            string                 `json:"id"`
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    struct {
+		PersistedQuery *struct {
+			Version    int    `json:"version"`
+			Sha256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+// persistedID returns the ID of the persisted query the request refers to, if
+// any.
+func (req *request) persistedID() string {
+	if pq := req.Extensions.PersistedQuery; pq != nil && pq.Sha256Hash != "" {
+		return pq.Sha256Hash
+	}
+	return req.ID
+}
+
+// subscriptionKey is the context key holding the subscription setup or event
+// that the subscription resolver is executed for.
+type subscriptionKey struct{}
+
+// subscriptionSetup collects the top level fields selected by a subscription
+// operation, along with the arguments of the ones that take any.
+type subscriptionSetup struct {
+	lock   sync.Mutex
+	fields []string
+	filter BlockFilterCriteria
+}
+
+func (s *subscriptionSetup) add(field string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.fields = append(s.fields, field)
+}
+
+// subscriptionEvent is a single event to deliver to a subscription operation.
+type subscriptionEvent struct {
+	header *types.Header
+	log    *types.Log
+	tx     *types.Transaction
+}
+
+// SubscriptionResolver is the top-level object subscription operations are
+// executed against, once to set up the subscription and then for every event
+// delivered to it.
+type SubscriptionResolver struct {
+	backend ccmapi.Backend
+}
+
+func (r *SubscriptionResolver) NewHeads(ctx context.Context) (*Block, error) {
+	switch ev := ctx.Value(subscriptionKey{}).(type) {
+	case *subscriptionSetup:
+		ev.add("newHeads")
+		return nil, errSubscriptionSetup
+	case *subscriptionEvent:
+		if ev.header != nil {
+			return &Block{
+				backend:   r.backend,
+				hash:      ev.header.Hash(),
+				header:    ev.header,
+				canonical: unknown,
+			}, nil
+		}
+	}
+	return nil, errNoSubscriptionEvent
+}
+
+func (r *SubscriptionResolver) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (*Log, error) {
+	switch ev := ctx.Value(subscriptionKey{}).(type) {
+	case *subscriptionSetup:
+		ev.add("logs")
+		ev.filter = args.Filter
+		return nil, errSubscriptionSetup
+	case *subscriptionEvent:
+		if ev.log != nil {
+			return &Log{
+				backend:     r.backend,
+				transaction: &Transaction{backend: r.backend, hash: ev.log.TxHash},
+				log:         ev.log,
+			}, nil
+		}
+	}
+	return nil, errNoSubscriptionEvent
+}
+
+func (r *SubscriptionResolver) PendingTransactions(ctx context.Context) (*Transaction, error) {
+	switch ev := ctx.Value(subscriptionKey{}).(type) {
+	case *subscriptionSetup:
+		ev.add("pendingTransactions")
+		return nil, errSubscriptionSetup
+	case *subscriptionEvent:
+		if ev.tx != nil {
+			return &Transaction{backend: r.backend, hash: ev.tx.Hash(), tx: ev.tx}, nil
+		}
+	}
+	return nil, errNoSubscriptionEvent
+}
+
+// subscriptions executes GraphQL subscription operations, feeding them with the
+// events of the chain.
+type subscriptions struct {
+	backend ccmapi.Backend
+	schema  *graphql.Schema // Full schema, to validate the submitted documents with
+	events  *graphql.Schema // Schema rooted at the subscription type, to execute events with
+	timeout time.Duration   // Maximum execution time of the operation per event
+
+	system     *filters.EventSystem // Event system, created on first use
+	systemOnce sync.Once
+}
+
+// newSubscriptions creates the executor of subscription operations for the
+// given schema, bounding their depth and execution time by the limits.
+func newSubscriptions(backend ccmapi.Backend, full *graphql.Schema, limits Limits) (*subscriptions, error) {
+	events, err := graphql.ParseSchema(eventSchemaDefinition.ReplaceAllLiteralString(schema, "schema { query: Subscription }"), &SubscriptionResolver{backend}, graphql.MaxDepth(limits.MaxDepth))
+	if err != nil {
+		return nil, err
+	}
+	return &subscriptions{backend: backend, schema: full, events: events, timeout: limits.Timeout}, nil
+}
+
+// eventSystem returns the event system subscriptions are fed from.
+func (s *subscriptions) eventSystem() *filters.EventSystem {
+	s.systemOnce.Do(func() {
+		s.system = filters.NewEventSystem(s.backend.EventMux(), s.backend, false)
+	})
+	return s.system
+}
+
+// setup validates a subscription request and rewrites its document to execute
+// against the event schema. It returns the rewritten document, along with the
+// top level field subscribed to.
+func (s *subscriptions) setup(ctx context.Context, req *request) (string, *subscriptionSetup, []*gqlerrors.QueryError) {
+	if errs := s.schema.Validate(req.Query); len(errs) > 0 {
+		return "", nil, errs
+	}
+	query, err := subscriptionQuery(req.Query)
+	if err != nil {
+		return "", nil, []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}
+	}
+	// Execute the operation without an event to find out what it subscribes to
+	setup := new(subscriptionSetup)
+	res := s.events.Exec(context.WithValue(ctx, subscriptionKey{}, setup), query, req.OperationName, req.Variables)
+	for _, err := range res.Errors {
+		if err.ResolverError != errSubscriptionSetup {
+			return "", nil, res.Errors
+		}
+	}
+	if len(setup.fields) != 1 {
+		return "", nil, []*gqlerrors.QueryError{gqlerrors.Errorf("%s", errSubscriptionFields)}
+	}
+	return query, setup, nil
+}
+
+// subscribe sets up the subscription operation of the given request, and
+// delivers the result of its execution for every matching event on the
+// returned channel, until the context is cancelled.
+func (s *subscriptions) subscribe(ctx context.Context, req *request) (<-chan *graphql.Response, []*gqlerrors.QueryError) {
+	query, setup, errs := s.setup(ctx, req)
+	if errs != nil {
+		return nil, errs
+	}
+	var (
+		headers chan *types.Header
+		logs    chan []*types.Log
+		txs     chan []*types.Transaction
+		sub     *filters.Subscription
+	)
+	switch setup.fields[0] {
+	case "newHeads":
+		headers = make(chan *types.Header)
+		sub = s.eventSystem().SubscribeNewHeads(headers)
+
+	case "logs":
+		crit := ccmchain.FilterQuery{}
+		if setup.filter.Addresses != nil {
+			crit.Addresses = *setup.filter.Addresses
+		}
+		if setup.filter.Topics != nil {
+			crit.Topics = *setup.filter.Topics
+		}
+		logs = make(chan []*types.Log)
+
+		var err error
+		if sub, err = s.eventSystem().SubscribeLogs(crit, logs); err != nil {
+			return nil, []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}
+		}
+	case "pendingTransactions":
+		txs = make(chan []*types.Transaction)
+		sub = s.eventSystem().SubscribeFilteredPendingTxs(ccmchain.PendingTxQuery{}, txs)
+	}
+	results := make(chan *graphql.Response)
+	go func() {
+		defer close(results)
+		defer sub.Unsubscribe()
+
+		for {
+			var events []*subscriptionEvent
+			select {
+			case header := <-headers:
+				events = append(events, &subscriptionEvent{header: header})
+			case found := <-logs:
+				// The schema has no notion of removed logs, only deliver new ones
+				for _, log := range found {
+					if !log.Removed {
+						events = append(events, &subscriptionEvent{log: log})
+					}
+				}
+			case found := <-txs:
+				for _, tx := range found {
+					events = append(events, &subscriptionEvent{tx: tx})
+				}
+			case <-ctx.Done():
+				return
+			}
+			for _, ev := range events {
+				res := s.exec(context.WithValue(ctx, subscriptionKey{}, ev), query, req)
+				select {
+				case results <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return results, nil
+}
+
+// exec executes the rewritten subscription operation for a single event.
+func (s *subscriptions) exec(ctx context.Context, query string, req *request) *graphql.Response {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	return s.events.Exec(ctx, query, req.OperationName, req.Variables)
+}
+
+// operation is the header of an operation definition within a GraphQL document.
+type operation struct {
+	kind   string // Operation type: query, mutation or subscription
+	name   string // Name of the operation, empty if anonymous
+	offset int    // Position of the operation type in the document, -1 for the query shorthand
+}
+
+// parseOperations scans a GraphQL document for the operations it defines. The
+// document is not validated, that is left to the GraphQL schema.
+func parseOperations(doc string) []operation {
+	var (
+		ops    []operation
+		depth  int  // Nesting depth of the current position
+		header bool // Whether an operation or fragment header is being scanned
+		named  bool // Whether the next name at the top level names an operation
+	)
+	for l := newLexer(doc); l.tok != ""; l.next() {
+		switch tok := l.tok; {
+		case tok == "{" || tok == "(" || tok == "[":
+			if depth == 0 && tok == "{" {
+				if !header {
+					ops = append(ops, operation{kind: "query", offset: -1})
+				}
+				header = false
+			}
+			depth++
+			named = false
+
+		case tok == "}" || tok == ")" || tok == "]":
+			if depth > 0 {
+				depth--
+			}
+			named = false
+
+		case isNameToken(tok):
+			if depth > 0 {
+				continue
+			}
+			switch {
+			case named:
+				ops[len(ops)-1].name = tok
+				named = false
+			case header:
+				// Fragment names and type conditions
+			case tok == "query" || tok == "mutation" || tok == "subscription":
+				ops = append(ops, operation{kind: tok, offset: l.offset})
+				header, named = true, true
+			case tok == "fragment":
+				header = true
+			}
+		default:
+			named = false
+		}
+	}
+	return ops
+}
+
+// subscriptionQuery rewrites the subscription operations of a GraphQL document
+// into queries, to execute them against the event schema. Documents containing
+// any other operations are rejected.
+func subscriptionQuery(doc string) (string, error) {
+	query := []byte(doc)
+	for _, op := range parseOperations(doc) {
+		if op.kind != "subscription" {
+			return "", errMixedSubscriptionDoc
+		}
+		// Pad the keyword to keep the positions of any errors reported intact
+		copy(query[op.offset:], "query       ")
+	}
+	return string(query), nil
+}
+
+// selectOperation returns the operation of the given name, or the only one if
+// no name is given. Nil is returned if there's no such operation.
+func selectOperation(ops []operation, name string) *operation {
+	if name == "" {
+		if len(ops) != 1 {
+			return nil
+		}
+		return &ops[0]
+	}
+	for i := range ops {
+		if ops[i].name == name {
+			return &ops[i]
+		}
+	}
+	return nil
+}
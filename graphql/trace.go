@@ -0,0 +1,346 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/ccm/tracers/native"
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/state"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/internal/ccmapi"
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+// traceTimeout is the maximum time spent executing a traced call or transaction,
+// including the transactions preceding the latter in its block.
+const traceTimeout = 5 * time.Second
+
+// StorageSlot encapsulates the value of a storage slot passed to an account
+// override.
+type StorageSlot struct {
+	Slot  common.Hash
+	Value common.Hash
+}
+
+// AccountOverride encapsulates the fields of an account replaced for the
+// duration of a call.
+type AccountOverride struct {
+	Address   common.Address
+	Nonce     *hexutil.Uint64
+	Code      *hexutil.Bytes
+	Balance   *hexutil.Big
+	State     *[]StorageSlot
+	StateDiff *[]StorageSlot
+}
+
+// toStateOverride converts the account overrides passed to a call into their
+// internal representation.
+func toStateOverride(overrides *[]AccountOverride) *ccmapi.StateOverride {
+	if overrides == nil {
+		return nil
+	}
+	slots := func(list *[]StorageSlot) *map[common.Hash]common.Hash {
+		if list == nil {
+			return nil
+		}
+		storage := make(map[common.Hash]common.Hash, len(*list))
+		for _, slot := range *list {
+			storage[slot.Slot] = slot.Value
+		}
+		return &storage
+	}
+	diff := make(ccmapi.StateOverride, len(*overrides))
+	for _, override := range *overrides {
+		account := ccmapi.OverrideAccount{
+			Nonce:     override.Nonce,
+			Code:      override.Code,
+			State:     slots(override.State),
+			StateDiff: slots(override.StateDiff),
+		}
+		if override.Balance != nil {
+			balance := override.Balance
+			account.Balance = &balance
+		}
+		diff[override.Address] = account
+	}
+	return &diff
+}
+
+// callFrameData is a call frame of the native call tracer, decoded from the
+// JSON encoding it is reported in.
+type callFrameData struct {
+	Type    string           `json:"type"`
+	From    *common.Address  `json:"from"`
+	To      *common.Address  `json:"to"`
+	Value   *hexutil.Big     `json:"value"`
+	Gas     *hexutil.Uint64  `json:"gas"`
+	GasUsed *hexutil.Uint64  `json:"gasUsed"`
+	Input   *hexutil.Bytes   `json:"input"`
+	Output  *hexutil.Bytes   `json:"output"`
+	Error   string           `json:"error"`
+	Calls   []*callFrameData `json:"calls"`
+}
+
+// CallFrame represents a single call made during the execution of a transaction.
+type CallFrame struct {
+	frame *callFrameData
+}
+
+func (c *CallFrame) Type() string {
+	return c.frame.Type
+}
+
+func (c *CallFrame) From() *common.Address {
+	return c.frame.From
+}
+
+func (c *CallFrame) To() *common.Address {
+	return c.frame.To
+}
+
+func (c *CallFrame) Value() *hexutil.Big {
+	return c.frame.Value
+}
+
+func (c *CallFrame) Gas() *hexutil.Uint64 {
+	return c.frame.Gas
+}
+
+func (c *CallFrame) GasUsed() *hexutil.Uint64 {
+	return c.frame.GasUsed
+}
+
+func (c *CallFrame) Input() *hexutil.Bytes {
+	return c.frame.Input
+}
+
+func (c *CallFrame) Output() *hexutil.Bytes {
+	return c.frame.Output
+}
+
+func (c *CallFrame) Error() *string {
+	if c.frame.Error == "" {
+		return nil
+	}
+	return &c.frame.Error
+}
+
+func (c *CallFrame) Calls() []*CallFrame {
+	calls := make([]*CallFrame, len(c.frame.Calls))
+	for i, call := range c.frame.Calls {
+		calls[i] = &CallFrame{call}
+	}
+	return calls
+}
+
+// StorageDiff represents the change of a single storage slot.
+type StorageDiff struct {
+	address  common.Address
+	slot     common.Hash
+	from, to common.Hash
+}
+
+func (d *StorageDiff) Address() common.Address {
+	return d.address
+}
+
+func (d *StorageDiff) Slot() common.Hash {
+	return d.slot
+}
+
+func (d *StorageDiff) From() common.Hash {
+	return d.from
+}
+
+func (d *StorageDiff) To() common.Hash {
+	return d.to
+}
+
+// ExecutionTrace represents the outcome of a traced transaction or call.
+type ExecutionTrace struct {
+	data        hexutil.Bytes
+	gasUsed     hexutil.Uint64
+	status      hexutil.Uint64
+	call        *CallFrame
+	storageDiff []*StorageDiff
+}
+
+func (t *ExecutionTrace) Data() hexutil.Bytes {
+	return t.data
+}
+
+func (t *ExecutionTrace) GasUsed() hexutil.Uint64 {
+	return t.gasUsed
+}
+
+func (t *ExecutionTrace) Status() hexutil.Uint64 {
+	return t.status
+}
+
+func (t *ExecutionTrace) Call() *CallFrame {
+	return t.call
+}
+
+func (t *ExecutionTrace) StorageDiff() []*StorageDiff {
+	return t.storageDiff
+}
+
+// traceCall executes a call on top of the state of the given block, with the
+// given accounts overridden beforehand, tracing its execution.
+func traceCall(ctx context.Context, backend ccmapi.Backend, args ccmapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *ccmapi.StateOverride) (*ExecutionTrace, error) {
+	statedb, header, err := backend.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(statedb); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, traceTimeout)
+	defer cancel()
+
+	return traceMessage(ctx, backend, statedb, header, args.ToMessage(backend, backend.RPCGasCap()), true)
+}
+
+// traceTransaction re-executes the transaction at the given index of a block
+// on top of the state it was included in, tracing its execution.
+func traceTransaction(ctx context.Context, backend ccmapi.Backend, block *types.Block, index int) (*ExecutionTrace, error) {
+	statedb, _, err := backend.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithHash(block.ParentHash(), false))
+	if err != nil {
+		return nil, err
+	}
+	if statedb == nil {
+		return nil, fmt.Errorf("state of parent block %#x not available", block.ParentHash())
+	}
+	ctx, cancel := context.WithTimeout(ctx, traceTimeout)
+	defer cancel()
+
+	var (
+		header = block.Header()
+		signer = types.MakeSigner(backend.ChainConfig(), block.Number())
+	)
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, err
+		}
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if i == index {
+			return traceMessage(ctx, backend, statedb, header, msg, false)
+		}
+		if _, err := executeMessage(ctx, backend, statedb, header, msg, nil, false); err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+	}
+	return nil, fmt.Errorf("transaction index %d out of range for block %#x", index, block.Hash())
+}
+
+// traceMessage executes a message on top of the given state, collecting the
+// calls it made and diffing the storage it touched.
+func traceMessage(ctx context.Context, backend ccmapi.Backend, statedb *state.StateDB, header *types.Header, msg core.Message, funded bool) (*ExecutionTrace, error) {
+	var (
+		calls  = native.NewCallTracer()
+		differ = native.NewStateDiffTracer(header.Coinbase)
+		pre    = statedb.Copy()
+	)
+	result, err := executeMessage(ctx, backend, statedb, header, msg, &vm.Config{Debug: true, Tracer: native.NewMuxTracer(calls, differ)}, funded)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := calls.GetResult()
+	if err != nil {
+		return nil, err
+	}
+	frame := new(callFrameData)
+	if err := json.Unmarshal(enc, frame); err != nil {
+		return nil, err
+	}
+	trace := &ExecutionTrace{
+		data:    result.ReturnData,
+		gasUsed: hexutil.Uint64(result.UsedGas),
+		status:  hexutil.Uint64(types.ReceiptStatusSuccessful),
+		call:    &CallFrame{frame},
+	}
+	if result.Failed() {
+		trace.status = hexutil.Uint64(types.ReceiptStatusFailed)
+	}
+	for addr, account := range differ.Diff(pre, statedb) {
+		for slot, change := range account.Storage {
+			diff := &StorageDiff{address: addr, slot: slot}
+			if change.From != nil {
+				diff.from = change.From.(common.Hash)
+			}
+			if change.To != nil {
+				diff.to = change.To.(common.Hash)
+			}
+			trace.storageDiff = append(trace.storageDiff, diff)
+		}
+	}
+	sort.Slice(trace.storageDiff, func(i, j int) bool {
+		a, b := trace.storageDiff[i], trace.storageDiff[j]
+		if c := bytes.Compare(a.address[:], b.address[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(a.slot[:], b.slot[:]) < 0
+	})
+	return trace, nil
+}
+
+// executeMessage applies a message on top of the given state. Unless funded,
+// the sender has to pay for the execution out of its own balance, otherwise
+// it is granted unlimited funds like for calls.
+func executeMessage(ctx context.Context, backend ccmapi.Backend, statedb *state.StateDB, header *types.Header, msg core.Message, vmConfig *vm.Config, funded bool) (*core.ExecutionResult, error) {
+	balance := statedb.GetBalance(msg.From())
+	evm, vmError, err := backend.GetEVM(ctx, msg, statedb, header, vmConfig)
+	if err != nil {
+		return nil, err
+	}
+	if !funded {
+		statedb.SetBalance(msg.From(), balance)
+	}
+	// Abort the execution if the context is done before it finishes
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err := vmError(); err != nil {
+		return nil, err
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted: %v", ctx.Err())
+	}
+	if err != nil {
+		return nil, err
+	}
+	statedb.Finalise(evm.ChainConfig().IsEIP158(header.Number))
+	return result, nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/consensus/ccmash"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/core/state"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/crypto"
+	"github.com/ccmchain/go-ccmchain/internal/ccmapi"
+	"github.com/ccmchain/go-ccmchain/params"
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+var (
+	traceKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	traceSender = crypto.PubkeyToAddress(traceKey.PublicKey)
+
+	// counterCode increments the counter in slot 0 and returns its new value
+	counterCode = common.FromHex("0x6000546001018060005560005260206000f3")
+	counterAddr = common.HexToAddress("0x0100")
+
+	// forwarderCode calls the counter with all the gas available
+	forwarderCode = append(append(common.FromHex("0x6000600060006000600073"), counterAddr.Bytes()...), common.FromHex("0x5af100")...)
+	forwarderAddr = common.HexToAddress("0x0200")
+)
+
+// traceBackend is a Backend implementing just enough of the interface to trace
+// transactions and calls on top of a local chain.
+type traceBackend struct {
+	ccmapi.Backend
+
+	chain *core.BlockChain
+}
+
+// newTraceBackend creates a chain with a single block, calling the counter
+// directly and then through the forwarder.
+func newTraceBackend(t *testing.T) (*traceBackend, *types.Block) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				traceSender:   {Balance: big.NewInt(params.Ccmchain)},
+				counterAddr:   {Code: counterCode, Balance: common.Big0},
+				forwarderAddr: {Code: forwarderCode, Balance: common.Big0},
+			},
+		}
+		signer = types.HomesteadSigner{}
+	)
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ccmash.NewFaker(), db, 1, func(i int, gen *core.BlockGen) {
+		for _, to := range []common.Address{counterAddr, forwarderAddr} {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(traceSender), to, nil, 100000, big.NewInt(1), nil), signer, traceKey)
+			gen.AddTx(tx)
+		}
+	})
+	db = rawdb.NewMemoryDatabase()
+	genesis.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, ccmash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	return &traceBackend{chain: chain}, blocks[0]
+}
+
+func (b *traceBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
+
+func (b *traceBackend) RPCGasCap() *big.Int { return nil }
+
+func (b *traceBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *traceBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *traceBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header := b.chain.CurrentHeader()
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header = b.chain.GetHeaderByHash(hash)
+	}
+	state, err := b.chain.StateAt(header.Root)
+	return state, header, err
+}
+
+func (b *traceBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), new(big.Int).Lsh(common.Big1, 128))
+	if vmConfig == nil {
+		vmConfig = new(vm.Config)
+	}
+	context := core.NewEVMContext(msg, header, b.chain, nil)
+	return vm.NewEVM(context, state, b.chain.Config(), *vmConfig), func() error { return nil }, nil
+}
+
+// Tests that tracing a transaction replays the ones preceding it in its block
+// and reports its nested calls and storage changes.
+func TestTraceTransaction(t *testing.T) {
+	backend, block := newTraceBackend(t)
+	defer backend.chain.Stop()
+
+	trace, err := traceTransaction(context.Background(), backend, block, 1)
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if trace.Status() != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+		t.Fatalf("traced transaction failed")
+	}
+	receipts := backend.chain.GetReceiptsByHash(block.Hash())
+	if want := receipts[1].GasUsed; uint64(trace.GasUsed()) != want {
+		t.Errorf("gas used mismatch: have %d, want %d", trace.GasUsed(), want)
+	}
+	// The forwarder must have been called, calling the counter in turn
+	call := trace.Call()
+	if call.Type() != "CALL" || call.From() == nil || *call.From() != traceSender || call.To() == nil || *call.To() != forwarderAddr {
+		t.Fatalf("top call mismatch: %+v", call.frame)
+	}
+	calls := call.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("nested call count mismatch: have %d, want 1", len(calls))
+	}
+	if nested := calls[0]; nested.Type() != "CALL" || *nested.From() != forwarderAddr || *nested.To() != counterAddr {
+		t.Errorf("nested call mismatch: %+v", nested.frame)
+	}
+	// The counter must have been incremented on top of the first transaction
+	diffs := trace.StorageDiff()
+	if len(diffs) != 1 {
+		t.Fatalf("storage diff count mismatch: have %d, want 1", len(diffs))
+	}
+	if d := diffs[0]; d.Address() != counterAddr || d.Slot() != (common.Hash{}) || d.From() != common.BigToHash(common.Big1) || d.To() != common.BigToHash(common.Big2) {
+		t.Errorf("storage diff mismatch: %x slot %x: %x -> %x", d.Address(), d.Slot(), d.From(), d.To())
+	}
+	if _, err := traceTransaction(context.Background(), backend, block, 2); err == nil {
+		t.Errorf("out of range transaction traced")
+	}
+}
+
+// Tests that calls are traced on top of the requested state overrides.
+func TestTraceCall(t *testing.T) {
+	backend, _ := newTraceBackend(t)
+	defer backend.chain.Stop()
+
+	var (
+		target = common.HexToAddress("0x0300")
+		code   = hexutil.Bytes(counterCode)
+		from   = common.HexToAddress("0xdeadbeef")
+	)
+	overrides := toStateOverride(&[]AccountOverride{{
+		Address:   target,
+		Code:      &code,
+		StateDiff: &[]StorageSlot{{Slot: common.Hash{}, Value: common.BigToHash(big.NewInt(5))}},
+	}})
+	trace, err := traceCall(context.Background(), backend, ccmapi.CallArgs{From: &from, To: &target}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), overrides)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	if trace.Status() != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+		t.Fatalf("traced call failed")
+	}
+	if have := new(big.Int).SetBytes(trace.Data()); have.Uint64() != 6 {
+		t.Errorf("return value mismatch: have %v, want 6", have)
+	}
+	if call := trace.Call(); *call.From() != from || *call.To() != target || len(call.Calls()) != 0 {
+		t.Errorf("call mismatch: %+v", call.frame)
+	}
+	diffs := trace.StorageDiff()
+	if len(diffs) != 1 {
+		t.Fatalf("storage diff count mismatch: have %d, want 1", len(diffs))
+	}
+	if d := diffs[0]; d.Address() != target || d.From() != common.BigToHash(big.NewInt(5)) || d.To() != common.BigToHash(big.NewInt(6)) {
+		t.Errorf("storage diff mismatch: %x slot %x: %x -> %x", d.Address(), d.Slot(), d.From(), d.To())
+	}
+	// The chain state must not have been modified
+	state, _ := backend.chain.State()
+	if code := state.GetCode(target); len(code) != 0 {
+		t.Errorf("override leaked into the chain state")
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/log"
+	"github.com/gorilla/websocket"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+const (
+	wsProtocol          = "graphql-ws"
+	wsReadLimit         = 1024 * 1024
+	wsWriteTimeout      = 10 * time.Second
+	wsKeepAliveInterval = 15 * time.Second
+	wsMaxOperations     = 100 // Maximum number of operations running at once on a connection
+)
+
+// Message types of the graphql-ws protocol, as specified at
+// https://github.com/apollographql/subscriptions-transport-ws/blob/master/PROTOCOL.md
+const (
+	wsConnectionInit      = "connection_init"
+	wsConnectionAck       = "connection_ack"
+	wsConnectionError     = "connection_error"
+	wsConnectionKeepAlive = "ka"
+	wsConnectionTerminate = "connection_terminate"
+	wsStart               = "start"
+	wsStop                = "stop"
+	wsData                = "data"
+	wsError               = "error"
+	wsComplete            = "complete"
+)
+
+// wsMessage is a message of the graphql-ws protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsHandler serves GraphQL operations over websocket connections, speaking the
+// graphql-ws protocol. Subscriptions are only available this way.
+type wsHandler struct {
+	subs     *subscriptions
+	limiter  *limiter
+	upgrader websocket.Upgrader
+}
+
+// newWSHandler creates a handler upgrading requests from the given origins to
+// websocket connections serving GraphQL operations within the limits.
+func newWSHandler(subs *subscriptions, limiter *limiter, origins func(*http.Request) bool) *wsHandler {
+	return &wsHandler{
+		subs:    subs,
+		limiter: limiter,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			Subprotocols:    []string{wsProtocol},
+			CheckOrigin:     origins,
+		},
+	}
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL websocket upgrade failed", "err", err)
+		return
+	}
+	if conn.Subprotocol() != wsProtocol {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseProtocolError, "unsupported subprotocol"), time.Now().Add(wsWriteTimeout))
+		conn.Close()
+		return
+	}
+	c := &wsConn{
+		subs:    h.subs,
+		limiter: h.limiter,
+		conn:    conn,
+		ops:     make(map[string]context.CancelFunc),
+	}
+	c.serve()
+}
+
+// wsConn is a single websocket connection serving GraphQL operations.
+type wsConn struct {
+	subs    *subscriptions
+	limiter *limiter
+	conn    *websocket.Conn
+
+	writeLock sync.Mutex // Serialises writes to the connection
+
+	lock sync.Mutex
+	ops  map[string]context.CancelFunc // Running operations by client assigned id
+	wg   sync.WaitGroup
+}
+
+// serve processes the messages of the client until the connection is closed.
+func (c *wsConn) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(wsReadLimit)
+
+	initialised := false
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			log.Trace("GraphQL websocket connection closed", "err", err)
+			return
+		}
+		switch {
+		case msg.Type == wsConnectionInit:
+			if !initialised {
+				initialised = true
+				c.send("", wsConnectionAck, nil)
+				c.send("", wsConnectionKeepAlive, nil)
+
+				c.wg.Add(1)
+				go c.keepAlive(ctx)
+			}
+		case !initialised:
+			c.send("", wsConnectionError, map[string]string{"message": "connection not initialised"})
+			return
+
+		case msg.Type == wsStart:
+			c.start(ctx, msg.ID, msg.Payload)
+
+		case msg.Type == wsStop:
+			c.stop(msg.ID)
+
+		case msg.Type == wsConnectionTerminate:
+			return
+
+		default:
+			c.send(msg.ID, wsError, map[string]string{"message": "unknown message type " + msg.Type})
+		}
+	}
+}
+
+// start runs the operation of a start message. Queries and mutations are
+// answered at once, subscriptions keep delivering results until stopped.
+func (c *wsConn) start(ctx context.Context, id string, payload json.RawMessage) {
+	var req request
+	if err := json.Unmarshal(payload, &req); err != nil {
+		c.send(id, wsError, []*gqlerrors.QueryError{gqlerrors.Errorf("invalid payload: %v", err)})
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch {
+	case id == "":
+		c.send(id, wsError, []*gqlerrors.QueryError{gqlerrors.Errorf("missing operation id")})
+		return
+	case c.ops[id] != nil:
+		c.send(id, wsError, []*gqlerrors.QueryError{gqlerrors.Errorf("duplicate operation id %s", id)})
+		return
+	case len(c.ops) >= wsMaxOperations:
+		c.send(id, wsError, []*gqlerrors.QueryError{gqlerrors.Errorf("too many active operations")})
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.ops[id] = cancel
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer c.finish(id)
+
+		if errs := c.limiter.prepare(&req); errs != nil {
+			c.send(id, wsError, errs)
+			return
+		}
+		if op := selectOperation(parseOperations(req.Query), req.OperationName); op == nil || op.kind != "subscription" {
+			ctx, cancel := c.limiter.context(ctx)
+			defer cancel()
+
+			c.send(id, wsData, c.subs.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+			return
+		}
+		results, errs := c.subs.subscribe(ctx, &req)
+		if errs != nil {
+			c.send(id, wsError, errs)
+			return
+		}
+		for res := range results {
+			if err := c.send(id, wsData, res); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// stop cancels the operation with the given id.
+func (c *wsConn) stop(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if cancel := c.ops[id]; cancel != nil {
+		cancel()
+	}
+}
+
+// finish drops a terminated operation, notifying the client about it.
+func (c *wsConn) finish(id string) {
+	c.lock.Lock()
+	cancel := c.ops[id]
+	delete(c.ops, id)
+	c.lock.Unlock()
+
+	cancel()
+	c.send(id, wsComplete, nil)
+}
+
+// keepAlive periodically sends keep-alive messages until the context is done.
+func (c *wsConn) keepAlive(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(wsKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.send("", wsConnectionKeepAlive, nil); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// send writes a message to the client.
+func (c *wsConn) send(id string, typ string, payload interface{}) error {
+	msg := wsMessage{ID: id, Type: typ}
+	if payload != nil {
+		enc, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg.Payload = enc
+	}
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(msg)
+}
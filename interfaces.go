@@ -22,13 +22,51 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/ccmchain/go-ccmchain/accounts/abi"
 	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
 	"github.com/ccmchain/go-ccmchain/core/types"
 )
 
 // NotFound is returned by API mccmods if the requested item does not exist.
 var NotFound = errors.New("not found")
 
+// RevertError is returned by contract calls and gas estimations if the
+// execution was aborted by a REVERT opcode. Reason holds the decoded revert
+// reason, if any, and Data the raw bytes returned by the contract.
+type RevertError struct {
+	Reason string
+	Data   []byte
+}
+
+// NewRevertError creates a revert error from the data returned by a reverted
+// execution, decoding the revert reason if the contract supplied one.
+func NewRevertError(data []byte) *RevertError {
+	reason, err := abi.UnpackRevert(data)
+	if err != nil {
+		reason = ""
+	}
+	return &RevertError{Reason: reason, Data: data}
+}
+
+// Error implements the error interface.
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return "execution reverted"
+	}
+	return "execution reverted: " + e.Reason
+}
+
+// ErrorCode returns the JSON-RPC error code for reverted executions.
+func (e *RevertError) ErrorCode() int {
+	return 3
+}
+
+// ErrorData returns the hex encoded revert data.
+func (e *RevertError) ErrorData() interface{} {
+	return hexutil.Encode(e.Data)
+}
+
 // TODO: move subscription to package event
 
 // Subscription represents an event subscription where events are
@@ -150,6 +188,14 @@ type FilterQuery struct {
 	Topics [][]common.Hash
 }
 
+// PendingTxQuery contains options for filtering transactions entering the pool.
+// Empty fields match any transaction.
+type PendingTxQuery struct {
+	From      []common.Address // restricts matches to transactions sent by these accounts
+	To        []common.Address // restricts matches to transactions sent to these accounts
+	Selectors [][4]byte        // restricts matches to calls of these method selectors
+}
+
 // LogFilterer provides access to contract log events using a one-off query or continuous
 // event subscription.
 //
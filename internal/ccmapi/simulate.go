@@ -0,0 +1,318 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccmapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain"
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/state"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/log"
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks that can be simulated
+	// in a single ccm_simulateV1 request.
+	maxSimulateBlocks = 256
+
+	// simulateTimeout is the maximum amount of time a whole simulation request
+	// may run before all pending executions are aborted.
+	simulateTimeout = 5 * time.Second
+)
+
+var (
+	errSimulateNoBlocks   = errors.New("empty simulation request")
+	errSimulateTooMany    = fmt.Errorf("too many blocks to simulate (max %d)", maxSimulateBlocks)
+	errSimulateGasBudget  = errors.New("simulation exceeds the global gas cap")
+	errSimulateOutOfOrder = errors.New("simulated block numbers must be strictly increasing")
+	errSimulateTimeTravel = errors.New("simulated block timestamps must not decrease")
+)
+
+// BlockOverrides is a set of header fields to override when executing calls in
+// the context of a simulated block.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	Time       *hexutil.Uint64 `json:"time"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase   *common.Address `json:"coinbase"`
+}
+
+// Apply overrides the given header fields into the given header.
+func (diff *BlockOverrides) Apply(header *types.Header) {
+	if diff == nil {
+		return
+	}
+	if diff.Number != nil {
+		header.Number = new(big.Int).Set(diff.Number.ToInt())
+	}
+	if diff.Difficulty != nil {
+		header.Difficulty = new(big.Int).Set(diff.Difficulty.ToInt())
+	}
+	if diff.Time != nil {
+		header.Time = uint64(*diff.Time)
+	}
+	if diff.GasLimit != nil {
+		header.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		header.Coinbase = *diff.Coinbase
+	}
+}
+
+// SimulateBlock is a batch of calls to be executed in the context of a single
+// simulated block, on top of the state left behind by all the previous ones.
+type SimulateBlock struct {
+	BlockOverrides *BlockOverrides `json:"blockOverrides"`
+	StateOverrides *StateOverride  `json:"stateOverrides"`
+	Calls          []CallArgs      `json:"calls"`
+}
+
+// SimulateOpts is the wrapper for the ccm_simulateV1 request parameters.
+type SimulateOpts struct {
+	BlockStateCalls []SimulateBlock `json:"blockStateCalls"`
+}
+
+// SimulatedCallResult is the outcome of a single call within a simulated block.
+type SimulatedCallResult struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Logs       []*types.Log   `json:"logs"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Status     hexutil.Uint64 `json:"status"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// SimulatedBlockResult is the outcome of all the calls within a simulated block
+// along with the header fields the calls were executed with.
+type SimulatedBlockResult struct {
+	Number     hexutil.Uint64         `json:"number"`
+	ParentHash common.Hash            `json:"parentHash"`
+	Timestamp  hexutil.Uint64         `json:"timestamp"`
+	GasLimit   hexutil.Uint64         `json:"gasLimit"`
+	GasUsed    hexutil.Uint64         `json:"gasUsed"`
+	Miner      common.Address         `json:"miner"`
+	Calls      []*SimulatedCallResult `json:"calls"`
+}
+
+// simulator executes a sequence of simulated blocks against a shared state.
+type simulator struct {
+	b      Backend
+	state  *state.StateDB
+	budget uint64 // Gas still available for the remaining calls
+	capped bool   // Whether the budget is limited by the global gas cap
+
+	logBase uint // Number of logs emitted before the current block
+}
+
+// SimulateV1 executes a series of calls, optionally grouped into multiple
+// simulated blocks, on top of the state of the given block. Every call sees
+// the state changes made by all the calls preceding it, so dependent flows
+// (e.g. approve followed by a transfer) can be dry-run as a whole.
+//
+// The total gas consumed by all calls is limited by the node's global gas cap.
+func (s *PublicBlockChainAPI) SimulateV1(ctx context.Context, opts SimulateOpts, blockNrOrHash rpc.BlockNumberOrHash) ([]*SimulatedBlockResult, error) {
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errSimulateNoBlocks
+	}
+	if len(opts.BlockStateCalls) > maxSimulateBlocks {
+		return nil, errSimulateTooMany
+	}
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	sim := &simulator{b: s.b, state: state}
+	if gasCap := s.b.RPCGasCap(); gasCap != nil {
+		sim.budget, sim.capped = gasCap.Uint64(), true
+	}
+	// Abort all executions if the simulation runs for too long
+	ctx, cancel := context.WithTimeout(ctx, simulateTimeout)
+	defer cancel()
+
+	var (
+		parent  = header
+		results = make([]*SimulatedBlockResult, 0, len(opts.BlockStateCalls))
+	)
+	for _, block := range opts.BlockStateCalls {
+		header, err := sim.makeHeader(parent, block.BlockOverrides)
+		if err != nil {
+			return nil, err
+		}
+		result, err := sim.processBlock(ctx, header, &block)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+		parent = header
+	}
+	return results, nil
+}
+
+// makeHeader derives the header of the next simulated block from its parent,
+// applying the requested overrides on top.
+func (sim *simulator) makeHeader(parent *types.Header, overrides *BlockOverrides) (*types.Header, error) {
+	header := types.CopyHeader(parent)
+	header.ParentHash = parent.Hash()
+	header.Number = new(big.Int).Add(parent.Number, common.Big1)
+	header.Time = parent.Time + 1
+	header.GasUsed = 0
+
+	overrides.Apply(header)
+	if header.Number.Cmp(parent.Number) <= 0 {
+		return nil, errSimulateOutOfOrder
+	}
+	if header.Time < parent.Time {
+		return nil, errSimulateTimeTravel
+	}
+	return header, nil
+}
+
+// processBlock applies the state overrides of a simulated block and executes
+// all its calls in order.
+func (sim *simulator) processBlock(ctx context.Context, header *types.Header, block *SimulateBlock) (*SimulatedBlockResult, error) {
+	if err := block.StateOverrides.Apply(sim.state); err != nil {
+		return nil, err
+	}
+	sim.logBase = uint(len(sim.state.Logs()))
+
+	var (
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		results = make([]*SimulatedCallResult, 0, len(block.Calls))
+	)
+	for i, args := range block.Calls {
+		result, err := sim.processCall(ctx, header, gp, i, args)
+		if err != nil {
+			return nil, fmt.Errorf("block %d, call %d: %v", header.Number, i, err)
+		}
+		results = append(results, result)
+	}
+	return &SimulatedBlockResult{
+		Number:     hexutil.Uint64(header.Number.Uint64()),
+		ParentHash: header.ParentHash,
+		Timestamp:  hexutil.Uint64(header.Time),
+		GasLimit:   hexutil.Uint64(header.GasLimit),
+		GasUsed:    hexutil.Uint64(header.GasUsed),
+		Miner:      header.Coinbase,
+		Calls:      results,
+	}, nil
+}
+
+// processCall executes a single call of a simulated block against the shared
+// state, collecting its return data and logs.
+func (sim *simulator) processCall(ctx context.Context, header *types.Header, gp *core.GasPool, index int, args CallArgs) (*SimulatedCallResult, error) {
+	// Default the gas allowance to whatever is left in the block and the
+	// gas budget, and the gas price to zero so unfunded senders can be used
+	if args.Gas == nil {
+		gas := gp.Gas()
+		if sim.capped && sim.budget < gas {
+			gas = sim.budget
+		}
+		args.Gas = (*hexutil.Uint64)(&gas)
+	}
+	if sim.capped && uint64(*args.Gas) > sim.budget {
+		return nil, errSimulateGasBudget
+	}
+	if args.GasPrice == nil {
+		args.GasPrice = new(hexutil.Big)
+	}
+	msg := args.ToMessage(sim.b, nil)
+
+	// Preserve the sender balance, the backend grants unlimited funds to
+	// callers, but the simulated calls need to observe real balances.
+	balance := sim.state.GetBalance(msg.From())
+
+	evm, vmError, err := sim.b.GetEVM(ctx, msg, sim.state, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	sim.state.SetBalance(msg.From(), balance)
+
+	// Wait for the context to be done and cancel the evm. Even if the
+	// EVM has finished, cancelling may be done (repeatedly)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+	// Give the call a pseudo transaction hash so its logs can be told apart
+	var (
+		nonce = sim.state.GetNonce(msg.From())
+		tx    *types.Transaction
+	)
+	if to := msg.To(); to != nil {
+		tx = types.NewTransaction(nonce, *to, msg.Value(), msg.Gas(), msg.GasPrice(), msg.Data())
+	} else {
+		tx = types.NewContractCreation(nonce, msg.Value(), msg.Gas(), msg.GasPrice(), msg.Data())
+	}
+	txHash := tx.Hash()
+	sim.state.Prepare(txHash, common.Hash{}, index)
+
+	res, err := core.ApplyMessage(evm, msg, gp)
+	if err := vmError(); err != nil {
+		return nil, err
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", simulateTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sim.capped {
+		sim.budget -= res.UsedGas
+	}
+	header.GasUsed += res.UsedGas
+
+	// Finalise the state changes so the next call starts from a clean slate
+	sim.state.Finalise(evm.ChainConfig().IsEIP158(header.Number))
+
+	result := &SimulatedCallResult{
+		ReturnData: res.ReturnData,
+		Logs:       sim.state.GetLogs(txHash),
+		GasUsed:    hexutil.Uint64(res.UsedGas),
+		Status:     hexutil.Uint64(types.ReceiptStatusSuccessful),
+	}
+	if result.Logs == nil {
+		result.Logs = []*types.Log{}
+	}
+	for _, l := range result.Logs {
+		l.BlockNumber = header.Number.Uint64()
+		l.Index -= sim.logBase
+	}
+	if res.Failed() {
+		result.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+		if len(res.Revert()) > 0 {
+			result.Error = ccmchain.NewRevertError(res.Revert()).Error()
+		} else {
+			result.Error = res.Err.Error()
+		}
+	}
+	log.Trace("Simulated call executed", "block", header.Number, "index", index, "gas", res.UsedGas, "failed", res.Failed())
+	return result, nil
+}
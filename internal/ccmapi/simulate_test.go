@@ -0,0 +1,269 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ccmapi
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+	"github.com/ccmchain/go-ccmchain/consensus/ccmash"
+	"github.com/ccmchain/go-ccmchain/core"
+	"github.com/ccmchain/go-ccmchain/core/rawdb"
+	"github.com/ccmchain/go-ccmchain/core/state"
+	"github.com/ccmchain/go-ccmchain/core/types"
+	"github.com/ccmchain/go-ccmchain/core/vm"
+	"github.com/ccmchain/go-ccmchain/params"
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+var (
+	// counterCode increments the counter in slot 0 and returns its new value
+	counterCode = common.FromHex("0x6000546001018060005560005260206000f3")
+
+	// contextCode returns the number and timestamp of the executing block
+	contextCode = common.FromHex("0x436000524260205260406000f3")
+
+	// revertReason is the ABI encoded Error("nope") reverted by revertCode
+	revertReason = common.FromHex("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6e6f706500000000000000000000000000000000000000000000000000000000")
+	revertCode = append(common.FromHex("0x6064600c60003960646000fd"), revertReason...)
+
+	counterAddr = common.HexToAddress("0x0100")
+	contextAddr = common.HexToAddress("0x0200")
+	revertAddr  = common.HexToAddress("0x0300")
+	sender      = common.HexToAddress("0x1000")
+)
+
+// simulateBackend is a Backend implementing just enough of the interface to
+// run simulations on top of a local chain with no blocks beyond the genesis.
+type simulateBackend struct {
+	Backend
+
+	chain  *core.BlockChain
+	gasCap *big.Int
+}
+
+func newSimulateBackend(t *testing.T, gasCap *big.Int) *simulateBackend {
+	db := rawdb.NewMemoryDatabase()
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			counterAddr: {Code: counterCode, Balance: common.Big0},
+			contextAddr: {Code: contextCode, Balance: common.Big0},
+			revertAddr:  {Code: revertCode, Balance: common.Big0},
+		},
+	}
+	genesis.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, ccmash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	return &simulateBackend{chain: chain, gasCap: gasCap}
+}
+
+func (b *simulateBackend) RPCGasCap() *big.Int { return b.gasCap }
+
+func (b *simulateBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header := b.chain.CurrentHeader()
+	state, err := b.chain.StateAt(header.Root)
+	return state, header, err
+}
+
+func (b *simulateBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), new(big.Int).Lsh(common.Big1, 128))
+	context := core.NewEVMContext(msg, header, b.chain, nil)
+	return vm.NewEVM(context, state, b.chain.Config(), vm.Config{}), func() error { return nil }, nil
+}
+
+// simulate runs the given simulated blocks on top of the latest block.
+func simulate(b Backend, blocks ...SimulateBlock) ([]*SimulatedBlockResult, error) {
+	api := NewPublicBlockChainAPI(b)
+	return api.SimulateV1(context.Background(), SimulateOpts{BlockStateCalls: blocks}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+}
+
+// Tests that simulated calls see the state changes of all the calls preceding
+// them, both within the same simulated block and across blocks.
+func TestSimulateDependentCalls(t *testing.T) {
+	b := newSimulateBackend(t, nil)
+	defer b.chain.Stop()
+
+	call := CallArgs{From: &sender, To: &counterAddr}
+	results, err := simulate(b,
+		SimulateBlock{Calls: []CallArgs{call, call}},
+		SimulateBlock{Calls: []CallArgs{call}},
+	)
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	var counters []uint64
+	for i, block := range results {
+		for j, res := range block.Calls {
+			if res.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+				t.Fatalf("block %d, call %d: failed: %s", i, j, res.Error)
+			}
+			counters = append(counters, new(big.Int).SetBytes(res.ReturnData).Uint64())
+		}
+	}
+	if len(counters) != 3 || counters[0] != 1 || counters[1] != 2 || counters[2] != 3 {
+		t.Fatalf("counter mismatch: have %v, want [1 2 3]", counters)
+	}
+	if results[0].GasUsed != results[0].Calls[0].GasUsed+results[0].Calls[1].GasUsed {
+		t.Errorf("block gas mismatch: have %d, want %d", results[0].GasUsed, results[0].Calls[0].GasUsed+results[0].Calls[1].GasUsed)
+	}
+	// Ensure the simulation did not leak into the chain state
+	state, _ := b.chain.State()
+	if slot := state.GetState(counterAddr, common.Hash{}); slot != (common.Hash{}) {
+		t.Errorf("simulation modified the chain state: counter %x", slot)
+	}
+}
+
+// Tests that the block overrides of the simulated blocks are applied, and that
+// the blocks without overrides derive their fields from their parents.
+func TestSimulateBlockOverrides(t *testing.T) {
+	b := newSimulateBackend(t, nil)
+	defer b.chain.Stop()
+
+	var (
+		number = (*hexutil.Big)(big.NewInt(100))
+		time   = hexutil.Uint64(5000)
+		call   = CallArgs{From: &sender, To: &contextAddr}
+	)
+	results, err := simulate(b,
+		SimulateBlock{BlockOverrides: &BlockOverrides{Number: number, Time: &time}, Calls: []CallArgs{call}},
+		SimulateBlock{Calls: []CallArgs{call}},
+	)
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	for i, want := range []struct{ number, time uint64 }{{100, 5000}, {101, 5001}} {
+		if uint64(results[i].Number) != want.number || uint64(results[i].Timestamp) != want.time {
+			t.Errorf("block %d: header mismatch: have #%d at %d, want #%d at %d", i, results[i].Number, results[i].Timestamp, want.number, want.time)
+		}
+		ret := results[i].Calls[0].ReturnData
+		if len(ret) != 64 {
+			t.Fatalf("block %d: return data length mismatch: have %d, want 64", i, len(ret))
+		}
+		if number, time := new(big.Int).SetBytes(ret[:32]).Uint64(), new(big.Int).SetBytes(ret[32:]).Uint64(); number != want.number || time != want.time {
+			t.Errorf("block %d: executed at #%d at %d, want #%d at %d", i, number, time, want.number, want.time)
+		}
+	}
+	if results[1].ParentHash == (common.Hash{}) || results[1].ParentHash == b.chain.CurrentHeader().Hash() {
+		t.Errorf("second block not chained to the first simulated one")
+	}
+	// Ensure block numbers going backwards and timestamps decreasing are rejected
+	past := hexutil.Uint64(10)
+	if _, err := simulate(b,
+		SimulateBlock{BlockOverrides: &BlockOverrides{Number: number}},
+		SimulateBlock{BlockOverrides: &BlockOverrides{Number: number}},
+	); err != errSimulateOutOfOrder {
+		t.Errorf("out of order error mismatch: have %v, want %v", err, errSimulateOutOfOrder)
+	}
+	if _, err := simulate(b,
+		SimulateBlock{BlockOverrides: &BlockOverrides{Time: &time}},
+		SimulateBlock{BlockOverrides: &BlockOverrides{Time: &past}},
+	); err != errSimulateTimeTravel {
+		t.Errorf("time travel error mismatch: have %v, want %v", err, errSimulateTimeTravel)
+	}
+}
+
+// Tests that overriding the full storage of an account in a later block replaces
+// the storage set by the earlier overrides, instead of being merged into it.
+func TestSimulateStorageOverrides(t *testing.T) {
+	b := newSimulateBackend(t, nil)
+	defer b.chain.Stop()
+
+	var (
+		first  = map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(5))}
+		second = map[common.Hash]common.Hash{common.BigToHash(common.Big1): common.BigToHash(common.Big1)}
+		call   = CallArgs{From: &sender, To: &counterAddr}
+	)
+	results, err := simulate(b,
+		SimulateBlock{StateOverrides: &StateOverride{counterAddr: {State: &first}}, Calls: []CallArgs{call}},
+		SimulateBlock{StateOverrides: &StateOverride{counterAddr: {State: &second}}, Calls: []CallArgs{call}},
+	)
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	for i, want := range []uint64{6, 1} {
+		res := results[i].Calls[0]
+		if res.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+			t.Fatalf("block %d: call failed: %s", i, res.Error)
+		}
+		if have := new(big.Int).SetBytes(res.ReturnData).Uint64(); have != want {
+			t.Errorf("block %d: counter mismatch: have %d, want %d", i, have, want)
+		}
+	}
+}
+
+// Tests that the global gas cap limits the total gas of all simulated calls,
+// not just the gas of the individual ones.
+func TestSimulateGasBudget(t *testing.T) {
+	b := newSimulateBackend(t, big.NewInt(50000))
+	defer b.chain.Stop()
+
+	// A single counter call fits into the budget, but the gas left over is not
+	// enough for a second one in the next block
+	call := CallArgs{From: &sender, To: &counterAddr}
+	results, err := simulate(b, SimulateBlock{Calls: []CallArgs{call}})
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	if res := results[0].Calls[0]; res.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+		t.Fatalf("call failed: %s", res.Error)
+	}
+	if _, err := simulate(b, SimulateBlock{Calls: []CallArgs{call}}, SimulateBlock{Calls: []CallArgs{call}}); err == nil {
+		t.Fatalf("calls beyond the gas budget accepted")
+	}
+	// Explicitly requesting more gas than left in the budget is rejected
+	gas := hexutil.Uint64(30000)
+	if _, err := simulate(b, SimulateBlock{Calls: []CallArgs{call, {From: &sender, To: &counterAddr, Gas: &gas}}}); err == nil || !strings.Contains(err.Error(), errSimulateGasBudget.Error()) {
+		t.Fatalf("gas budget error mismatch: have %v, want %v", err, errSimulateGasBudget)
+	}
+}
+
+// Tests that reverting calls are reported with their revert reason, without
+// aborting the calls following them.
+func TestSimulateRevert(t *testing.T) {
+	b := newSimulateBackend(t, nil)
+	defer b.chain.Stop()
+
+	results, err := simulate(b, SimulateBlock{Calls: []CallArgs{{From: &sender, To: &revertAddr}, {From: &sender, To: &counterAddr}}})
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	reverted := results[0].Calls[0]
+	if reverted.Status != hexutil.Uint64(types.ReceiptStatusFailed) {
+		t.Fatalf("reverting call succeeded")
+	}
+	if want := "execution reverted: nope"; reverted.Error != want {
+		t.Errorf("revert error mismatch: have %q, want %q", reverted.Error, want)
+	}
+	if !bytes.Equal(reverted.ReturnData, revertReason) {
+		t.Errorf("revert data mismatch: have %x, want %x", reverted.ReturnData, revertReason)
+	}
+	if res := results[0].Calls[1]; res.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) {
+		t.Errorf("call after revert failed: %s", res.Error)
+	}
+}
@@ -214,8 +214,11 @@ func (s *LightCcmchain) APIs() []rpc.API {
 		}, {
 			Namespace: "ccm",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true),
-			Public:    true,
+			Service: filters.NewPublicFilterAPI(s.ApiBackend, true, filters.Config{
+				LogBlockRange: s.config.RPCLogBlockRange,
+				LogResultCap:  s.config.RPCLogResultCap,
+			}),
+			Public: true,
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -185,8 +185,11 @@ func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 			modules = append(modules, strings.TrimSpace(m))
 		}
 	}
-
-	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, allowedVHosts, api.node.config.HTTPTimeouts); err != nil {
+	secret, err := api.node.config.JWTSecretKey()
+	if err != nil {
+		return false, err
+	}
+	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, allowedVHosts, api.node.config.HTTPTimeouts, secret); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -239,8 +242,11 @@ func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 			modules = append(modules, strings.TrimSpace(m))
 		}
 	}
-
-	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll); err != nil {
+	secret, err := api.node.config.JWTSecretKey()
+	if err != nil {
+		return false, err
+	}
+	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll, secret); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -18,6 +18,8 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -25,6 +27,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ccmchain/go-ccmchain/accounts"
 	"github.com/ccmchain/go-ccmchain/accounts/external"
@@ -136,6 +139,10 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// HTTPPathPrefix is the URL path under which the HTTP RPC interface is served.
+	// The default empty prefix serves it at the root.
+	HTTPPathPrefix string `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -162,6 +169,43 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSPathPrefix is the URL path under which the websocket RPC interface is
+	// served. If the websocket endpoint is the same as the HTTP one, both are
+	// served by a single listener, telling websocket upgrades apart from plain
+	// HTTP requests.
+	WSPathPrefix string `toml:",omitempty"`
+
+	// BinaryHost is the host interface on which to start the TCP server of binary
+	// framed RPC clients. If this field is empty, no such endpoint will be started.
+	// Requests on it are not authenticated.
+	BinaryHost string `toml:",omitempty"`
+
+	// BinaryPort is the TCP port number on which to start the binary RPC server.
+	// The default zero value is valid and will pick a port number randomly
+	// (useful for ephemeral nodes).
+	BinaryPort int `toml:",omitempty"`
+
+	// BinaryModules is a list of API modules to expose via the binary RPC
+	// interface. If the module list is empty, all RPC API endpoints designated
+	// public will be exposed.
+	BinaryModules []string `toml:",omitempty"`
+
+	// RPCLimits bounds batch sizes, response sizes and method execution times on
+	// the IPC, HTTP, websocket and binary RPC endpoints. The in-process endpoint
+	// is not limited.
+	RPCLimits rpc.ServerLimits
+
+	// RPCAudit configures the audit log of the calls served by the IPC, HTTP,
+	// websocket and binary RPC endpoints. If its path is empty, calls are not
+	// recorded. Parameters of personal_ methods are never recorded.
+	RPCAudit rpc.AuditConfig
+
+	// JWTSecret is the path to a file holding the hex-encoded 32 byte HS256 secret
+	// used to authenticate HTTP, websocket and binary RPC clients. If the file does not
+	// exist, a random secret is generated and stored there. If this field is empty,
+	// requests are not authenticated.
+	JWTSecret string `toml:",omitempty"`
+
 	// GraphQLHost is the host interface on which to start the GraphQL server. If this
 	// field is empty, no GraphQL API endpoint will be started.
 	GraphQLHost string `toml:",omitempty"`
@@ -185,6 +229,31 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLPathPrefix is the URL path under which the GraphQL API is served if
+	// its endpoint is the same as the HTTP RPC one, sharing the HTTP RPC listener.
+	GraphQLPathPrefix string `toml:",omitempty"`
+
+	// GraphQLMaxDepth is the maximum field nesting depth of the GraphQL operations
+	// accepted. Zero means unlimited.
+	GraphQLMaxDepth int `toml:",omitempty"`
+
+	// GraphQLMaxCost is the maximum estimated cost of the GraphQL operations
+	// accepted, that is the number of fields they resolve weighted by expense.
+	// Zero means unlimited.
+	GraphQLMaxCost int `toml:",omitempty"`
+
+	// GraphQLTimeout is the maximum execution time of a GraphQL operation. Zero
+	// means unlimited.
+	GraphQLTimeout time.Duration `toml:",omitempty"`
+
+	// GraphQLPersistedQueries is the path to a JSON file mapping query IDs to
+	// GraphQL documents, which clients may then execute by ID.
+	GraphQLPersistedQueries string `toml:",omitempty"`
+
+	// GraphQLPersistedOnly restricts the GraphQL server to executing the
+	// persisted queries.
+	GraphQLPersistedOnly bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -277,10 +346,19 @@ func DefaultWSEndpoint() string {
 	return config.WSEndpoint()
 }
 
+// BinaryEndpoint resolves the binary RPC endpoint based on the configured host
+// interface and port parameters.
+func (c *Config) BinaryEndpoint() string {
+	if c.BinaryHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.BinaryHost, c.BinaryPort)
+}
+
 // ExtRPCEnabled returns the indicator whccmer node enables the external
-// RPC(http, ws or graphql).
+// RPC(http, ws, binary or graphql).
 func (c *Config) ExtRPCEnabled() bool {
-	return c.HTTPHost != "" || c.WSHost != "" || c.GraphQLHost != ""
+	return c.HTTPHost != "" || c.WSHost != "" || c.BinaryHost != "" || c.GraphQLHost != ""
 }
 
 // NodeName returns the devp2p node identifier.
@@ -391,6 +469,40 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// JWTSecretKey retrieves the secret authenticating RPC requests, generating and
+// persisting a new one if the configured file does not exist yet. It returns nil
+// if authentication is disabled.
+func (c *Config) JWTSecretKey() ([]byte, error) {
+	if c.JWTSecret == "" {
+		return nil, nil
+	}
+	if data, err := ioutil.ReadFile(c.JWTSecret); err == nil {
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT secret in %s: %v", c.JWTSecret, err)
+		}
+		if len(secret) != 32 {
+			return nil, fmt.Errorf("invalid JWT secret in %s: length %d, want 32", c.JWTSecret, len(secret))
+		}
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// No secret found, generate and store a new one.
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.JWTSecret), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(c.JWTSecret, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", c.JWTSecret)
+	return secret, nil
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*enode.Node {
 	return c.parsePersistentNodes(&c.staticNodesWarning, c.ResolvePath(datadirStaticNodes))
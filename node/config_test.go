@@ -160,3 +160,41 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that JWT secrets are generated when missing, reloaded when present and
+// rejected when malformed.
+func TestJWTSecretKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Authentication disabled, no secret expected
+	if secret, err := (&Config{}).JWTSecretKey(); secret != nil || err != nil {
+		t.Fatalf("unexpected secret for disabled authentication: %x, %v", secret, err)
+	}
+	// Missing secret file, ensure one is generated and persisted
+	config := &Config{JWTSecret: filepath.Join(dir, "auth", "jwtsecret")}
+	secret1, err := config.JWTSecretKey()
+	if err != nil {
+		t.Fatalf("failed to generate JWT secret: %v", err)
+	}
+	if len(secret1) != 32 {
+		t.Fatalf("generated JWT secret length mismatch: have %d, want 32", len(secret1))
+	}
+	secret2, err := config.JWTSecretKey()
+	if err != nil {
+		t.Fatalf("failed to load persisted JWT secret: %v", err)
+	}
+	if !bytes.Equal(secret1, secret2) {
+		t.Fatalf("persisted JWT secret mismatch: have %x, want %x", secret2, secret1)
+	}
+	// Malformed secret file, ensure it's rejected
+	if err := ioutil.WriteFile(config.JWTSecret, []byte("0xdeadbeef"), 0600); err != nil {
+		t.Fatalf("failed to overwrite JWT secret: %v", err)
+	}
+	if _, err := config.JWTSecretKey(); err == nil {
+		t.Fatalf("short JWT secret accepted")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/ccmchain/go-ccmchain/p2p"
 	"github.com/ccmchain/go-ccmchain/p2p/nat"
@@ -34,6 +35,8 @@ const (
 	DefaultWSPort      = 8546        // Default TCP port for the websocket RPC server
 	DefaultGraphQLHost = "localhost" // Default host interface for the GraphQL server
 	DefaultGraphQLPort = 8547        // Default TCP port for the GraphQL server
+	DefaultBinaryHost  = "localhost" // Default host interface for the binary RPC server
+	DefaultBinaryPort  = 8548        // Default TCP port for the binary RPC server
 )
 
 // DefaultConfig contains reasonable default settings.
@@ -45,8 +48,16 @@ var DefaultConfig = Config{
 	HTTPTimeouts:        rpc.DefaultHTTPTimeouts,
 	WSPort:              DefaultWSPort,
 	WSModules:           []string{"net", "web3"},
+	BinaryPort:          DefaultBinaryPort,
+	BinaryModules:       []string{"net", "web3"},
+	RPCLimits:           rpc.DefaultServerLimits,
+	RPCAudit:            rpc.DefaultAuditConfig,
 	GraphQLPort:         DefaultGraphQLPort,
 	GraphQLVirtualHosts: []string{"localhost"},
+	GraphQLPathPrefix:   "/graphql",
+	GraphQLMaxDepth:     20,
+	GraphQLMaxCost:      1000000,
+	GraphQLTimeout:      30 * time.Second,
 	P2P: p2p.Config{
 		ListenAddr: ":17575",
 		MaxPeers:   50,
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -63,10 +64,19 @@ type Node struct {
 	httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
 	httpListener  net.Listener // HTTP RPC listener socket to server API requests
 	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
+	httpMux       *rpcMux      // HTTP RPC request dispatcher, also serving shared websockets
+	httpMounts    []*rpcRoute  // Handlers of services to serve on the HTTP RPC endpoint
 
 	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
+	wsShared   bool         // Whether websockets are served by the HTTP RPC listener
+
+	binaryEndpoint string       // Binary RPC endpoint (interface + port) to listen at (empty = binary RPC disabled)
+	binaryListener net.Listener // Binary RPC listener socket to serve API requests
+	binaryHandler  *rpc.Server  // Binary RPC request handler to process the API requests
+
+	rpcAudit *rpc.AuditLogger // Audit log of the calls served by the RPC endpoints, if enabled
 
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
@@ -117,6 +127,7 @@ func New(conf *Config) (*Node, error) {
 		ipcEndpoint:       conf.IPCEndpoint(),
 		httpEndpoint:      conf.HTTPEndpoint(),
 		wsEndpoint:        conf.WSEndpoint(),
+		binaryEndpoint:    conf.BinaryEndpoint(),
 		eventmux:          new(event.TypeMux),
 		log:               conf.Logger,
 	}, nil
@@ -190,12 +201,16 @@ func (n *Node) Start() error {
 	n.log.Info("Starting peer-to-peer node", "instance", n.serverConfig.Name)
 
 	// Otherwise copy and specialize the P2P configuration
-	services := make(map[reflect.Type]Service)
+	var (
+		services = make(map[reflect.Type]Service)
+		mounts   []*rpcRoute
+	)
 	for _, constructor := range n.serviceFuncs {
 		// Create a new context for the particular service
 		ctx := &ServiceContext{
 			config:         n.config,
 			services:       make(map[reflect.Type]Service),
+			handlers:       &mounts,
 			EventMux:       n.eventmux,
 			AccountManager: n.accman,
 		}
@@ -236,6 +251,7 @@ func (n *Node) Start() error {
 		started = append(started, kind)
 	}
 	// Lastly start the configured RPC interfaces
+	n.httpMounts = mounts
 	if err := n.startRPC(services); err != nil {
 		for _, service := range services {
 			service.Stop()
@@ -283,7 +299,24 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	for _, service := range services {
 		apis = append(apis, service.APIs()...)
 	}
-	// Start the various API endpoints, terminating all in case of errors
+	secret, err := n.config.JWTSecretKey()
+	if err != nil {
+		return err
+	}
+	if err := n.openAuditLog(); err != nil {
+		return err
+	}
+	if err := n.startEndpoints(apis, secret); err != nil {
+		n.closeAuditLog()
+		return err
+	}
+	// All API endpoints started successfully
+	n.rpcAPIs = apis
+	return nil
+}
+
+// startEndpoints starts the various API endpoints, terminating all in case of errors.
+func (n *Node) startEndpoints(apis []rpc.API, secret []byte) error {
 	if err := n.startInProc(apis); err != nil {
 		return err
 	}
@@ -291,22 +324,56 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
-	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, n.config.HTTPTimeouts); err != nil {
+	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts, n.config.HTTPTimeouts, secret); err != nil {
 		n.stopIPC()
 		n.stopInProc()
 		return err
 	}
-	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
+	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll, secret); err != nil {
 		n.stopHTTP()
 		n.stopIPC()
 		n.stopInProc()
 		return err
 	}
-	// All API endpoints started successfully
-	n.rpcAPIs = apis
+	if err := n.startBinary(n.binaryEndpoint, apis, n.config.BinaryModules, secret); err != nil {
+		n.stopWS()
+		n.stopHTTP()
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
+	return nil
+}
+
+// openAuditLog opens the audit log of the calls served by the RPC endpoints, if
+// one is configured.
+func (n *Node) openAuditLog() error {
+	config := n.config.RPCAudit
+	if config.Path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0700); err != nil {
+		return err
+	}
+	audit, err := rpc.NewAuditLogger(config)
+	if err != nil {
+		return err
+	}
+	n.rpcAudit = audit
+	n.log.Info("RPC audit log opened", "path", config.Path, "methods", strings.Join(config.Methods, ","), "sample", config.SampleRate)
 	return nil
 }
 
+// closeAuditLog closes the audit log of the RPC endpoints.
+func (n *Node) closeAuditLog() {
+	if n.rpcAudit != nil {
+		n.rpcAudit.Close()
+		n.rpcAudit = nil
+
+		n.log.Info("RPC audit log closed", "path", n.config.RPCAudit.Path)
+	}
+}
+
 // startInProc initializes an in-process RPC endpoint.
 func (n *Node) startInProc(apis []rpc.API) error {
 	// Register all the APIs exposed by the services
@@ -334,8 +401,15 @@ func (n *Node) startIPC(apis []rpc.API) error {
 	if n.ipcEndpoint == "" {
 		return nil // IPC disabled.
 	}
-	listener, handler, err := rpc.StartIPCEndpoint(n.ipcEndpoint, apis)
+	handler, err := rpc.NewEndpointServer(apis, nil, true, n.config.RPCLimits, nil)
+	if err != nil {
+		return err
+	}
+	handler.SetAuditLogger(n.rpcAudit)
+
+	listener, err := rpc.StartIPCListener(n.ipcEndpoint, handler)
 	if err != nil {
+		handler.Stop()
 		return err
 	}
 	n.ipcListener = listener
@@ -358,30 +432,59 @@ func (n *Node) stopIPC() {
 	}
 }
 
-// startHTTP initializes and starts the HTTP RPC endpoint.
-func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts) error {
+// startHTTP initializes and starts the HTTP RPC endpoint, serving the handlers
+// mounted by services too.
+func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts, secret []byte) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	handler, err := rpc.NewEndpointServer(apis, modules, false, n.config.RPCLimits, secret)
+	if err != nil {
+		return err
+	}
+	handler.SetAuditLogger(n.rpcAudit)
+
+	var (
+		mux   = newRPCMux(n.httpMounts)
+		route = &rpcRoute{name: "HTTP", prefix: normalisePathPrefix(n.config.HTTPPathPrefix), handler: rpc.NewHTTPHandler(cors, vhosts, handler)}
+	)
+	if mount := mux.conflict(route); mount != nil {
+		handler.Stop()
+		return fmt.Errorf("%s handler path %q shadows the HTTP RPC path %q", mount.name, mount.prefix, route.prefix)
+	}
+	mux.setHTTP(route)
+
+	listener, err := net.Listen("tcp", endpoint)
 	if err != nil {
+		handler.Stop()
 		return err
 	}
-	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	go rpc.NewHTTPTimeoutServer(timeouts, mux).Serve(listener)
+
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s%s", endpoint, route.prefix), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","), "auth", secret != nil)
+	for _, mount := range n.httpMounts {
+		n.log.Info(mount.name+" endpoint opened", "url", fmt.Sprintf("http://%s%s", endpoint, mount.prefix))
+	}
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
 	n.httpHandler = handler
+	n.httpMux = mux
 
 	return nil
 }
 
-// stopHTTP terminates the HTTP RPC endpoint.
+// stopHTTP terminates the HTTP RPC endpoint, along with the websocket endpoint
+// if it is served by the same listener.
 func (n *Node) stopHTTP() {
+	if n.wsShared {
+		n.stopWS()
+	}
 	if n.httpListener != nil {
 		n.httpListener.Close()
 		n.httpListener = nil
+		n.httpMux = nil
 
 		n.log.Info("HTTP endpoint closed", "url", fmt.Sprintf("http://%s", n.httpEndpoint))
 	}
@@ -391,20 +494,46 @@ func (n *Node) stopHTTP() {
 	}
 }
 
-// startWS initializes and starts the websocket RPC endpoint.
-func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool) error {
+// startWS initializes and starts the websocket RPC endpoint. If the endpoint is
+// the one of the running HTTP RPC endpoint, websocket connections are accepted
+// by its listener, rather than a separate one.
+func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrigins []string, exposeAll bool, secret []byte) error {
 	// Short circuit if the WS endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	handler, err := rpc.NewEndpointServer(apis, modules, exposeAll, n.config.RPCLimits, secret)
 	if err != nil {
 		return err
 	}
-	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	handler.SetAuditLogger(n.rpcAudit)
+
+	route := &rpcRoute{name: "WebSocket", prefix: normalisePathPrefix(n.config.WSPathPrefix), handler: handler.WebsocketHandler(wsOrigins)}
+
+	if n.httpMux != nil && endpoint == n.httpEndpoint {
+		if mount := n.httpMux.conflict(route); mount != nil {
+			handler.Stop()
+			return fmt.Errorf("%s handler path %q shadows the WebSocket RPC path %q", mount.name, mount.prefix, route.prefix)
+		}
+		n.httpMux.setWS(route)
+		n.wsShared = true
+
+		n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s%s", n.httpListener.Addr(), route.prefix), "shared", true, "auth", secret != nil)
+	} else {
+		listener, err := net.Listen("tcp", endpoint)
+		if err != nil {
+			handler.Stop()
+			return err
+		}
+		mux := newRPCMux(nil)
+		mux.setWS(route)
+		go (&http.Server{Handler: mux}).Serve(listener)
+
+		n.wsListener = listener
+		n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s%s", listener.Addr(), route.prefix), "auth", secret != nil)
+	}
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
-	n.wsListener = listener
 	n.wsHandler = handler
 
 	return nil
@@ -412,6 +541,12 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 
 // stopWS terminates the websocket RPC endpoint.
 func (n *Node) stopWS() {
+	if n.wsShared {
+		n.httpMux.setWS(nil)
+		n.wsShared = false
+
+		n.log.Info("WebSocket endpoint closed", "url", fmt.Sprintf("ws://%s", n.wsEndpoint))
+	}
 	if n.wsListener != nil {
 		n.wsListener.Close()
 		n.wsListener = nil
@@ -424,6 +559,46 @@ func (n *Node) stopWS() {
 	}
 }
 
+// startBinary initializes and starts the binary RPC endpoint.
+func (n *Node) startBinary(endpoint string, apis []rpc.API, modules []string, secret []byte) error {
+	// Short circuit if the binary endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	handler, err := rpc.NewEndpointServer(apis, modules, false, n.config.RPCLimits, secret)
+	if err != nil {
+		return err
+	}
+	handler.SetAuditLogger(n.rpcAudit)
+
+	listener, err := rpc.StartTCPListener(endpoint, handler)
+	if err != nil {
+		handler.Stop()
+		return err
+	}
+	n.log.Info("Binary RPC endpoint opened", "url", fmt.Sprintf("rlp+tcp://%s", listener.Addr()), "auth", secret != nil)
+	// All listeners booted successfully
+	n.binaryEndpoint = endpoint
+	n.binaryListener = listener
+	n.binaryHandler = handler
+
+	return nil
+}
+
+// stopBinary terminates the binary RPC endpoint.
+func (n *Node) stopBinary() {
+	if n.binaryListener != nil {
+		n.binaryListener.Close()
+		n.binaryListener = nil
+
+		n.log.Info("Binary RPC endpoint closed", "url", fmt.Sprintf("rlp+tcp://%s", n.binaryEndpoint))
+	}
+	if n.binaryHandler != nil {
+		n.binaryHandler.Stop()
+		n.binaryHandler = nil
+	}
+}
+
 // Stop terminates a running node along with all it's services. In the node was
 // not started, an error is returned.
 func (n *Node) Stop() error {
@@ -436,9 +611,11 @@ func (n *Node) Stop() error {
 	}
 
 	// Terminate the API, services and the p2p server.
+	n.stopBinary()
 	n.stopWS()
 	n.stopHTTP()
 	n.stopIPC()
+	n.closeAuditLog()
 	n.rpcAPIs = nil
 	failure := &StopError{
 		Services: make(map[reflect.Type]error),
@@ -591,12 +768,27 @@ func (n *Node) WSEndpoint() string {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
+	if n.wsShared && n.httpListener != nil {
+		return n.httpListener.Addr().String()
+	}
 	if n.wsListener != nil {
 		return n.wsListener.Addr().String()
 	}
 	return n.wsEndpoint
 }
 
+// BinaryEndpoint retrieves the current binary RPC endpoint used by the protocol
+// stack.
+func (n *Node) BinaryEndpoint() string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.binaryListener != nil {
+		return n.binaryListener.Addr().String()
+	}
+	return n.binaryEndpoint
+}
+
 // EventMux retrieves the event multiplexer used by all the network services in
 // the current protocol stack.
 func (n *Node) EventMux() *event.TypeMux {
@@ -0,0 +1,116 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// rpcRoute is an HTTP handler served under a path prefix of an RPC listener.
+type rpcRoute struct {
+	name    string       // Name of the API served, for logging
+	prefix  string       // Normalised path prefix, empty for the root
+	handler http.Handler // Handler to serve the matching requests with
+}
+
+// matches reports whether the route serves the given request path.
+func (r *rpcRoute) matches(path string) bool {
+	return r.prefix == "" || path == r.prefix || strings.HasPrefix(path, r.prefix+"/")
+}
+
+// rpcMux dispatches the requests received by an RPC listener between handlers
+// mounted by services, JSON-RPC over websocket connections and JSON-RPC over
+// HTTP, in that order, by path prefix. Websocket upgrade requests are told apart
+// from plain HTTP requests, so both may be served under the same prefix.
+type rpcMux struct {
+	lock   sync.RWMutex
+	mounts []*rpcRoute // Handlers mounted by services, longest prefix first
+	http   *rpcRoute   // HTTP JSON-RPC handler, nil if not served
+	ws     *rpcRoute   // Websocket JSON-RPC handler, nil if not served
+}
+
+// newRPCMux creates a dispatcher serving the given mounted handlers.
+func newRPCMux(mounts []*rpcRoute) *rpcMux {
+	mounts = append([]*rpcRoute(nil), mounts...)
+	sort.SliceStable(mounts, func(i, j int) bool { return len(mounts[i].prefix) > len(mounts[j].prefix) })
+	return &rpcMux{mounts: mounts}
+}
+
+// setHTTP sets the route of JSON-RPC over HTTP, nil to stop serving it.
+func (m *rpcMux) setHTTP(route *rpcRoute) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.http = route
+}
+
+// setWS sets the route of JSON-RPC over websocket connections, nil to stop
+// serving it.
+func (m *rpcMux) setWS(route *rpcRoute) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.ws = route
+}
+
+// conflict returns the mounted handler shadowing the given route, if any. The
+// HTTP and websocket routes can't be told apart from mounted handlers by anything
+// but their prefix.
+func (m *rpcMux) conflict(route *rpcRoute) *rpcRoute {
+	for _, mount := range m.mounts {
+		if mount.matches(route.prefix) {
+			return mount
+		}
+	}
+	return nil
+}
+
+func (m *rpcMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.lock.RLock()
+	httpRoute, wsRoute := m.http, m.ws
+	m.lock.RUnlock()
+
+	for _, mount := range m.mounts {
+		if mount.matches(r.URL.Path) {
+			mount.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+	if wsRoute != nil && websocket.IsWebSocketUpgrade(r) && wsRoute.matches(r.URL.Path) {
+		wsRoute.handler.ServeHTTP(w, r)
+		return
+	}
+	if httpRoute != nil && httpRoute.matches(r.URL.Path) {
+		httpRoute.handler.ServeHTTP(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// normalisePathPrefix turns a configured path prefix into the form matched by
+// routes, with a leading but no trailing slash. The root is represented by the
+// empty string.
+func normalisePathPrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ccmchain/go-ccmchain/p2p"
+	"github.com/ccmchain/go-ccmchain/rpc"
+)
+
+// namedAPI is an RPC API reporting the namespace it is served under.
+type namedAPI struct {
+	name string
+}
+
+func (api *namedAPI) Name() string { return api.name }
+
+// mountingService is a test service serving two RPC namespaces, mounting an
+// HTTP handler on the node's HTTP RPC endpoint too.
+type mountingService struct{}
+
+func newMountingService(prefix string) ServiceConstructor {
+	return func(ctx *ServiceContext) (Service, error) {
+		ctx.RegisterHandler("Test", prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("mounted " + r.URL.Path))
+		}))
+		return new(mountingService), nil
+	}
+}
+
+func (s *mountingService) Protocols() []p2p.Protocol { return nil }
+func (s *mountingService) Start(*p2p.Server) error   { return nil }
+func (s *mountingService) Stop() error               { return nil }
+
+func (s *mountingService) APIs() []rpc.API {
+	return []rpc.API{
+		{Namespace: "alpha", Version: "1.0", Service: &namedAPI{"alpha"}, Public: true},
+		{Namespace: "beta", Version: "1.0", Service: &namedAPI{"beta"}, Public: true},
+	}
+}
+
+// Tests that HTTP and websocket RPC requests are served by a single listener if
+// their endpoints match, each under its own path prefix and with its own module
+// list, next to the handlers mounted by services.
+func TestSharedRPCEndpoint(t *testing.T) {
+	config := testNodeConfig()
+	config.HTTPHost, config.WSHost = "127.0.0.1", "127.0.0.1"
+	config.HTTPModules, config.WSModules = []string{"alpha"}, []string{"beta"}
+	config.HTTPPathPrefix, config.WSPathPrefix = "/rpc", "/ws/"
+	config.WSOrigins = []string{"*"}
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	defer stack.Close()
+
+	if err := stack.Register(newMountingService("/mounted")); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	if stack.HTTPEndpoint() != stack.WSEndpoint() {
+		t.Fatalf("endpoint mismatch: http %s, ws %s", stack.HTTPEndpoint(), stack.WSEndpoint())
+	}
+	base := stack.HTTPEndpoint()
+
+	// Check the HTTP module list and path prefix
+	httpClient, err := rpc.DialHTTP("http://" + base + "/rpc")
+	if err != nil {
+		t.Fatalf("failed to dial HTTP: %v", err)
+	}
+	defer httpClient.Close()
+
+	var name string
+	if err := httpClient.Call(&name, "alpha_name"); err != nil || name != "alpha" {
+		t.Fatalf("HTTP call result mismatch: have %q (%v), want %q", name, err, "alpha")
+	}
+	if err := httpClient.Call(&name, "beta_name"); err == nil {
+		t.Fatalf("HTTP call of websocket only module succeeded")
+	}
+	// Check the websocket module list and path prefix
+	wsClient, err := rpc.DialWebsocket(context.Background(), "ws://"+base+"/ws", "")
+	if err != nil {
+		t.Fatalf("failed to dial websocket: %v", err)
+	}
+	defer wsClient.Close()
+
+	if err := wsClient.Call(&name, "beta_name"); err != nil || name != "beta" {
+		t.Fatalf("websocket call result mismatch: have %q (%v), want %q", name, err, "beta")
+	}
+	if err := wsClient.Call(&name, "alpha_name"); err == nil {
+		t.Fatalf("websocket call of HTTP only module succeeded")
+	}
+	if _, err := rpc.DialWebsocket(context.Background(), "ws://"+base+"/rpc", ""); err == nil {
+		t.Fatalf("websocket served outside of its path prefix")
+	}
+	// Check the mounted handler and unknown paths
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/mounted", http.StatusOK, "mounted /mounted"},
+		{"/mounted/sub", http.StatusOK, "mounted /mounted/sub"},
+		{"/mountedx", http.StatusNotFound, ""},
+		{"/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get("http://" + base + tt.path)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.path, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status mismatch: have %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+		if tt.body != "" && string(body) != tt.body {
+			t.Errorf("%s: body mismatch: have %q, want %q", tt.path, body, tt.body)
+		}
+	}
+	// Ensure the shared websocket endpoint goes down with the HTTP one
+	if err := stack.Stop(); err != nil {
+		t.Fatalf("failed to stop node: %v", err)
+	}
+	if _, err := rpc.DialWebsocket(context.Background(), "ws://"+base+"/ws", ""); err == nil {
+		t.Fatalf("websocket served after stopping the node")
+	}
+}
+
+// Tests that a mounted handler shadowing the HTTP RPC endpoint is rejected.
+func TestMountedHandlerConflict(t *testing.T) {
+	config := testNodeConfig()
+	config.HTTPHost = "127.0.0.1"
+	config.HTTPPathPrefix = "/api/rpc"
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	defer stack.Close()
+
+	if err := stack.Register(newMountingService("api")); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err == nil || !strings.Contains(err.Error(), "shadows") {
+		t.Fatalf("start error mismatch: have %v, want path conflict", err)
+	}
+}
+
+// Tests that the binary RPC endpoint serves its own module list.
+func TestBinaryRPCEndpoint(t *testing.T) {
+	config := testNodeConfig()
+	config.BinaryHost = "127.0.0.1"
+	config.BinaryModules = []string{"beta"}
+
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	defer stack.Close()
+
+	if err := stack.Register(newMountingService("/mounted")); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	client, err := rpc.Dial("rlp+tcp://" + stack.BinaryEndpoint())
+	if err != nil {
+		t.Fatalf("failed to dial binary endpoint: %v", err)
+	}
+	defer client.Close()
+
+	var name string
+	if err := client.Call(&name, "beta_name"); err != nil || name != "beta" {
+		t.Fatalf("binary call result mismatch: have %q (%v), want %q", name, err, "beta")
+	}
+	if err := client.Call(&name, "alpha_name"); err == nil {
+		t.Fatalf("binary call of unexposed module succeeded")
+	}
+}
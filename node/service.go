@@ -17,6 +17,7 @@
 package node
 
 import (
+	"net/http"
 	"path/filepath"
 	"reflect"
 
@@ -34,6 +35,7 @@ import (
 type ServiceContext struct {
 	config         *Config
 	services       map[reflect.Type]Service // Index of the already constructed services
+	handlers       *[]*rpcRoute             // HTTP handlers to serve on the HTTP RPC endpoint
 	EventMux       *event.TypeMux           // Event multiplexer used for decoupled notifications
 	AccountManager *accounts.Manager        // Account manager created by the node.
 }
@@ -91,6 +93,16 @@ func (ctx *ServiceContext) ExtRPCEnabled() bool {
 	return ctx.config.ExtRPCEnabled()
 }
 
+// RegisterHandler mounts an HTTP handler on the node's HTTP RPC endpoint, serving
+// all requests for paths under the given prefix. Handlers registered while the
+// HTTP RPC endpoint is disabled are never served.
+func (ctx *ServiceContext) RegisterHandler(name string, prefix string, handler http.Handler) {
+	if ctx.handlers == nil {
+		return
+	}
+	*ctx.handlers = append(*ctx.handlers, &rpcRoute{name: name, prefix: normalisePathPrefix(prefix), handler: handler})
+}
+
 // ServiceConstructor is the function signature of the constructors needed to be
 // registered for service instantiation.
 type ServiceConstructor func(ctx *ServiceContext) (Service, error)
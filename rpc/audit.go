@@ -0,0 +1,266 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/log"
+)
+
+// AuditConfig configures the audit log of the calls served by a Server. Method
+// lists hold method names, or name prefixes if the entry ends with '*'.
+type AuditConfig struct {
+	Path       string   // file the records are appended to, rotated when full
+	Methods    []string // methods whose calls are recorded, all if empty
+	SampleRate float64  // fraction of the calls recorded, all if zero
+	Redact     []string // methods whose parameters are not recorded, besides personal_*
+	MaxSize    int      // size in bytes at which the file is rotated, unlimited if zero
+	MaxFiles   int      // number of rotated files kept besides the current one
+}
+
+// DefaultAuditConfig contains the default settings of the audit log.
+var DefaultAuditConfig = AuditConfig{
+	MaxSize:  100 * 1024 * 1024,
+	MaxFiles: 5,
+}
+
+// auditRedactedMethods are the methods whose parameters, which carry passwords
+// and keys, are never recorded.
+var auditRedactedMethods = []string{"personal_*"}
+
+// AuditRecord is a call recorded in the audit log, one JSON object per line.
+type AuditRecord struct {
+	Time     time.Time       `json:"time"`
+	Conn     string          `json:"conn,omitempty"`     // remote address of the caller
+	Method   string          `json:"method"`             // method called
+	Params   json.RawMessage `json:"params,omitempty"`   // call parameters, unless redacted
+	Redacted bool            `json:"redacted,omitempty"` // whether the parameters were left out
+	Result   json.RawMessage `json:"result,omitempty"`   // result of a successful call
+	Error    *AuditError     `json:"error,omitempty"`    // error of a failed call
+	Elapsed  time.Duration   `json:"elapsed"`            // execution time of the call
+}
+
+// AuditError is the error of a call recorded in the audit log.
+type AuditError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// methodSet matches method names against a list of names and name prefixes.
+type methodSet struct {
+	names    map[string]bool
+	prefixes []string
+}
+
+func newMethodSet(methods []string) *methodSet {
+	set := &methodSet{names: make(map[string]bool)}
+	for _, method := range methods {
+		if strings.HasSuffix(method, "*") {
+			set.prefixes = append(set.prefixes, strings.TrimSuffix(method, "*"))
+		} else {
+			set.names[method] = true
+		}
+	}
+	return set
+}
+
+// empty reports whether the set matches no method at all.
+func (set *methodSet) empty() bool {
+	return len(set.names) == 0 && len(set.prefixes) == 0
+}
+
+func (set *methodSet) contains(method string) bool {
+	if set.names[method] {
+		return true
+	}
+	for _, prefix := range set.prefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditLogger records the calls served by a Server, and their answers, to a
+// rotating file. It is safe for concurrent use and may be shared by servers.
+type AuditLogger struct {
+	methods *methodSet
+	redact  *methodSet
+	sample  float64
+
+	lock sync.Mutex
+	file *rotatingFile
+}
+
+// NewAuditLogger opens the audit log configured by config, appending to the
+// file if it exists.
+func NewAuditLogger(config AuditConfig) (*AuditLogger, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("no audit log path configured")
+	}
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("invalid audit sample rate %v, want 0 < rate <= 1", config.SampleRate)
+	}
+	file, err := openRotatingFile(config.Path, int64(config.MaxSize), config.MaxFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLogger{
+		methods: newMethodSet(config.Methods),
+		redact:  newMethodSet(append(append([]string{}, auditRedactedMethods...), config.Redact...)),
+		sample:  config.SampleRate,
+		file:    file,
+	}, nil
+}
+
+// Close closes the audit log file. Calls served afterwards are not recorded.
+func (l *AuditLogger) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// record writes the call msg and its answer to the log, if the method and the
+// sampling rate select it.
+func (l *AuditLogger) record(conn string, msg, answer *jsonrpcMessage, elapsed time.Duration) {
+	if l == nil {
+		return
+	}
+	if !l.methods.empty() && !l.methods.contains(msg.Method) {
+		return
+	}
+	if l.sample > 0 && l.sample < 1 && rand.Float64() >= l.sample {
+		return
+	}
+	rec := &AuditRecord{
+		Time:    time.Now().UTC(),
+		Conn:    conn,
+		Method:  msg.Method,
+		Result:  answer.Result,
+		Elapsed: elapsed,
+	}
+	if l.redact.contains(msg.Method) {
+		rec.Redacted = true
+	} else {
+		rec.Params = msg.Params
+	}
+	if answer.Error != nil {
+		rec.Error = &AuditError{Code: answer.Error.Code, Message: answer.Error.Message}
+		if answer.Error.Data != nil {
+			rec.Error.Data, _ = json.Marshal(answer.Error.Data)
+		}
+	}
+	enc, err := json.Marshal(rec)
+	if err != nil {
+		log.Warn("Failed to encode RPC audit record", "method", msg.Method, "err", err)
+		return
+	}
+	enc = append(enc, '\n')
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.file == nil {
+		return
+	}
+	if _, err := l.file.Write(enc); err != nil {
+		log.Warn("Failed to write RPC audit record", "method", msg.Method, "err", err)
+	}
+}
+
+// rotatingFile is an append-only file which is moved aside once it reaches its size
+// limit, keeping a limited number of older files suffixed .1 (the newest) to .N.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// Write appends b to the file, rotating it first if b would exceed the size limit.
+func (f *rotatingFile) Write(b []byte) (int, error) {
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current file aside, dropping the oldest one, and starts a new one.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if f.maxFiles > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", f.path, f.maxFiles))
+		for i := f.maxFiles - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		}
+		if err := os.Rename(f.path, f.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(f.path); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Close closes the current file.
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
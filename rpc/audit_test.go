@@ -0,0 +1,160 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readAuditLog decodes the records of an audit log file.
+func readAuditLog(t *testing.T, path string) []*AuditRecord {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal("can't open audit log:", err)
+	}
+	defer file.Close()
+
+	var records []*AuditRecord
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		rec := new(AuditRecord)
+		if err := json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			t.Fatal("invalid audit record:", err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	audit, err := NewAuditLogger(AuditConfig{Path: path, Methods: []string{"test_*", "personal_echo"}})
+	if err != nil {
+		t.Fatal("can't open audit log:", err)
+	}
+	server := newTestServer()
+	if err := server.RegisterName("personal", new(testService)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuditLogger(audit)
+	client := DialInProc(server)
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(&result, "personal_echo", "secret", 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "test_returnError"); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := client.Call(&result, "nftest_echo", "ignored", 1, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	client.Close()
+	server.Stop()
+	audit.Close()
+
+	records := readAuditLog(t, path)
+	if len(records) != 3 {
+		t.Fatalf("record count mismatch: have %d, want 3", len(records))
+	}
+	if rec := records[0]; rec.Method != "test_echo" || string(rec.Params) != `["hello",10,{"S":"world"}]` || string(rec.Result) != `{"String":"hello","Int":10,"Args":{"S":"world"}}` {
+		t.Errorf("echo record mismatch: %+v", rec)
+	}
+	if rec := records[1]; rec.Method != "personal_echo" || !rec.Redacted || rec.Params != nil || rec.Result == nil {
+		t.Errorf("personal record not redacted: %+v", rec)
+	}
+	if rec := records[2]; rec.Method != "test_returnError" || rec.Error == nil || rec.Error.Code != 444 || string(rec.Error.Data) != `"testError data"` {
+		t.Errorf("error record mismatch: %+v, %+v", rec, rec.Error)
+	}
+}
+
+func TestAuditLogSampling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	audit, err := NewAuditLogger(AuditConfig{Path: path, SampleRate: 0.5})
+	if err != nil {
+		t.Fatal("can't open audit log:", err)
+	}
+	msg := &jsonrpcMessage{Version: vsn, ID: json.RawMessage(`1`), Method: "test_echo"}
+	for i := 0; i < 1000; i++ {
+		audit.record("", msg, msg.response(i), 0)
+	}
+	audit.Close()
+
+	if n := len(readAuditLog(t, path)); n < 350 || n > 650 {
+		t.Fatalf("sampled record count %d out of range", n)
+	}
+	if _, err := NewAuditLogger(AuditConfig{Path: path, SampleRate: 2}); err == nil {
+		t.Fatal("invalid sample rate accepted")
+	}
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	file, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cc\n", "dddddd\n", "eeeeee\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	want := map[string]string{
+		path:        "eeeeee\n",
+		path + ".1": "dddddd\n",
+		path + ".2": "bbbbbb\ncc\n",
+	}
+	for name, content := range want {
+		have, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(have) != content {
+			t.Errorf("%s: content mismatch: have %q, want %q", filepath.Base(name), have, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("too many rotated files kept")
+	}
+}
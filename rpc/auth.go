@@ -0,0 +1,173 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jwtIssuedAtWindow is the maximum allowed distance between the issuance time of
+// a token and the local clock. Tokens are meant to be minted for every request
+// (or connection), so anything older is considered a replay.
+const jwtIssuedAtWindow = 60 * time.Second
+
+var (
+	errMissingToken     = errors.New("missing authentication token")
+	errMalformedToken   = errors.New("malformed authentication token")
+	errInvalidSignature = errors.New("invalid token signature")
+	errStaleToken       = errors.New("token issuance time outside of allowed window")
+)
+
+// jwtHeader is the JOSE header of an HS256 token.
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// JWTClaims are the claims of a token authenticating RPC requests.
+type JWTClaims struct {
+	IssuedAt   int64    `json:"iat"`                  // unix time of token creation
+	Subject    string   `json:"sub,omitempty"`        // optional identity of the caller
+	Namespaces []string `json:"namespaces,omitempty"` // permitted API namespaces, all if empty
+}
+
+// allows reports whether the claims permit calling methods of the given namespace.
+func (c *JWTClaims) allows(namespace string) bool {
+	if c == nil || len(c.Namespaces) == 0 || namespace == MetadataApi {
+		return true
+	}
+	for _, ns := range c.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+type jwtClaimsKey struct{}
+
+// JWTClaimsFromContext retrieves the token claims of an authenticated RPC call,
+// returning nil if the call was not authenticated.
+func JWTClaimsFromContext(ctx context.Context) *JWTClaims {
+	claims, _ := ctx.Value(jwtClaimsKey{}).(*JWTClaims)
+	return claims
+}
+
+// NewJWTToken creates an HS256 token carrying the given claims, signed with secret.
+func NewJWTToken(secret []byte, claims JWTClaims) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: "HS256", Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(jwtSignature(secret, signed)), nil
+}
+
+// parseJWTToken verifies the signature and freshness of an HS256 token and returns
+// its claims.
+func parseJWTToken(secret []byte, token string, now time.Time) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedToken
+	}
+	// Check the header before trusting the signature algorithm.
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Algorithm != "HS256" {
+		return nil, fmt.Errorf("unsupported token algorithm %q", header.Algorithm)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	if !hmac.Equal(sig, jwtSignature(secret, parts[0]+"."+parts[1])) {
+		return nil, errInvalidSignature
+	}
+	// Signature valid, decode the claims and check the issuance time.
+	claims := new(JWTClaims)
+	if err := decodeJWTSegment(parts[1], claims); err != nil {
+		return nil, err
+	}
+	issued := time.Unix(claims.IssuedAt, 0)
+	if claims.IssuedAt == 0 || issued.Before(now.Add(-jwtIssuedAtWindow)) || issued.After(now.Add(jwtIssuedAtWindow)) {
+		return nil, errStaleToken
+	}
+	return claims, nil
+}
+
+// jwtAuthenticate verifies the bearer token of an HTTP request.
+func jwtAuthenticate(secret []byte, r *http.Request) (*JWTClaims, error) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return nil, errMissingToken
+	}
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, errMalformedToken
+	}
+	return parseJWTToken(secret, strings.TrimSpace(auth[len("Bearer "):]), time.Now())
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	blob, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errMalformedToken
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		return errMalformedToken
+	}
+	return nil
+}
+
+func jwtSignature(secret []byte, signed string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+// HTTPAuth is a function that adds authentication headers to the HTTP requests
+// (or websocket handshakes) sent by a client.
+type HTTPAuth func(h http.Header) error
+
+// NewJWTAuth creates an HTTPAuth that attaches a freshly issued HS256 token with
+// the given claims to every request. The issuance time of the claims is overridden.
+func NewJWTAuth(secret []byte, claims JWTClaims) HTTPAuth {
+	return func(h http.Header) error {
+		fresh := claims
+		fresh.IssuedAt = time.Now().Unix()
+		token, err := NewJWTToken(secret, fresh)
+		if err != nil {
+			return err
+		}
+		h.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
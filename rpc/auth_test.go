@@ -0,0 +1,189 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testJWTSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestJWTTokenVerification(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	token, err := NewJWTToken(testJWTSecret, JWTClaims{IssuedAt: now.Unix(), Subject: "alice", Namespaces: []string{"test"}})
+	if err != nil {
+		t.Fatalf("can't create token: %v", err)
+	}
+	claims, err := parseJWTToken(testJWTSecret, token, now)
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if claims.Subject != "alice" || !claims.allows("test") || claims.allows("admin") {
+		t.Fatalf("wrong claims decoded: %+v", claims)
+	}
+	if _, err := parseJWTToken([]byte("wrong secret"), token, now); err != errInvalidSignature {
+		t.Errorf("wrong error for bad signature: %v", err)
+	}
+	if _, err := parseJWTToken(testJWTSecret, token, now.Add(2*jwtIssuedAtWindow)); err != errStaleToken {
+		t.Errorf("wrong error for stale token: %v", err)
+	}
+	if _, err := parseJWTToken(testJWTSecret, token, now.Add(-2*jwtIssuedAtWindow)); err != errStaleToken {
+		t.Errorf("wrong error for future token: %v", err)
+	}
+	// Unsigned tokens must not be accepted.
+	parts := strings.Split(token, ".")
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+	if _, err := parseJWTToken(testJWTSecret, unsigned, now); err == nil {
+		t.Error("unsigned token accepted")
+	}
+	if _, err := parseJWTToken(testJWTSecret, "garbage", now); err != errMalformedToken {
+		t.Errorf("wrong error for malformed token: %v", err)
+	}
+}
+
+// This test checks that HTTP requests require a valid token and are limited to
+// the namespaces permitted by its claims.
+func TestHTTPJWTAuth(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer()
+	srv.SetJWTSecret(testJWTSecret)
+	httpsrv := httptest.NewServer(srv)
+	defer srv.Stop()
+	defer httpsrv.Close()
+
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1); err == nil {
+		t.Fatal("unauthenticated call succeeded")
+	}
+	client.Close()
+
+	auth := NewJWTAuth(testJWTSecret, JWTClaims{Namespaces: []string{"test"}})
+	client, err = DialHTTPWithAuth(httpsrv.URL, new(http.Client), auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	testJWTNamespaces(t, client)
+}
+
+// This test checks that websocket handshakes require a valid token and that calls
+// are limited to the namespaces permitted by its claims.
+func TestWebsocketJWTAuth(t *testing.T) {
+	t.Parallel()
+
+	var (
+		srv     = newTestServer()
+		httpsrv = httptest.NewServer(srv.WebsocketHandler([]string{"*"}))
+		wsURL   = "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+	)
+	srv.SetJWTSecret(testJWTSecret)
+	defer srv.Stop()
+	defer httpsrv.Close()
+
+	if client, err := DialWebsocket(context.Background(), wsURL, ""); err == nil {
+		client.Close()
+		t.Fatal("unauthenticated handshake succeeded")
+	}
+	auth := NewJWTAuth(testJWTSecret, JWTClaims{Namespaces: []string{"test"}})
+	client, err := DialWebsocketWithAuth(context.Background(), wsURL, "", auth)
+	if err != nil {
+		t.Fatalf("can't dial: %v", err)
+	}
+	defer client.Close()
+	testJWTNamespaces(t, client)
+}
+
+// This test checks that listeners of a server with a JWT secret only serve binary
+// clients presenting a valid token, limited to the namespaces permitted by its claims.
+func TestBinaryJWTAuth(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer()
+	srv.SetJWTSecret(testJWTSecret)
+	defer srv.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("can't listen:", err)
+	}
+	defer listener.Close()
+	go srv.ServeListener(listener)
+	endpoint := listener.Addr().String()
+
+	unauthenticated := map[string]func() (*Client, error){
+		"json": func() (*Client, error) {
+			return newClient(context.Background(), func(ctx context.Context) (ServerCodec, error) {
+				conn, err := net.Dial("tcp", endpoint)
+				if err != nil {
+					return nil, err
+				}
+				return NewJSONCodec(conn), nil
+			})
+		},
+		"binary": func() (*Client, error) { return DialBinaryTCP(context.Background(), endpoint) },
+		"forged": func() (*Client, error) {
+			return DialBinaryTCPWithJWT(context.Background(), endpoint, []byte("wrong secret"), JWTClaims{})
+		},
+	}
+	for name, dial := range unauthenticated {
+		client, err := dial()
+		if err != nil {
+			t.Fatalf("%s: can't dial: %v", name, err)
+		}
+		var result Result
+		if err := client.Call(&result, "test_echo", "x", 1); err == nil {
+			t.Errorf("%s: unauthenticated call succeeded", name)
+		}
+		client.Close()
+	}
+	client, err := DialBinaryTCPWithJWT(context.Background(), endpoint, testJWTSecret, JWTClaims{Namespaces: []string{"test"}})
+	if err != nil {
+		t.Fatalf("can't dial: %v", err)
+	}
+	defer client.Close()
+	testJWTNamespaces(t, client)
+}
+
+func testJWTNamespaces(t *testing.T, client *Client) {
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatalf("permitted call failed: %v", err)
+	}
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil {
+		t.Fatalf("metadata call failed: %v", err)
+	}
+	var n int
+	err := client.Call(&n, "nftest_echo", 1)
+	if code := errorCode(err); code != unauthorizedErrorCode {
+		t.Fatalf("wrong error code %d (%v), want %d", code, err, unauthorizedErrorCode)
+	}
+}
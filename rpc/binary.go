@@ -0,0 +1,419 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/rlp"
+)
+
+// The binary transport carries the messages of the JSON-RPC protocol as RLP lists in
+// length-prefixed frames, rather than as a stream of JSON objects. Method parameters,
+// results and error data stay JSON encoded, so all registered services and
+// subscriptions are served unchanged, but the envelope holding them is never scanned,
+// validated or escaped as JSON. This spares the repeated passes over large results that
+// the JSON stream codec makes on both ends of the connection.
+//
+// Clients open the connection with binaryPreamble, or with binaryAuthPreamble followed
+// by the big endian uint16 size of a JWT token and the token itself. Every frame then
+// starts with the big endian uint32 size of the RLP encoded binaryFrame following it.
+
+const (
+	maxBinaryFrameSize = 128 * 1024 * 1024 // size limit of a single frame
+	binaryFrameHeader  = 4                 // size of the frame length prefix
+	maxBinaryTokenSize = 4096              // size limit of the token in the preamble
+)
+
+// binaryPreamble opens binary connections. It can't be the start of a JSON value, which
+// lets listeners serve both transports.
+var binaryPreamble = []byte{0x00, 'R', 'L', 'P', 0x01}
+
+// binaryAuthPreamble opens binary connections authenticated with a JWT token.
+var binaryAuthPreamble = []byte{0x00, 'R', 'L', 'P', 0x02}
+
+var errBinaryFrameSize = errors.New("binary RPC frame too large")
+
+// Flags recording which optional fields of a binaryMessage are set.
+const (
+	binaryHasID = 1 << iota
+	binaryHasParams
+	binaryHasResult
+	binaryHasError
+	binaryHasErrorData
+)
+
+// binaryFrame is the RLP encoded content of a frame, a single message or a batch.
+type binaryFrame struct {
+	Batch    bool
+	Messages []*binaryMessage
+}
+
+// binaryMessage is the binary form of a jsonrpcMessage.
+type binaryMessage struct {
+	Flags  uint
+	ID     []byte // JSON encoded request ID
+	Method string
+	Params []byte // JSON encoded parameters
+	Result []byte // JSON encoded result
+	Error  binaryError
+}
+
+// binaryError is the binary form of a jsonError.
+type binaryError struct {
+	Code    uint64 // two's complement of the signed error code
+	Message string
+	Data    []byte // JSON encoded error data
+}
+
+// newBinaryMessage converts a message into its binary form.
+func newBinaryMessage(msg *jsonrpcMessage) (*binaryMessage, error) {
+	bin := &binaryMessage{Method: msg.Method}
+	if msg.ID != nil {
+		bin.Flags |= binaryHasID
+		bin.ID = msg.ID
+	}
+	if msg.Params != nil {
+		bin.Flags |= binaryHasParams
+		bin.Params = msg.Params
+	}
+	if msg.Result != nil {
+		bin.Flags |= binaryHasResult
+		bin.Result = msg.Result
+	}
+	if msg.Error != nil {
+		bin.Flags |= binaryHasError
+		bin.Error.Code = uint64(int64(msg.Error.Code))
+		bin.Error.Message = msg.Error.Message
+		if msg.Error.Data != nil {
+			data, err := json.Marshal(msg.Error.Data)
+			if err != nil {
+				return nil, err
+			}
+			bin.Flags |= binaryHasErrorData
+			bin.Error.Data = data
+		}
+	}
+	return bin, nil
+}
+
+// message converts a binary message back into its JSON-RPC form.
+func (bin *binaryMessage) message() *jsonrpcMessage {
+	msg := &jsonrpcMessage{Version: vsn, Method: bin.Method}
+	if bin.Flags&binaryHasID != 0 {
+		msg.ID = json.RawMessage(bin.ID)
+	}
+	if bin.Flags&binaryHasParams != 0 {
+		msg.Params = json.RawMessage(bin.Params)
+	}
+	if bin.Flags&binaryHasResult != 0 {
+		msg.Result = json.RawMessage(bin.Result)
+	}
+	if bin.Flags&binaryHasError != 0 {
+		msg.Error = &jsonError{Code: int(int64(bin.Error.Code)), Message: bin.Error.Message}
+		if bin.Flags&binaryHasErrorData != 0 {
+			msg.Error.Data = json.RawMessage(bin.Error.Data)
+		}
+	}
+	return msg
+}
+
+// binaryCodec reads and writes RPC messages in binary frames on the underlying
+// connection.
+type binaryCodec struct {
+	remoteAddr string
+	closer     sync.Once        // close closed channel once
+	closed     chan interface{} // closed on Close
+	r          io.Reader        // source of incoming frames
+	encMu      sync.Mutex       // guards writes to the connection
+	conn       Conn
+}
+
+// NewBinaryCodec creates a codec exchanging binary frames over the given connection.
+// The connection preamble must already have been exchanged. If conn implements
+// ConnRemoteAddr, log messages will use it to include the remote address of the
+// connection.
+func NewBinaryCodec(conn Conn) ServerCodec {
+	return newBinaryCodec(conn, conn)
+}
+
+func newBinaryCodec(r io.Reader, conn Conn) *binaryCodec {
+	codec := &binaryCodec{
+		closed: make(chan interface{}),
+		r:      r,
+		conn:   conn,
+	}
+	if ra, ok := conn.(ConnRemoteAddr); ok {
+		codec.remoteAddr = ra.RemoteAddr()
+	}
+	return codec
+}
+
+func (c *binaryCodec) RemoteAddr() string {
+	return c.remoteAddr
+}
+
+func (c *binaryCodec) Read() ([]*jsonrpcMessage, bool, error) {
+	var header [binaryFrameHeader]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, false, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxBinaryFrameSize {
+		return nil, false, errBinaryFrameSize
+	}
+	// Buffer the payload as it arrives instead of allocating the announced size
+	// up front, so clients can't pin large buffers by sending a bare header.
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, c.r, int64(size)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, false, err
+	}
+	var frame binaryFrame
+	if err := rlp.DecodeBytes(buf.Bytes(), &frame); err != nil {
+		return nil, false, err
+	}
+	if !frame.Batch && len(frame.Messages) != 1 {
+		return nil, false, fmt.Errorf("invalid binary RPC frame with %d messages", len(frame.Messages))
+	}
+	msgs := make([]*jsonrpcMessage, len(frame.Messages))
+	for i, bin := range frame.Messages {
+		msgs[i] = bin.message()
+	}
+	return msgs, frame.Batch, nil
+}
+
+// Write sends a message or a batch of messages to the remote end.
+func (c *binaryCodec) Write(ctx context.Context, v interface{}) error {
+	var frame binaryFrame
+	switch v := v.(type) {
+	case *jsonrpcMessage:
+		bin, err := newBinaryMessage(v)
+		if err != nil {
+			return err
+		}
+		frame.Messages = []*binaryMessage{bin}
+	case []*jsonrpcMessage:
+		frame.Batch = true
+		for _, msg := range v {
+			bin, err := newBinaryMessage(msg)
+			if err != nil {
+				return err
+			}
+			frame.Messages = append(frame.Messages, bin)
+		}
+	default:
+		return fmt.Errorf("can't write %T in a binary RPC frame", v)
+	}
+	enc, err := rlp.EncodeToBytes(&frame)
+	if err != nil {
+		return err
+	}
+	if len(enc) > maxBinaryFrameSize {
+		return errBinaryFrameSize
+	}
+	buf := make([]byte, binaryFrameHeader, binaryFrameHeader+len(enc))
+	binary.BigEndian.PutUint32(buf, uint32(len(enc)))
+	buf = append(buf, enc...)
+
+	c.encMu.Lock()
+	defer c.encMu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultWriteTimeout)
+	}
+	c.conn.SetWriteDeadline(deadline)
+	_, err = c.conn.Write(buf)
+	return err
+}
+
+// Close the underlying connection
+func (c *binaryCodec) Close() {
+	c.closer.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// Closed returns a channel which will be closed when Close is called
+func (c *binaryCodec) Closed() <-chan interface{} {
+	return c.closed
+}
+
+// bufferedConn is a connection whose first bytes were already read into a buffer.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// withRemoteAddr tags a connection accepted over TCP with the address of the remote
+// end, which identifies the client for rate limiting and auditing.
+func withRemoteAddr(conn Conn, raw net.Conn) Conn {
+	if addr, ok := raw.RemoteAddr().(*net.TCPAddr); ok {
+		return connWithRemoteAddr{conn, addr.String()}
+	}
+	return conn
+}
+
+// newConnCodec creates the codec of an accepted connection, exchanging binary frames if
+// the client opened the connection with a binary preamble and JSON otherwise. If secret
+// is non-nil, only binary clients presenting a token signed by it are served, and the
+// claims of the token are returned.
+func newConnCodec(conn net.Conn, secret []byte) (ServerCodec, *JWTClaims, error) {
+	r := bufio.NewReader(conn)
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first[0] != binaryPreamble[0] {
+		if secret != nil {
+			return nil, nil, errMissingToken
+		}
+		return NewJSONCodec(withRemoteAddr(&bufferedConn{conn, r}, conn)), nil, nil
+	}
+	preamble := make([]byte, len(binaryPreamble))
+	if _, err := io.ReadFull(r, preamble); err != nil {
+		return nil, nil, err
+	}
+	var claims *JWTClaims
+	switch {
+	case bytes.Equal(preamble, binaryPreamble):
+		if secret != nil {
+			return nil, nil, errMissingToken
+		}
+	case bytes.Equal(preamble, binaryAuthPreamble):
+		token, err := readBinaryToken(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		if secret != nil {
+			if claims, err = parseJWTToken(secret, token, time.Now()); err != nil {
+				return nil, nil, err
+			}
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported binary RPC preamble %x", preamble)
+	}
+	return newBinaryCodec(r, withRemoteAddr(conn, conn)), claims, nil
+}
+
+// readBinaryToken reads the size prefixed token following binaryAuthPreamble.
+func readBinaryToken(r io.Reader) (string, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", err
+	}
+	size := binary.BigEndian.Uint16(header[:])
+	if size > maxBinaryTokenSize {
+		return "", errMalformedToken
+	}
+	token := make([]byte, size)
+	if _, err := io.ReadFull(r, token); err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+// newBinaryClientCodec sends the binary preamble on a new client connection, creating
+// the codec exchanging binary frames over it. If token is non-empty, the connection
+// is authenticated with it.
+func newBinaryClientCodec(conn net.Conn, token string) (ServerCodec, error) {
+	preamble := binaryPreamble
+	if token != "" {
+		if len(token) > maxBinaryTokenSize {
+			conn.Close()
+			return nil, errMalformedToken
+		}
+		preamble = make([]byte, 0, len(binaryAuthPreamble)+2+len(token))
+		preamble = append(preamble, binaryAuthPreamble...)
+		preamble = append(preamble, byte(len(token)>>8), byte(len(token)))
+		preamble = append(preamble, token...)
+	}
+	if _, err := conn.Write(preamble); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return NewBinaryCodec(conn), nil
+}
+
+// DialBinaryIPC creates a new IPC client exchanging binary frames with the server
+// over the given endpoint, which is interpreted like in DialIPC.
+//
+// The context is used for the initial connection establishment. It does not
+// affect subsequent interactions with the client.
+func DialBinaryIPC(ctx context.Context, endpoint string) (*Client, error) {
+	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		conn, err := newIPCConnection(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return newBinaryClientCodec(conn, "")
+	})
+}
+
+// DialBinaryTCP creates a new client exchanging binary frames with the server
+// listening on the given TCP endpoint (host:port).
+//
+// The context is used for the initial connection establishment. It does not
+// affect subsequent interactions with the client.
+func DialBinaryTCP(ctx context.Context, endpoint string) (*Client, error) {
+	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return newBinaryClientCodec(conn, "")
+	})
+}
+
+// DialBinaryTCPWithJWT is like DialBinaryTCP, but authenticates every connection to
+// the server with a freshly issued HS256 token carrying the given claims, signed with
+// secret. The issuance time of the claims is overridden.
+func DialBinaryTCPWithJWT(ctx context.Context, endpoint string, secret []byte, claims JWTClaims) (*Client, error) {
+	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		fresh := claims
+		fresh.IssuedAt = time.Now().Unix()
+		token, err := NewJWTToken(secret, fresh)
+		if err != nil {
+			return nil, err
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return newBinaryClientCodec(conn, token)
+	})
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBinaryMessageConversion(t *testing.T) {
+	tests := []*jsonrpcMessage{
+		{Version: vsn, ID: json.RawMessage(`1`), Method: "test_echo", Params: json.RawMessage(`["x",1]`)},
+		{Version: vsn, Method: "nftest_subscription", Params: json.RawMessage(`{"subscription":"0x1","result":1}`)},
+		{Version: vsn, ID: json.RawMessage(`"a"`), Result: json.RawMessage(`null`)},
+		{Version: vsn, ID: json.RawMessage(`2`), Error: &jsonError{Code: -32601, Message: "not found"}},
+		{Version: vsn, ID: json.RawMessage(`3`), Error: &jsonError{Code: 444, Message: "data", Data: json.RawMessage(`{"a":1}`)}},
+	}
+	for i, msg := range tests {
+		bin, err := newBinaryMessage(msg)
+		if err != nil {
+			t.Fatalf("test %d: conversion failed: %v", i, err)
+		}
+		if have := bin.message(); !reflect.DeepEqual(have, msg) {
+			t.Errorf("test %d: message mismatch:\nhave %v\nwant %v", i, have, msg)
+		}
+	}
+}
+
+func TestBinaryClient(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("can't listen:", err)
+	}
+	defer listener.Close()
+	go server.ServeListener(listener)
+
+	client, err := Dial("rlp+tcp://" + listener.Addr().String())
+	if err != nil {
+		t.Fatal("can't dial:", err)
+	}
+	defer client.Close()
+
+	// Check single and batch calls
+	var result Result
+	if err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Result{"hello", 10, &Args{"world"}}); !reflect.DeepEqual(result, want) {
+		t.Errorf("incorrect result %#v, want %#v", result, want)
+	}
+	batch := []BatchElem{
+		{Method: "test_echo", Args: []interface{}{"hello", 10, &Args{"world"}}, Result: new(Result)},
+		{Method: "no_such_method", Args: []interface{}{1}, Result: new(int)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch[0].Error != nil || !reflect.DeepEqual(batch[0].Result, &Result{"hello", 10, &Args{"world"}}) {
+		t.Errorf("batch result mismatch: %v, %#v", batch[0].Error, batch[0].Result)
+	}
+	if batch[1].Error == nil {
+		t.Errorf("batch call of missing method succeeded")
+	}
+	// Check that error codes and data are transferred
+	err = client.Call(nil, "test_returnError")
+	if ec, ok := err.(Error); !ok || ec.ErrorCode() != 444 {
+		t.Fatalf("error mismatch: have %v", err)
+	}
+	if de, ok := err.(DataError); !ok || string(de.ErrorData().(json.RawMessage)) != `"testError data"` {
+		t.Fatalf("error data mismatch: have %v", err)
+	}
+	// Check subscriptions
+	nc := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", 5, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+
+	for i := 0; i < 5; i++ {
+		select {
+		case val := <-nc:
+			if val != i {
+				t.Fatalf("value mismatch: got %d, want %d", val, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("notification %d timeout", i)
+		}
+	}
+}
+
+// Tests that JSON clients are still served by listeners accepting binary clients.
+func TestBinaryListenerServesJSON(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("can't listen:", err)
+	}
+	defer listener.Close()
+	go server.ServeListener(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal("can't dial:", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := conn.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]}`)); err != nil {
+		t.Fatal("write failed:", err)
+	}
+	var resp jsonrpcMessage
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&resp); err != nil {
+		t.Fatal("read failed:", err)
+	}
+	if want := `{"String":"x","Int":1,"Args":null}`; string(resp.Result) != want {
+		t.Fatalf("result mismatch: have %s, want %s", resp.Result, want)
+	}
+}
+
+// Tests that binary clients on TCP are identified by their address, and thus
+// subject to the rate limits of the server.
+func TestBinaryTCPRateLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(ServerLimits{RateLimit: 1, RateBurst: 2, MethodWeights: map[string]int{"test_echo": 2}})
+	defer server.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("can't listen:", err)
+	}
+	defer listener.Close()
+	go server.ServeListener(listener)
+
+	client, err := Dial("rlp+tcp://" + listener.Addr().String())
+	if err != nil {
+		t.Fatal("can't dial:", err)
+	}
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	err = client.Call(&result, "test_echo", "x", 1)
+	if code := errorCode(err); code != rateLimitedErrorCode {
+		t.Fatalf("wrong error code %d (%v), want %d", code, err, rateLimitedErrorCode)
+	}
+}
+
+// Tests that frames announcing more data than sent are rejected without the
+// announced size being allocated.
+func TestBinaryTruncatedFrame(t *testing.T) {
+	frame := make([]byte, binaryFrameHeader+10)
+	binary.BigEndian.PutUint32(frame, maxBinaryFrameSize)
+
+	codec := newBinaryCodec(bytes.NewReader(frame), nil)
+	if _, _, err := codec.Read(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("error mismatch: have %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -77,6 +77,7 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool
 	services *serviceRegistry
+	policy   connPolicy // restrictions on calls served on this connection
 
 	idCounter uint32
 
@@ -112,6 +113,10 @@ type clientConn struct {
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
 	handler := newHandler(ctx, conn, c.idgen, c.services)
+	handler.limits = c.policy.limits
+	handler.claims = c.policy.claims
+	handler.limiter = c.policy.limiter
+	handler.audit = c.policy.audit
 	return &clientConn{conn, handler}
 }
 
@@ -155,6 +160,9 @@ func (op *requestOp) wait(ctx context.Context, c *Client) (*jsonrpcMessage, erro
 // domain sockets on supported platforms and named pipes on Windows. If you want to
 // configure transport options, use DialHTTP, DialWebsocket or DialIPC instead.
 //
+// The binary transport is selected by the "rlp+tcp" scheme, as in rlp+tcp://host:port,
+// and by the "rlp+ipc" scheme over a local socket, as in rlp+ipc:///path/to/gccm.ipc.
+//
 // For websocket connections, the origin is set to the local host name.
 //
 // The client reconnects automatically if the connection is lost.
@@ -176,6 +184,10 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 		return DialHTTP(rawurl)
 	case "ws", "wss":
 		return DialWebsocket(ctx, rawurl, "")
+	case "rlp+tcp":
+		return DialBinaryTCP(ctx, u.Host)
+	case "rlp+ipc":
+		return DialBinaryIPC(ctx, u.Path)
 	case "stdio":
 		return DialStdIO(ctx)
 	case "":
@@ -197,17 +209,18 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), connPolicy{})
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, policy connPolicy) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
+		policy:      policy,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
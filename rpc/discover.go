@@ -0,0 +1,360 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"math/big"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+)
+
+const (
+	openRPCVersion = "1.2.6"
+	discoverMethod = "rpc.discover" // served by the discover method of the metadata service
+
+	// paramNamesMethod is the method of ParamNamer, which is not exposed over RPC.
+	paramNamesMethod = "RPCParamNames"
+)
+
+// ParamNamer may be implemented by services to name the parameters of their
+// methods in the service discovery document, as Go does not retain parameter
+// names at runtime. Names are keyed by RPC method name without the namespace,
+// e.g. "getBalance", and list the parameters following the context, if any.
+// Parameters without a name are documented as param1, param2, etc.
+type ParamNamer interface {
+	RPCParamNames() map[string][]string
+}
+
+// OpenRPCDocument describes the methods offered by a server, following version
+// 1.2.6 of the OpenRPC specification at https://spec.open-rpc.org.
+type OpenRPCDocument struct {
+	OpenRPC    string            `json:"openrpc"`
+	Info       OpenRPCInfo       `json:"info"`
+	Methods    []*OpenRPCMethod  `json:"methods"`
+	Components OpenRPCComponents `json:"components"`
+}
+
+// OpenRPCInfo holds the metadata of the API.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCMethod describes a method. Subscriptions are offered by the subscribe
+// method of their namespace, taking the subscription name as first parameter,
+// so the parameters of each are listed in the x-subscriptions extension of it.
+type OpenRPCMethod struct {
+	Name           string                 `json:"name"`
+	Params         []*OpenRPCDescriptor   `json:"params"`
+	Result         *OpenRPCDescriptor     `json:"result"`
+	ParamStructure string                 `json:"paramStructure"`
+	Subscriptions  []*OpenRPCSubscription `json:"x-subscriptions,omitempty"`
+}
+
+// OpenRPCSubscription describes a subscription offered by a subscribe method.
+type OpenRPCSubscription struct {
+	Name   string               `json:"name"`
+	Params []*OpenRPCDescriptor `json:"params"`
+}
+
+// OpenRPCDescriptor describes a parameter or result of a method, as an OpenRPC
+// content descriptor.
+type OpenRPCDescriptor struct {
+	Name     string                 `json:"name"`
+	Required bool                   `json:"required,omitempty"`
+	Schema   map[string]interface{} `json:"schema"`
+}
+
+// OpenRPCComponents holds the JSON schemas of the structs referenced by the
+// methods, keyed by type name.
+type OpenRPCComponents struct {
+	Schemas map[string]map[string]interface{} `json:"schemas"`
+}
+
+// Discover returns the OpenRPC document describing the methods and subscriptions
+// offered by the server. It is served as rpc.discover, as mandated by the spec.
+func (s *RPCService) Discover() *OpenRPCDocument {
+	s.server.services.mu.Lock()
+	defer s.server.services.mu.Unlock()
+
+	gen := &schemaGenerator{
+		schemas: make(map[string]map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+	}
+	doc := &OpenRPCDocument{
+		OpenRPC: openRPCVersion,
+		Info:    OpenRPCInfo{Title: "Ccmchain JSON-RPC API", Version: "1.0"},
+	}
+	for name, svc := range s.server.services.services {
+		for method, cb := range svc.callbacks {
+			m := &OpenRPCMethod{
+				Name:           name + serviceMethodSeparator + method,
+				Params:         gen.params(cb),
+				Result:         gen.result(cb),
+				ParamStructure: "by-position",
+			}
+			if name == MetadataApi && method == "discover" {
+				m.Name = discoverMethod
+			}
+			doc.Methods = append(doc.Methods, m)
+		}
+		if len(svc.subscriptions) == 0 {
+			continue
+		}
+		var (
+			kinds []interface{}
+			subs  []*OpenRPCSubscription
+		)
+		for kind, cb := range svc.subscriptions {
+			kinds = append(kinds, kind)
+			subs = append(subs, &OpenRPCSubscription{Name: kind, Params: gen.params(cb)})
+		}
+		sort.Slice(kinds, func(i, j int) bool { return kinds[i].(string) < kinds[j].(string) })
+		sort.Slice(subs, func(i, j int) bool { return subs[i].Name < subs[j].Name })
+
+		doc.Methods = append(doc.Methods, &OpenRPCMethod{
+			Name: name + subscribeMethodSuffix,
+			Params: []*OpenRPCDescriptor{{
+				Name:     "subscription",
+				Required: true,
+				Schema:   map[string]interface{}{"type": "string", "enum": kinds},
+			}},
+			Result:         &OpenRPCDescriptor{Name: "subscriptionId", Schema: map[string]interface{}{"type": "string"}},
+			ParamStructure: "by-position",
+			Subscriptions:  subs,
+		}, &OpenRPCMethod{
+			Name: name + unsubscribeMethodSuffix,
+			Params: []*OpenRPCDescriptor{{
+				Name:     "subscriptionId",
+				Required: true,
+				Schema:   map[string]interface{}{"type": "string"},
+			}},
+			Result:         &OpenRPCDescriptor{Name: "result", Schema: map[string]interface{}{"type": "boolean"}},
+			ParamStructure: "by-position",
+		})
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool { return doc.Methods[i].Name < doc.Methods[j].Name })
+	doc.Components.Schemas = gen.schemas
+	return doc
+}
+
+// JSON schemas of the encodings used by common types.
+var (
+	quantitySchema = map[string]interface{}{"type": "string", "pattern": "^0x(0|[1-9a-fA-F][0-9a-fA-F]*)$"}
+	bytesSchema    = map[string]interface{}{"type": "string", "pattern": "^0x([0-9a-fA-F]{2})*$"}
+	addressSchema  = map[string]interface{}{"type": "string", "pattern": "^0x[0-9a-fA-F]{40}$"}
+	hashSchema     = map[string]interface{}{"type": "string", "pattern": "^0x[0-9a-fA-F]{64}$"}
+
+	blockNumberSchema = map[string]interface{}{
+		"oneOf": []interface{}{
+			quantitySchema,
+			map[string]interface{}{"type": "string", "enum": []string{"earliest", "latest", "pending"}},
+		},
+	}
+	blockNumberOrHashSchema = map[string]interface{}{
+		"oneOf": []interface{}{
+			blockNumberSchema,
+			hashSchema,
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"blockNumber":      blockNumberSchema,
+					"blockHash":        hashSchema,
+					"requireCanonical": map[string]interface{}{"type": "boolean"},
+				},
+			},
+		},
+	}
+)
+
+// knownSchemas are the schemas of types whose JSON encoding is not apparent
+// from reflection.
+var knownSchemas = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(common.Address{}):     addressSchema,
+	reflect.TypeOf(common.Hash{}):        hashSchema,
+	reflect.TypeOf(hexutil.Big{}):        quantitySchema,
+	reflect.TypeOf(hexutil.Uint64(0)):    quantitySchema,
+	reflect.TypeOf(hexutil.Uint(0)):      quantitySchema,
+	reflect.TypeOf(hexutil.Bytes{}):      bytesSchema,
+	reflect.TypeOf(BlockNumber(0)):       blockNumberSchema,
+	reflect.TypeOf(BlockNumberOrHash{}):  blockNumberOrHashSchema,
+	reflect.TypeOf(ID("")):               {"type": "string"},
+	reflect.TypeOf(big.Int{}):            {"type": "integer"},
+	reflect.TypeOf(json.RawMessage{}):    {},
+	reflect.TypeOf([]byte{}):             {"type": "string", "contentEncoding": "base64"},
+	reflect.TypeOf((*error)(nil)).Elem(): {"type": "string"},
+}
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaGenerator derives JSON schemas from Go types, collecting the schemas
+// of structs as components referenced by name.
+type schemaGenerator struct {
+	schemas map[string]map[string]interface{} // Struct schemas by component name
+	names   map[reflect.Type]string           // Component names of the structs seen
+}
+
+// params describes the parameters of a callback. Trailing pointer parameters
+// may be omitted by callers, so they are not required.
+func (g *schemaGenerator) params(cb *callback) []*OpenRPCDescriptor {
+	optional := len(cb.argTypes)
+	for optional > 0 && cb.argTypes[optional-1].Kind() == reflect.Ptr {
+		optional--
+	}
+	params := make([]*OpenRPCDescriptor, len(cb.argTypes))
+	for i, typ := range cb.argTypes {
+		name := "param" + strconv.Itoa(i+1)
+		if i < len(cb.paramNames) && cb.paramNames[i] != "" {
+			name = cb.paramNames[i]
+		}
+		params[i] = &OpenRPCDescriptor{Name: name, Required: i < optional, Schema: g.schema(typ)}
+	}
+	return params
+}
+
+// result describes the result of a callback.
+func (g *schemaGenerator) result(cb *callback) *OpenRPCDescriptor {
+	fntype := cb.fn.Type()
+	if fntype.NumOut() == 0 || cb.errPos == 0 {
+		return &OpenRPCDescriptor{Name: "result", Schema: map[string]interface{}{"type": "null"}}
+	}
+	return &OpenRPCDescriptor{Name: "result", Schema: g.schema(fntype.Out(0))}
+}
+
+// schema returns the JSON schema of the encoding of a type.
+func (g *schemaGenerator) schema(typ reflect.Type) map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if schema, ok := knownSchemas[typ]; ok {
+		return schema
+	}
+	ptr := reflect.PtrTo(typ)
+	switch {
+	case ptr.Implements(jsonMarshalerType) || ptr.Implements(jsonUnmarshalerType):
+		// Custom encodings can't be inferred, leave them open
+		return map[string]interface{}{"title": typ.Name()}
+	case ptr.Implements(textMarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(typ.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(typ.Elem())}
+	case reflect.Struct:
+		return g.structRef(typ)
+	default:
+		// Interfaces and anything else may hold any value
+		return map[string]interface{}{}
+	}
+}
+
+// structRef returns a reference to the schema of a struct, generating it if
+// it wasn't yet. Anonymous structs are not referenced, but described inline.
+func (g *schemaGenerator) structRef(typ reflect.Type) map[string]interface{} {
+	if typ.Name() == "" {
+		return g.structSchema(typ)
+	}
+	name, ok := g.names[typ]
+	if !ok {
+		name = typ.Name()
+		if _, taken := g.schemas[name]; taken {
+			name = path.Base(typ.PkgPath()) + "." + name
+		}
+		// Register the name before descending into the fields, so recursive
+		// structs can refer to themselves
+		g.names[typ] = name
+		g.schemas[name] = nil
+		g.schemas[name] = g.structSchema(typ)
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// structSchema generates the object schema of a struct, following the field
+// naming rules of encoding/json.
+func (g *schemaGenerator) structSchema(typ reflect.Type) map[string]interface{} {
+	var (
+		props    = make(map[string]interface{})
+		required []string
+	)
+	g.fields(typ, props, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fields collects the properties of the exported fields of a struct, along with
+// the ones of embedded structs without a name tag.
+func (g *schemaGenerator) fields(typ reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		ftype := field.Type
+		if field.Anonymous && name == "" {
+			for ftype.Kind() == reflect.Ptr {
+				ftype = ftype.Elem()
+			}
+			if ftype.Kind() == reflect.Struct {
+				g.fields(ftype, props, required)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		props[name] = g.schema(ftype)
+		if !strings.Contains(opts, "omitempty") && ftype.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ccmchain/go-ccmchain/common"
+	"github.com/ccmchain/go-ccmchain/common/hexutil"
+)
+
+type discoverTestService struct{}
+
+type DiscoverArgs struct {
+	From  common.Address `json:"from"`
+	Value *hexutil.Big   `json:"value,omitempty"`
+	Data  hexutil.Bytes  `json:"data"`
+	Next  *DiscoverArgs  `json:"next"`
+}
+
+func (s *discoverTestService) GetBalance(ctx context.Context, addr common.Address, block BlockNumberOrHash) (*hexutil.Big, error) {
+	return nil, nil
+}
+
+func (s *discoverTestService) Send(args DiscoverArgs, limit *hexutil.Uint64) common.Hash {
+	return common.Hash{}
+}
+
+func (s *discoverTestService) Events(ctx context.Context, kind string) (*Subscription, error) {
+	return nil, nil
+}
+
+func (s *discoverTestService) RPCParamNames() map[string][]string {
+	return map[string][]string{
+		"getBalance": {"address", "block"},
+		"send":       {"args"},
+		"events":     {"kind"},
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("disc", new(discoverTestService)); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc OpenRPCDocument
+	if err := client.Call(&doc, "rpc.discover"); err != nil {
+		t.Fatalf("rpc.discover failed: %v", err)
+	}
+	if doc.OpenRPC != openRPCVersion {
+		t.Errorf("openrpc version mismatch: have %s, want %s", doc.OpenRPC, openRPCVersion)
+	}
+	methods := make(map[string]*OpenRPCMethod)
+	var names []string
+	for _, m := range doc.Methods {
+		methods[m.Name] = m
+		names = append(names, m.Name)
+	}
+	want := []string{"disc_getBalance", "disc_send", "disc_subscribe", "disc_unsubscribe", "rpc.discover", "rpc_modules"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("method list mismatch: have %v, want %v", names, want)
+	}
+	// Annotated and trailing optional parameters must be described
+	balance := methods["disc_getBalance"]
+	if len(balance.Params) != 2 || balance.Params[0].Name != "address" || balance.Params[1].Name != "block" || !balance.Params[0].Required || !balance.Params[1].Required {
+		t.Errorf("getBalance params mismatch: %+v", balance.Params)
+	}
+	if balance.Params[0].Schema["pattern"] != addressSchema["pattern"] {
+		t.Errorf("address schema mismatch: %v", balance.Params[0].Schema)
+	}
+	if balance.Result.Schema["pattern"] != quantitySchema["pattern"] {
+		t.Errorf("balance result schema mismatch: %v", balance.Result.Schema)
+	}
+	send := methods["disc_send"]
+	if len(send.Params) != 2 || send.Params[0].Name != "args" || !send.Params[0].Required || send.Params[1].Name != "param2" || send.Params[1].Required {
+		t.Errorf("send params mismatch: %+v", send.Params)
+	}
+	if ref := send.Params[0].Schema["$ref"]; ref != "#/components/schemas/DiscoverArgs" {
+		t.Errorf("args schema reference mismatch: %v", ref)
+	}
+	args := doc.Components.Schemas["DiscoverArgs"]
+	props, _ := args["properties"].(map[string]interface{})
+	if len(props) != 4 || !reflect.DeepEqual(args["required"], []interface{}{"data", "from"}) {
+		t.Errorf("args schema mismatch: %v", args)
+	}
+	if next, _ := props["next"].(map[string]interface{}); next["$ref"] != "#/components/schemas/DiscoverArgs" {
+		t.Errorf("recursive schema reference mismatch: %v", props["next"])
+	}
+	// Subscriptions must be listed on the subscribe method
+	subscribe := methods["disc_subscribe"]
+	if len(subscribe.Subscriptions) != 1 || subscribe.Subscriptions[0].Name != "events" || subscribe.Subscriptions[0].Params[0].Name != "kind" {
+		t.Errorf("subscriptions mismatch: %+v", subscribe.Subscriptions)
+	}
+	if enum := subscribe.Params[0].Schema["enum"]; !reflect.DeepEqual(enum, []interface{}{"events"}) {
+		t.Errorf("subscription names mismatch: %v", enum)
+	}
+}
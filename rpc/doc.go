@@ -109,6 +109,15 @@ the client and server. The server will close the connection for any write error.
 
 For more information about subscriptions, see https://github.com/ccmchain/go-ccmchain/wiki/RPC-PUB-SUB.
 
+Binary Transport
+
+Listeners created by ServeListener also accept clients exchanging the messages in
+length-prefixed RLP frames instead of JSON text, avoiding the cost of scanning large
+results as JSON again on either end. Method parameters and results remain JSON
+encoded. Such clients are created by DialBinaryIPC and DialBinaryTCP, or by Dial with
+the "rlp+ipc" and "rlp+tcp" URL schemes. Servers configured with a JWT secret only accept
+binary clients presenting a token signed by it, created by DialBinaryTCPWithJWT.
+
 Reverse Calls
 
 In any method handler, an instance of rpc.Client can be accessed through the
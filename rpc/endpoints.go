@@ -22,8 +22,11 @@ import (
 	"github.com/ccmchain/go-ccmchain/log"
 )
 
-// StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules
-func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts) (net.Listener, *Server, error) {
+// NewEndpointServer creates a server for an RPC endpoint, configured with the
+// given limits and registering the APIs permitted by the module whitelist. If the
+// whitelist is empty, public APIs are registered, unless exposeAll is set. If
+// jwtSecret is non-nil, requests must be authenticated with tokens signed by it.
+func NewEndpointServer(apis []API, modules []string, exposeAll bool, limits ServerLimits, jwtSecret []byte) (*Server, error) {
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -31,61 +34,83 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.SetLimits(limits)
+	handler.SetJWTSecret(jwtSecret)
 	for _, api := range apis {
-		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
+		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return nil, nil, err
+				return nil, err
 			}
-			log.Debug("HTTP registered", "namespace", api.Namespace)
+			log.Debug("RPC API registered", "namespace", api.Namespace)
 		}
 	}
+	return handler, nil
+}
+
+// StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules/limits.
+// If jwtSecret is non-nil, requests must be authenticated with tokens signed by it.
+func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts, limits ServerLimits, jwtSecret []byte) (net.Listener, *Server, error) {
+	handler, err := NewEndpointServer(apis, modules, false, limits, jwtSecret)
+	if err != nil {
+		return nil, nil, err
+	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
 		return nil, nil, err
 	}
 	go NewHTTPServer(cors, vhosts, timeouts, handler).Serve(listener)
 	return listener, handler, err
 }
 
-// StartWSEndpoint starts a websocket endpoint
-func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool) (net.Listener, *Server, error) {
-
-	// Generate the whitelist based on the allowed modules
-	whitelist := make(map[string]bool)
-	for _, module := range modules {
-		whitelist[module] = true
-	}
-	// Register all the APIs exposed by the services
-	handler := NewServer()
-	for _, api := range apis {
-		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
-			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return nil, nil, err
-			}
-			log.Debug("WebSocket registered", "service", api.Service, "namespace", api.Namespace)
-		}
+// StartWSEndpoint starts a websocket endpoint. If jwtSecret is non-nil, connections
+// must be authenticated with tokens signed by it.
+func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool, limits ServerLimits, jwtSecret []byte) (net.Listener, *Server, error) {
+	handler, err := NewEndpointServer(apis, modules, exposeAll, limits, jwtSecret)
+	if err != nil {
+		return nil, nil, err
 	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
 		return nil, nil, err
 	}
 	go NewWSServer(wsOrigins, handler).Serve(listener)
 	return listener, handler, err
+}
+
+// StartBinaryEndpoint starts a TCP endpoint serving binary clients, configured with
+// modules/limits. Plain JSON-RPC clients are served on it too, unless jwtSecret is
+// non-nil, in which case only binary clients presenting tokens signed by it are.
+func StartBinaryEndpoint(endpoint string, apis []API, modules []string, limits ServerLimits, jwtSecret []byte) (net.Listener, *Server, error) {
+	handler, err := NewEndpointServer(apis, modules, false, limits, jwtSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	// All APIs registered, start the TCP listener
+	listener, err := StartTCPListener(endpoint, handler)
+	if err != nil {
+		return nil, nil, err
+	}
+	return listener, handler, nil
+}
 
+// StartTCPListener starts listening on the given TCP endpoint, serving JSON-RPC
+// and binary clients with handler in the background.
+func StartTCPListener(endpoint string, handler *Server) (net.Listener, error) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	go handler.ServeListener(listener)
+	return listener, nil
 }
 
 // StartIPCEndpoint starts an IPC endpoint.
-func StartIPCEndpoint(ipcEndpoint string, apis []API) (net.Listener, *Server, error) {
+func StartIPCEndpoint(ipcEndpoint string, apis []API, limits ServerLimits) (net.Listener, *Server, error) {
 	// Register all the APIs exposed by the services.
 	handler := NewServer()
+	handler.SetLimits(limits)
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return nil, nil, err
@@ -93,10 +118,20 @@ func StartIPCEndpoint(ipcEndpoint string, apis []API) (net.Listener, *Server, er
 		log.Debug("IPC registered", "namespace", api.Namespace)
 	}
 	// All APIs registered, start the IPC listener.
-	listener, err := ipcListen(ipcEndpoint)
+	listener, err := StartIPCListener(ipcEndpoint, handler)
 	if err != nil {
 		return nil, nil, err
 	}
-	go handler.ServeListener(listener)
 	return listener, handler, nil
 }
+
+// StartIPCListener starts listening on the given IPC endpoint, serving JSON-RPC
+// and binary clients with handler in the background.
+func StartIPCListener(ipcEndpoint string, handler *Server) (net.Listener, error) {
+	listener, err := ipcListen(ipcEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	go handler.ServeListener(listener)
+	return listener, nil
+}
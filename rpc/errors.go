@@ -16,9 +16,24 @@
 
 package rpc
 
-import "fmt"
-
-const defaultErrorCode = -32000
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultErrorCode       = -32000
+	timeoutErrorCode       = -32002
+	responseTooLargeCode   = -32003
+	unauthorizedErrorCode  = -32004
+	batchTooLargeErrorCode = -32600
+
+	// rateLimitedErrorCode is returned if a client exceeds its call rate. It
+	// is distinct from -32005, which the APIs use for individual queries
+	// exceeding the limits of the node (e.g. ccm_getLogs block ranges), so
+	// clients can tell backing off apart from narrowing the query.
+	rateLimitedErrorCode = -32006
+)
 
 type methodNotFoundError struct{ method string }
 
@@ -63,3 +78,53 @@ type invalidParamsError struct{ message string }
 func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
+
+// batch contains more calls than the server allows
+type batchTooLargeError struct{ limit int }
+
+func (e *batchTooLargeError) ErrorCode() int { return batchTooLargeErrorCode }
+
+func (e *batchTooLargeError) Error() string {
+	return fmt.Sprintf("batch too large (max %d items)", e.limit)
+}
+
+// response exceeds the size the server is willing to send
+type responseTooLargeError struct{ limit int }
+
+func (e *responseTooLargeError) ErrorCode() int { return responseTooLargeCode }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("response too large (max %d bytes)", e.limit)
+}
+
+// method did not finish within its execution timeout
+type timeoutError struct{ method string }
+
+func (e *timeoutError) ErrorCode() int { return timeoutErrorCode }
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("request timed out (%s)", e.method)
+}
+
+// namespace is not permitted by the claims of the authentication token
+type unauthorizedError struct{ namespace string }
+
+func (e *unauthorizedError) ErrorCode() int { return unauthorizedErrorCode }
+
+func (e *unauthorizedError) Error() string {
+	return fmt.Sprintf("access to the %s namespace is not permitted", e.namespace)
+}
+
+// client exceeded its call rate limit
+type rateLimitedError struct{ retryAfter time.Duration }
+
+func (e *rateLimitedError) ErrorCode() int { return rateLimitedErrorCode }
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry after %v", e.retryAfter)
+}
+
+// ErrorData returns the time in seconds after which the call may be retried.
+func (e *rateLimitedError) ErrorData() interface{} {
+	return map[string]float64{"retryAfter": e.retryAfter.Seconds()}
+}
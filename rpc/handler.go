@@ -61,11 +61,23 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	limits         ServerLimits
+	claims         *JWTClaims   // token claims of an authenticated connection
+	limiter        *rateLimiter // per-client call rate limiter, shared by the server
+	audit          *AuditLogger // audit log of the calls served, shared by the server
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
 }
 
+// connPolicy is the set of restrictions applied to calls served on a connection.
+type connPolicy struct {
+	limits  ServerLimits
+	claims  *JWTClaims   // claims of the token authenticating the connection, if any
+	limiter *rateLimiter // per-client call rate limiter, if enabled
+	audit   *AuditLogger // audit log of the calls served, if enabled
+}
+
 type callProc struct {
 	ctx       context.Context
 	notifiers []*Notifier
@@ -101,6 +113,13 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Reject batches exceeding the item limit without executing any of them:
+	if limit := h.limits.BatchItems; limit > 0 && len(msgs) > limit {
+		h.startCallProc(func(cp *callProc) {
+			h.respondBatchTooLarge(cp, msgs, limit)
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -114,11 +133,30 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
+		var (
+			answers  = make([]*jsonrpcMessage, 0, len(msgs))
+			size     int
+			tooLarge bool
+		)
 		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, msg); answer != nil {
-				answers = append(answers, answer)
+			// Once the response size limit is hit, remaining calls are answered
+			// with an error instead of being executed.
+			if tooLarge {
+				if !msg.isNotification() {
+					answers = append(answers, msg.errorResponse(&responseTooLargeError{h.limits.BatchResponseSize}))
+				}
+				continue
+			}
+			answer := h.handleCallMsg(cp, msg)
+			if answer == nil {
+				continue
+			}
+			size += len(answer.Result)
+			if limit := h.limits.BatchResponseSize; limit > 0 && size > limit {
+				answer = msg.errorResponse(&responseTooLargeError{limit})
+				tooLarge = true
 			}
+			answers = append(answers, answer)
 		}
 		h.addSubscriptions(cp.notifiers)
 		if len(answers) > 0 {
@@ -130,6 +168,22 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	})
 }
 
+// respondBatchTooLarge answers every call of an oversized batch with an error.
+func (h *handler) respondBatchTooLarge(cp *callProc, msgs []*jsonrpcMessage, limit int) {
+	err := &batchTooLargeError{limit}
+	answers := make([]*jsonrpcMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.isCall() {
+			answers = append(answers, msg.errorResponse(err))
+		}
+	}
+	if len(answers) == 0 {
+		h.conn.Write(cp.ctx, errorMessage(err))
+		return
+	}
+	h.conn.Write(cp.ctx, answers)
+}
+
 // handleMsg handles a single message.
 func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	if ok := h.handleImmediate(msg); ok {
@@ -296,6 +350,7 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 		return nil
 	case msg.isCall():
 		resp := h.handleCall(ctx, msg)
+		h.audit.record(h.conn.RemoteAddr(), msg, resp, time.Since(start))
 		if resp.Error != nil {
 			h.log.Warn("Served "+msg.Method, "reqid", idForLog{msg.ID}, "t", time.Since(start), "err", resp.Error.Message)
 		} else {
@@ -311,6 +366,9 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if !h.claims.allows(msg.namespace()) {
+		return msg.errorResponse(&unauthorizedError{msg.namespace()})
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -327,7 +385,12 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
-
+	if err := h.throttle(msg); err != nil {
+		return msg.errorResponse(err)
+	}
+	if timeout := h.limits.MethodTimeouts[msg.Method]; timeout > 0 {
+		return h.runMethodWithTimeout(cp.ctx, msg, callb, args, timeout)
+	}
 	return h.runMethod(cp.ctx, msg, callb, args)
 }
 
@@ -356,6 +419,9 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	}
 	args = args[1:]
 
+	if err := h.throttle(msg); err != nil {
+		return msg.errorResponse(err)
+	}
 	// Install notifier in context so the subscription handler can find it.
 	n := &Notifier{h: h, namespace: namespace}
 	cp.notifiers = append(cp.notifiers, n)
@@ -370,7 +436,30 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 	if err != nil {
 		return msg.errorResponse(err)
 	}
-	return msg.response(result)
+	resp := msg.response(result)
+	if limit := h.limits.ResponseSize; limit > 0 && len(resp.Result) > limit {
+		return msg.errorResponse(&responseTooLargeError{limit})
+	}
+	return resp
+}
+
+// runMethodWithTimeout runs the Go callback for an RPC method, answering with a
+// timeout error if it doesn't return in time. The callback's context is canceled
+// when the timeout expires, but callbacks ignoring it keep running in the background.
+func (h *handler) runMethodWithTimeout(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value, timeout time.Duration) *jsonrpcMessage {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan *jsonrpcMessage, 1)
+	go func() {
+		done <- h.runMethod(ctx, msg, callb, args)
+	}()
+	select {
+	case resp := <-done:
+		return resp
+	case <-ctx.Done():
+		return msg.errorResponse(&timeoutError{msg.Method})
+	}
 }
 
 // unsubscribe is the callback function for all *_unsubscribe calls.
@@ -46,6 +46,7 @@ var acceptedContentTypes = []string{contentType, "application/json-rpc", "applic
 type httpConn struct {
 	client    *http.Client
 	req       *http.Request
+	auth      HTTPAuth // optional request authenticator
 	closeOnce sync.Once
 	closed    chan interface{}
 }
@@ -107,6 +108,13 @@ var DefaultHTTPTimeouts = HTTPTimeouts{
 // DialHTTPWithClient creates a new RPC client that connects to an RPC server over HTTP
 // using the provided HTTP Client.
 func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
+	return DialHTTPWithAuth(endpoint, client, nil)
+}
+
+// DialHTTPWithAuth creates a new RPC client that connects to an RPC server over HTTP
+// using the provided HTTP Client. If auth is non-nil, it is invoked to authenticate
+// every request.
+func DialHTTPWithAuth(endpoint string, client *http.Client, auth HTTPAuth) (*Client, error) {
 	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -116,7 +124,7 @@ func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
 
 	initctx := context.Background()
 	return newClient(initctx, func(context.Context) (ServerCodec, error) {
-		return &httpConn{client: client, req: req, closed: make(chan interface{})}, nil
+		return &httpConn{client: client, req: req, auth: auth, closed: make(chan interface{})}, nil
 	})
 }
 
@@ -175,6 +183,17 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	req.Body = ioutil.NopCloser(bytes.NewReader(body))
 	req.ContentLength = int64(len(body))
 
+	if hc.auth != nil {
+		// The template request's headers are shared, copy them before authenticating.
+		header := make(http.Header, len(req.Header)+1)
+		for key, values := range req.Header {
+			header[key] = values
+		}
+		req.Header = header
+		if err := hc.auth(req.Header); err != nil {
+			return nil, err
+		}
+	}
 	resp, err := hc.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -213,10 +232,20 @@ func (t *httpServerConn) SetWriteDeadline(time.Time) error { return nil }
 //
 // Deprecated: Server implements http.Handler
 func NewHTTPServer(cors []string, vhosts []string, timeouts HTTPTimeouts, srv http.Handler) *http.Server {
+	return NewHTTPTimeoutServer(timeouts, NewHTTPHandler(cors, vhosts, srv))
+}
+
+// NewHTTPHandler wraps an HTTP handler with the CORS and virtual host checks of
+// the HTTP RPC endpoint.
+func NewHTTPHandler(cors []string, vhosts []string, srv http.Handler) http.Handler {
 	// Wrap the CORS-handler within a host-handler
 	handler := newCorsHandler(srv, cors)
-	handler = newVHostHandler(vhosts, handler)
+	return newVHostHandler(vhosts, handler)
+}
 
+// NewHTTPTimeoutServer creates an HTTP server around a handler, applying the
+// given timeouts to requests.
+func NewHTTPTimeoutServer(timeouts HTTPTimeouts, handler http.Handler) *http.Server {
 	// Make sure timeout values are meaningful
 	if timeouts.ReadTimeout < time.Second {
 		log.Warn("Sanitizing invalid HTTP read timeout", "provided", timeouts.ReadTimeout, "updated", DefaultHTTPTimeouts.ReadTimeout)
@@ -249,6 +278,14 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), code)
 		return
 	}
+	var claims *JWTClaims
+	if s.jwtSecret != nil {
+		var err error
+		if claims, err = jwtAuthenticate(s.jwtSecret, r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
 	// All checks passed, create a codec that reads direct from the request body
 	// untilEOF and writes the response to w and order the server to process a
 	// single request.
@@ -262,6 +299,9 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if origin := r.Header.Get("Origin"); origin != "" {
 		ctx = context.WithValue(ctx, "Origin", origin)
 	}
+	if claims != nil {
+		ctx = context.WithValue(ctx, jwtClaimsKey{}, claims)
+	}
 
 	w.Header().Set("content-type", contentType)
 	codec := newHTTPServerConn(r, w)
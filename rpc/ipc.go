@@ -24,7 +24,8 @@ import (
 	"github.com/ccmchain/go-ccmchain/p2p/netutil"
 )
 
-// ServeListener accepts connections on l, serving JSON-RPC on them.
+// ServeListener accepts connections on l, serving JSON-RPC on them. Connections
+// opened by binary clients exchange binary frames instead.
 func (s *Server) ServeListener(l net.Listener) error {
 	for {
 		conn, err := l.Accept()
@@ -35,10 +36,21 @@ func (s *Server) ServeListener(l net.Listener) error {
 			return err
 		}
 		log.Trace("Accepted RPC connection", "conn", conn.RemoteAddr())
-		go s.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+		go s.serveConn(conn)
 	}
 }
 
+// serveConn serves an accepted connection, once the transport of the client is known.
+func (s *Server) serveConn(conn net.Conn) {
+	codec, claims, err := newConnCodec(conn, s.jwtSecret)
+	if err != nil {
+		log.Debug("Failed to open RPC connection", "conn", conn.RemoteAddr(), "err", err)
+		conn.Close()
+		return
+	}
+	s.serveCodec(codec, claims)
+}
+
 // DialIPC create a new IPC client that connects to the given endpoint. On Unix it assumes
 // the endpoint is the full path to a unix socket, and Windows the endpoint is an
 // identifier for a named pipe.
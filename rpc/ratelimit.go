@@ -0,0 +1,181 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/metrics"
+)
+
+// rateLimiterSweepInterval is the minimum time between two sweeps of idle buckets.
+const rateLimiterSweepInterval = time.Minute
+
+var (
+	throttledMeter        = metrics.NewRegisteredMeter("rpc/throttled", nil)
+	rateLimitClientsGauge = metrics.NewRegisteredGauge("rpc/ratelimit/clients", nil)
+)
+
+// tokenBucket tracks the remaining call budget of a single client.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter applies token bucket rate limits to the calls of individual clients.
+// Every call consumes tokens according to the weight of its method, and buckets
+// refill at a constant rate up to their burst capacity.
+type rateLimiter struct {
+	rate     float64 // tokens added to a bucket per second
+	burst    float64 // maximum number of tokens in a bucket
+	weights  map[string]int
+	prefixes []string // wildcard weight patterns, longest first
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// newRateLimiter creates a rate limiter granting rate tokens per second to each
+// client, up to burst tokens. Method weights are keyed by method name, or by a
+// name prefix if the key ends with '*'. Methods without weight cost one token.
+func newRateLimiter(rate float64, burst int, weights map[string]int) *rateLimiter {
+	rl := &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		weights: make(map[string]int, len(weights)),
+		buckets: make(map[string]*tokenBucket),
+	}
+	if rl.burst < 1 {
+		rl.burst = math.Max(1, math.Ceil(rate))
+	}
+	for method, weight := range weights {
+		rl.weights[method] = weight
+		if strings.HasSuffix(method, "*") {
+			rl.prefixes = append(rl.prefixes, method)
+		}
+	}
+	sort.Slice(rl.prefixes, func(i, j int) bool { return len(rl.prefixes[i]) > len(rl.prefixes[j]) })
+	return rl
+}
+
+// weight returns the number of tokens a call to method consumes.
+func (rl *rateLimiter) weight(method string) float64 {
+	weight, ok := rl.weights[method]
+	if !ok {
+		weight = 1
+		for _, prefix := range rl.prefixes {
+			if strings.HasPrefix(method, prefix[:len(prefix)-1]) {
+				weight = rl.weights[prefix]
+				break
+			}
+		}
+	}
+	// Calls heavier than the burst could never be served, cap them.
+	return math.Min(float64(weight), rl.burst)
+}
+
+// take consumes the tokens of a call to method from the bucket of client. If the
+// bucket doesn't hold enough tokens, the time until it will is returned instead.
+func (rl *rateLimiter) take(client, method string, now time.Time) (bool, time.Duration) {
+	cost := rl.weight(method)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.sweep(now)
+	bucket := rl.buckets[client]
+	if bucket == nil {
+		bucket = &tokenBucket{tokens: rl.burst, last: now}
+		rl.buckets[client] = bucket
+		rateLimitClientsGauge.Update(int64(len(rl.buckets)))
+	}
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens = math.Min(rl.burst, bucket.tokens+elapsed.Seconds()*rl.rate)
+		bucket.last = now
+	}
+	if bucket.tokens >= cost {
+		bucket.tokens -= cost
+		return true, 0
+	}
+	wait := time.Duration((cost - bucket.tokens) / rl.rate * float64(time.Second))
+	return false, wait.Round(time.Millisecond) + time.Millisecond
+}
+
+// sweep drops the buckets which have refilled completely, as they are
+// indistinguishable from freshly created ones.
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rateLimiterSweepInterval {
+		return
+	}
+	rl.lastSweep = now
+	refill := time.Duration(rl.burst / rl.rate * float64(time.Second))
+	for client, bucket := range rl.buckets {
+		if now.Sub(bucket.last) >= refill {
+			delete(rl.buckets, client)
+		}
+	}
+	rateLimitClientsGauge.Update(int64(len(rl.buckets)))
+}
+
+// rateLimitKey identifies the client a connection belongs to for rate limiting:
+// the subject of its authentication token if present, its remote IP otherwise.
+// Local connections (in-process and IPC) have no key and are not limited.
+func rateLimitKey(claims *JWTClaims, remoteAddr string) string {
+	if claims != nil && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// throttle charges a call against the rate limit of the connection's client,
+// returning an error with a retry hint if the client exceeded it.
+func (h *handler) throttle(msg *jsonrpcMessage) error {
+	if h.limiter == nil {
+		return nil
+	}
+	client := rateLimitKey(h.claims, h.conn.RemoteAddr())
+	if client == "" {
+		return nil
+	}
+	ok, wait := h.limiter.take(client, msg.Method, time.Now())
+	if ok {
+		return nil
+	}
+	throttledMeter.Mark(1)
+	metrics.GetOrRegisterMeter("rpc/throttled/"+h.throttledMethod(msg), nil).Mark(1)
+	return &rateLimitedError{wait}
+}
+
+// throttledMethod returns the name of the method under which a throttled call is
+// metered. Calls not resolved by the service registry, like unsubscriptions which
+// are accepted in any namespace, share a single meter so that clients can't create
+// arbitrary numbers of them.
+func (h *handler) throttledMethod(msg *jsonrpcMessage) string {
+	if msg.isSubscribe() || h.reg.callback(msg.Method) != nil {
+		return msg.Method
+	}
+	return "other"
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 The go-ccmchain Authors
+// This file is part of the go-ccmchain library.
+//
+// The go-ccmchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ccmchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ccmchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ccmchain/go-ccmchain/metrics"
+)
+
+func TestRateLimiterWeights(t *testing.T) {
+	t.Parallel()
+
+	rl := newRateLimiter(10, 50, map[string]int{
+		"ccm_getLogs":  10,
+		"debug_*":      5,
+		"debug_trace*": 20,
+		"admin_*":      100,
+	})
+	tests := map[string]float64{
+		"ccm_getLogs":           10,
+		"ccm_blockNumber":       1,
+		"debug_traceBlock":      20,
+		"debug_getBadBlocks":    5,
+		"admin_addPeer":         50, // capped at burst
+		"ccm_getLogsSomething":  1,
+		"debug_traceTransactio": 20,
+	}
+	for method, want := range tests {
+		if have := rl.weight(method); have != want {
+			t.Errorf("%s: weight mismatch: have %v, want %v", method, have, want)
+		}
+	}
+}
+
+func TestRateLimiterTake(t *testing.T) {
+	t.Parallel()
+
+	var (
+		rl  = newRateLimiter(10, 5, map[string]int{"heavy": 3})
+		now = time.Now()
+	)
+	for i := 0; i < 5; i++ {
+		if ok, _ := rl.take("a", "light", now); !ok {
+			t.Fatalf("call %d within burst throttled", i)
+		}
+	}
+	ok, wait := rl.take("a", "light", now)
+	if ok {
+		t.Fatal("call exceeding burst not throttled")
+	}
+	if wait <= 0 || wait > 200*time.Millisecond {
+		t.Fatalf("wrong retry hint: %v", wait)
+	}
+	// Other clients have their own budget.
+	if ok, _ := rl.take("b", "heavy", now); !ok {
+		t.Fatal("call of fresh client throttled")
+	}
+	// Waiting for the hinted time must allow the call.
+	if ok, _ := rl.take("a", "light", now.Add(wait)); !ok {
+		t.Fatal("call after retry hint throttled")
+	}
+	// Fully refilled buckets are dropped on sweep.
+	later := now.Add(rateLimiterSweepInterval)
+	rl.take("c", "light", later)
+	if len(rl.buckets) != 1 {
+		t.Fatalf("idle buckets not swept: %d left", len(rl.buckets))
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		claims *JWTClaims
+		addr   string
+		want   string
+	}{
+		{nil, "", ""},
+		{nil, "10.0.0.1:4444", "10.0.0.1"},
+		{nil, "[::1]:4444", "::1"},
+		{&JWTClaims{}, "10.0.0.1:4444", "10.0.0.1"},
+		{&JWTClaims{Subject: "alice"}, "10.0.0.1:4444", "sub:alice"},
+	}
+	for _, test := range tests {
+		if have := rateLimitKey(test.claims, test.addr); have != test.want {
+			t.Errorf("key mismatch for %v/%q: have %q, want %q", test.claims, test.addr, have, test.want)
+		}
+	}
+}
+
+// This test checks that clients exceeding their rate limit receive errors with a
+// retry hint.
+func TestServerRateLimit(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer()
+	srv.SetLimits(ServerLimits{RateLimit: 1, RateBurst: 2, MethodWeights: map[string]int{"test_echo": 2}})
+	httpsrv := httptest.NewServer(srv)
+	defer srv.Stop()
+	defer httpsrv.Close()
+
+	client, err := DialHTTPWithClient(httpsrv.URL, new(http.Client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	err = client.Call(&result, "test_echo", "x", 1)
+	if code := errorCode(err); code != rateLimitedErrorCode {
+		t.Fatalf("wrong error code %d (%v), want %d", code, err, rateLimitedErrorCode)
+	}
+	data, ok := err.(DataError).ErrorData().(map[string]interface{})
+	if !ok || data["retryAfter"].(float64) <= 0 {
+		t.Fatalf("missing retry hint in error data: %v", err.(DataError).ErrorData())
+	}
+}
+
+// This test checks that throttled calls of methods unknown to the service registry
+// are metered under a shared name instead of their own.
+func TestServerRateLimitMeters(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer()
+	srv.SetLimits(ServerLimits{RateLimit: 1, RateBurst: 1})
+	httpsrv := httptest.NewServer(srv)
+	defer srv.Stop()
+	defer httpsrv.Close()
+
+	client, err := DialHTTPWithClient(httpsrv.URL, new(http.Client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	// Unsubscriptions are served in any namespace, rather than by the registry.
+	err = client.Call(nil, "meterTest_unsubscribe", "0x1")
+	if code := errorCode(err); code != rateLimitedErrorCode {
+		t.Fatalf("wrong error code %d (%v), want %d", code, err, rateLimitedErrorCode)
+	}
+	if metrics.DefaultRegistry.Get("rpc/throttled/meterTest_unsubscribe") != nil {
+		t.Error("meter registered for method unknown to the registry")
+	}
+	if metrics.DefaultRegistry.Get("rpc/throttled/other") == nil {
+		t.Error("no meter registered for methods unknown to the registry")
+	}
+}
@@ -20,8 +20,10 @@ import (
 	"context"
 	"io"
 	"sync/atomic"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
+	"github.com/ccmchain/go-ccmchain/common"
 	"github.com/ccmchain/go-ccmchain/log"
 )
 
@@ -40,12 +42,47 @@ const (
 	OptionSubscriptions = 1 << iota // support pub sub
 )
 
+// ServerLimits bounds the resources a single connection or client may consume on
+// a Server. A zero value for any of the fields disables the corresponding limit.
+//
+// Rate limits are enforced per remote IP address, or per token subject on
+// authenticated connections. Every call consumes tokens according to the weight
+// of its method, keyed by method name or by name prefix if the key ends in '*'.
+type ServerLimits struct {
+	BatchItems        int                      // maximum number of calls in a batch
+	BatchResponseSize int                      // maximum total size of a batch response in bytes
+	ResponseSize      int                      // maximum size of a single call result in bytes
+	MethodTimeouts    map[string]time.Duration // execution timeouts keyed by method name
+	RateLimit         float64                  // call tokens granted to each client per second
+	RateBurst         int                      // maximum call tokens a client may accumulate
+	MethodWeights     map[string]int           // call tokens consumed per method, 1 if unset
+}
+
+// DefaultServerLimits represents the default limits used by public RPC endpoints
+// if further configuration is not provided.
+var DefaultServerLimits = ServerLimits{
+	BatchItems:        1000,
+	BatchResponseSize: 25 * 1024 * 1024,
+	MethodWeights: map[string]int{
+		"ccm_call":        2,
+		"ccm_estimateGas": 2,
+		"ccm_getLogs":     10,
+		"ccm_getLogsPage": 10,
+		"debug_trace*":    20,
+		"trace_*":         20,
+	},
+}
+
 // Server is an RPC server.
 type Server struct {
-	services serviceRegistry
-	idgen    func() ID
-	run      int32
-	codecs   mapset.Set
+	services  serviceRegistry
+	idgen     func() ID
+	run       int32
+	codecs    mapset.Set
+	limits    ServerLimits
+	jwtSecret []byte       // HS256 secret authenticating requests and connections, if set
+	limiter   *rateLimiter // per-client call rate limiter, if enabled
+	audit     *AuditLogger // audit log of the calls served, if enabled
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -66,12 +103,48 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetLimits configures the resource limits applied to requests served by s. It
+// must be called before the server starts serving connections.
+func (s *Server) SetLimits(limits ServerLimits) {
+	timeouts := make(map[string]time.Duration, len(limits.MethodTimeouts))
+	for method, timeout := range limits.MethodTimeouts {
+		timeouts[method] = timeout
+	}
+	limits.MethodTimeouts = timeouts
+	s.limits = limits
+
+	s.limiter = nil
+	if limits.RateLimit > 0 {
+		s.limiter = newRateLimiter(limits.RateLimit, limits.RateBurst, limits.MethodWeights)
+	}
+}
+
+// SetJWTSecret enables authentication of HTTP and websocket requests, and of the
+// binary clients of listeners, with HS256 tokens signed by secret. It must be called before the server starts serving
+// connections.
+func (s *Server) SetJWTSecret(secret []byte) {
+	s.jwtSecret = common.CopyBytes(secret)
+}
+
+// SetAuditLogger enables recording the calls served by s, and their answers, to
+// the given audit log. It must be called before the server starts serving
+// connections.
+func (s *Server) SetAuditLogger(audit *AuditLogger) {
+	s.audit = audit
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(codec, nil)
+}
+
+// serveCodec serves the given codec, restricting calls to the namespaces permitted
+// by the claims of the token authenticating the connection.
+func (s *Server) serveCodec(codec ServerCodec, claims *JWTClaims) {
 	defer codec.Close()
 
 	// Don't serve if server is stopped.
@@ -83,7 +156,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, connPolicy{limits: s.limits, claims: claims, limiter: s.limiter, audit: s.audit})
 	<-codec.Closed()
 	c.Close()
 }
@@ -99,6 +172,10 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 
 	h := newHandler(ctx, codec, s.idgen, &s.services)
 	h.allowSubscribe = false
+	h.limits = s.limits
+	h.claims = JWTClaimsFromContext(ctx)
+	h.limiter = s.limiter
+	h.audit = s.audit
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.Read()
@@ -45,7 +45,7 @@ func TestServerRegisterName(t *testing.T) {
 		t.Fatalf("Expected service calc to be registered")
 	}
 
-	wantCallbacks := 7
+	wantCallbacks := 8
 	if len(svc.callbacks) != wantCallbacks {
 		t.Errorf("Expected %d callbacks for service 'service', got %d", wantCallbacks, len(svc.callbacks))
 	}
@@ -150,3 +150,91 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+// This test checks that batches exceeding the item or response size limits are
+// answered with errors.
+func TestServerBatchLimits(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(ServerLimits{BatchItems: 2, BatchResponseSize: 100})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	echo := func(str string) BatchElem {
+		return BatchElem{Method: "test_echo", Args: []interface{}{str, 1, nil}, Result: new(Result)}
+	}
+	// Batch with too many items.
+	batch := []BatchElem{echo("a"), echo("b"), echo("c")}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	for i, elem := range batch {
+		if code := errorCode(elem.Error); code != batchTooLargeErrorCode {
+			t.Errorf("elem %d: wrong error code %d (%v), want %d", i, code, elem.Error, batchTooLargeErrorCode)
+		}
+	}
+	// Batch whose second response pushes it over the size limit.
+	long := strings.Repeat("x", 60)
+	batch = []BatchElem{echo(long), echo(long)}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal("error sending batch:", err)
+	}
+	if batch[0].Error != nil {
+		t.Errorf("elem 0: unexpected error: %v", batch[0].Error)
+	}
+	if code := errorCode(batch[1].Error); code != responseTooLargeCode {
+		t.Errorf("elem 1: wrong error code %d (%v), want %d", code, batch[1].Error, responseTooLargeCode)
+	}
+}
+
+// This test checks that single call results exceeding the size limit are rejected.
+func TestServerResponseSizeLimit(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(ServerLimits{ResponseSize: 50})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1, nil); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	err := client.Call(&result, "test_echo", strings.Repeat("x", 60), 1, nil)
+	if code := errorCode(err); code != responseTooLargeCode {
+		t.Fatalf("wrong error code %d (%v), want %d", code, err, responseTooLargeCode)
+	}
+}
+
+// This test checks that methods exceeding their execution timeout are answered
+// with a timeout error without waiting for them to return.
+func TestServerMethodTimeout(t *testing.T) {
+	server := newTestServer()
+	server.SetLimits(ServerLimits{MethodTimeouts: map[string]time.Duration{
+		"test_sleep":       50 * time.Millisecond,
+		"test_echoWithCtx": 0,
+	}})
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	start := time.Now()
+	err := client.Call(nil, "test_sleep", 2*time.Second)
+	if code := errorCode(err); code != timeoutErrorCode {
+		t.Fatalf("wrong error code %d (%v), want %d", code, err, timeoutErrorCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("timeout error took %v", elapsed)
+	}
+	// Methods without a configured timeout are unaffected.
+	var result Result
+	if err := client.Call(&result, "test_echoWithCtx", "x", 1, nil); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func errorCode(err error) int {
+	if ec, ok := err.(Error); ok {
+		return ec.ErrorCode()
+	}
+	return 0
+}
@@ -57,6 +57,7 @@ type callback struct {
 	hasCtx      bool           // method's first argument is a context (not included in argTypes)
 	errPos      int            // err return idx, of -1 when method cannot return error
 	isSubscribe bool           // true if this is a subscription callback
+	paramNames  []string       // names of the arguments, for service discovery
 }
 
 func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
@@ -95,6 +96,9 @@ func (r *serviceRegistry) registerName(name string, rcvr interface{}) error {
 
 // callback returns the callback corresponding to the given RPC method name.
 func (r *serviceRegistry) callback(method string) *callback {
+	if method == discoverMethod {
+		method = MetadataApi + serviceMethodSeparator + "discover"
+	}
 	elem := strings.SplitN(method, serviceMethodSeparator, 2)
 	if len(elem) != 2 {
 		return nil
@@ -115,18 +119,23 @@ func (r *serviceRegistry) subscription(service, name string) *callback {
 // satisfies the criteria for a RPC callback or a subscription callback and adds it to the
 // collection of callbacks. See server documentation for a summary of these criteria.
 func suitableCallbacks(receiver reflect.Value) map[string]*callback {
+	var paramNames map[string][]string
+	if namer, ok := receiver.Interface().(ParamNamer); ok {
+		paramNames = namer.RPCParamNames()
+	}
 	typ := receiver.Type()
 	callbacks := make(map[string]*callback)
 	for m := 0; m < typ.NumMethod(); m++ {
 		method := typ.Method(m)
-		if method.PkgPath != "" {
-			continue // method not exported
+		if method.PkgPath != "" || method.Name == paramNamesMethod {
+			continue // method not exported or parameter names annotation
 		}
 		cb := newCallback(receiver, method.Func)
 		if cb == nil {
 			continue // function invalid
 		}
 		name := formatName(method.Name)
+		cb.paramNames = paramNames[name]
 		callbacks[name] = cb
 	}
 	return callbacks
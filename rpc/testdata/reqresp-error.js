@@ -0,0 +1,5 @@
+// This test calls the test_returnError method, which returns an error that
+// carries both a custom code and additional data.
+
+--> {"jsonrpc": "2.0", "id": 2, "method": "test_returnError", "params": []}
+<-- {"jsonrpc":"2.0","id":2,"error":{"code":444,"message":"testError","data":"testError data"}}
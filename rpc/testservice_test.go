@@ -53,6 +53,12 @@ func sequentialIDGenerator() func() ID {
 
 type testService struct{}
 
+type testError struct{}
+
+func (testError) Error() string          { return "testError" }
+func (testError) ErrorCode() int         { return 444 }
+func (testError) ErrorData() interface{} { return "testError data" }
+
 type Args struct {
 	S string
 }
@@ -77,6 +83,10 @@ func (s *testService) Sleep(ctx context.Context, duration time.Duration) {
 	time.Sleep(duration)
 }
 
+func (s *testService) ReturnError() error {
+	return testError{}
+}
+
 func (s *testService) Rets() (string, error) {
 	return "", nil
 }